@@ -0,0 +1,738 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sentlab/update-db/config"
+	"github.com/sentlab/update-db/excel"
+	"github.com/sentlab/update-db/importer"
+	"github.com/sentlab/update-db/sql"
+)
+
+// commonFlags holds the flags shared by every command.
+type commonFlags struct {
+	config *string
+	dsn    *string
+	driver *string
+}
+
+func addCommonFlags(fs *flag.FlagSet) commonFlags {
+	return commonFlags{
+		config: fs.String("config", "", "path to the YAML config file (default: $"+config.PathEnv+" or "+config.DefaultPath+")"),
+		dsn:    fs.String("dsn", "", "database connection string or SQLite file path (default: from config)"),
+		driver: fs.String("driver", "", "database driver: mysql, sqlite or postgres (default: from config or the DSN)"),
+	}
+}
+
+// load reads the configuration and applies the connection flags on top of it.
+func (f commonFlags) load() (*config.Config, error) {
+	cfg, err := config.Load(*f.config)
+	if err != nil {
+		return nil, err
+	}
+	setIfEmpty(f.dsn, cfg.Database.DSN)
+	setIfEmpty(f.driver, cfg.Database.Driver)
+	return cfg, nil
+}
+
+// open connects to the database selected by the flags and the configuration.
+func (f commonFlags) open(ctx context.Context) (*sql.Store, error) {
+	if *f.dsn == "" {
+		return nil, newUsageError("missing required flag --dsn")
+	}
+	return sql.Open(ctx, *f.driver, *f.dsn)
+}
+
+// setIfEmpty sets a flag value that was not given on the command line.
+func setIfEmpty(value *string, fallback string) {
+	if *value == "" {
+		*value = fallback
+	}
+}
+
+func runImport(ctx context.Context, args []string) error {
+	fs := newFlagSet("import", "Upload the rows of a CSV file or scanner report into a table as a new scan\nsnapshot, keeping earlier scans. The header is matched to the table columns\nby name, through the profile of the scanner that exported a CSV file or\nworkbook, or through a mapping file, and nothing is loaded when a required\ncolumn is missing. Scanner fields the table lacks are added as new columns.\n\nA directory or a .zip, .tar, .tar.gz or .gz archive loads the files it holds\ninto one scan, each row tagged with the file it came from in source_file.\n\nCVSS vectors are scored, and their version, scores and components such as\nthe attack vector fill cvss_* columns; rows without a CVSS score get the\nscore of their vector.")
+	common := addCommonFlags(fs)
+	tableName := fs.String("table", "", "table to upload the rows into (default: source table from config)")
+	filePath := fs.String("file", "", "path to the file, directory or archive to upload (required unless --csv is given)")
+	csvFilePath := fs.String("csv", "", "path to a CSV file to upload; same as --file with --format csv")
+	format := fs.String("format", "", "input format: "+strings.Join(importer.Formats(), ", ")+" (default: from the names and contents of the files)")
+	mappingPath := fs.String("mapping", "", "YAML file mapping source headers to table columns (default: from config)")
+	profile := fs.String("profile", "", "header profile of a CSV file or workbook: "+strings.Join(importer.ProfileNames(), ", ")+", one from the profiles file, or none (default: detected from the header)")
+	profilesPath := fs.String("profiles", "", "YAML file of header profiles tried before the built-in ones (default: from config)")
+	batchSize := fs.Int("batch-size", 0, "rows per INSERT statement (default: from config)")
+	txRows := fs.Int("tx-rows", 0, "rows per transaction (default: from config)")
+	quiet := fs.Bool("quiet", false, "do not report progress while loading")
+	create := fs.Bool("create", false, "create the table when it does not exist, inferring column types")
+	scanner := fs.String("scanner", "", "label of the scanner that produced the file (default: from config, or the format and the image, product or repository the file covers)")
+	replace := fs.Bool("replace", false, "replace every row and scan of the table with the new scan instead of adding one")
+	sheet := fs.String("sheet", "", "workbook sheet to read, or * for every sheet (default: the first sheet)")
+	headerRow := fs.Int("header-row", 0, "row number of the workbook header (default: the first row of column names)")
+	repository := fs.String("repository", "", "repository of SARIF results without version control details (default: from config, or the file name)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *csvFilePath != "" {
+		if *filePath != "" {
+			return newUsageError("--csv and --file are mutually exclusive")
+		}
+		*filePath = *csvFilePath
+		setIfEmpty(format, "csv")
+	}
+	if err := requireFlags(fs, "file"); err != nil {
+		return err
+	}
+	if info, err := os.Stat(*filePath); *format == "" && !importer.IsArchive(*filePath) && (err != nil || !info.IsDir()) {
+		detected, err := importer.DetectFormat(*filePath)
+		if err != nil {
+			return newUsageError("%v", err)
+		}
+		*format = detected
+	}
+
+	// Pick the files out of a directory or an archive
+	inputs, skipped, cleanup, err := importer.Expand(*filePath, *format)
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: skipped files of no known format: %s\n", strings.Join(skipped, ", "))
+	}
+	f, _ := importer.Lookup(inputs[0].Format)
+	for _, in := range inputs[1:] {
+		if other, _ := importer.Lookup(in.Format); other.Kind != f.Kind {
+			return fmt.Errorf("%s and %s load into different tables; import them separately", inputs[0].Name, in.Name)
+		}
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		return err
+	}
+	opts := sql.ImportOptions{
+		Required:   cfg.Import.Required,
+		BatchSize:  cfg.Import.BatchSize,
+		TxRows:     cfg.Import.TxRows,
+		Create:     *create,
+		SampleRows: cfg.Import.SampleRows,
+	}
+
+	// Inventories, SBOMs, VEX statements and EPSS scores go to their own tables,
+	// created on first use
+	switch f.Kind {
+	case importer.Assets:
+		setIfEmpty(tableName, cfg.Tables.Assets)
+		opts.Required = []string{importer.ColumnHost}
+		opts.Create = true
+	case importer.Components:
+		setIfEmpty(tableName, cfg.Tables.Components)
+		opts.Required = []string{importer.ColumnHost, importer.ColumnComponent}
+		opts.Create = true
+	case importer.Statements:
+		setIfEmpty(tableName, cfg.Tables.VEX)
+		opts.Required = []string{"vulnerability_id", "state"}
+		opts.Create = true
+	case importer.Scores:
+		setIfEmpty(tableName, cfg.Tables.EPSS)
+		opts.Required = []string{importer.ColumnCVE, importer.ColumnEPSS}
+		opts.Create = true
+	}
+	setIfEmpty(tableName, cfg.Tables.Source)
+	setIfEmpty(mappingPath, cfg.Import.Mapping)
+	setIfEmpty(profilesPath, cfg.Import.Profiles)
+	setIfEmpty(scanner, cfg.Import.Scanner)
+	if *batchSize > 0 {
+		opts.BatchSize = *batchSize
+	}
+	if *txRows > 0 {
+		opts.TxRows = *txRows
+	}
+	if !*quiet {
+		opts.Progress = printProgress
+	}
+	if *mappingPath != "" {
+		if opts.Mapping, err = config.LoadMapping(*mappingPath); err != nil {
+			return err
+		}
+	}
+
+	// SARIF results are reported under their repository with the configured severities
+	setIfEmpty(repository, cfg.Import.SARIF.Repository)
+	readOpts := importer.Options{Asset: *repository, Sheet: *sheet, HeaderRow: *headerRow, Profile: *profile, Fields: cfg.Import.JSONL.Fields, Levels: map[string]importer.Level{}}
+	for name, level := range cfg.Import.SARIF.Levels {
+		readOpts.Levels[name] = importer.Level{Severity: level.Severity, CVSS: level.CVSS}
+	}
+	if *profilesPath != "" {
+		profiles, err := config.LoadProfiles(*profilesPath)
+		if err != nil {
+			return err
+		}
+		for _, p := range profiles {
+			readOpts.Profiles = append(readOpts.Profiles, importer.Profile{Name: p.Name, Detect: p.Detect, Columns: p.Columns, Severities: p.Severities})
+		}
+	}
+	open := func(in importer.Input) (importer.Reader, error) {
+		fileOpts := readOpts
+		setIfEmpty(&fileOpts.Asset, strings.TrimSuffix(path.Base(in.Name), path.Ext(in.Name)))
+		return importer.Open(in.Path, in.Format, fileOpts)
+	}
+
+	// Unlabelled scans are told apart by format and by the image, product
+	// or repository they cover, so the latest scan of each is reported
+	if *scanner == "" {
+		if *scanner, err = defaultScanner(inputs, open); err != nil {
+			return err
+		}
+	}
+
+	s, err := common.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	what := inputs[0].Format + " file"
+	if inputs[0].Packed {
+		what = filepath.Base(*filePath)
+	}
+	if err := importScan(ctx, s, *tableName, filepath.Base(*filePath), *scanner, inputs, open, opts, *replace); err != nil {
+		return fmt.Errorf("error uploading %s: %w", what, err)
+	}
+
+	// Vulnerabilities embedded in SBOMs become findings for their product,
+	// added to the source table as a scan of their own
+	var embedding []importer.Input
+	for _, in := range inputs {
+		if format, _ := importer.Lookup(in.Format); format.EmbedsFindings() {
+			embedding = append(embedding, in)
+		}
+	}
+	if len(embedding) == 0 {
+		return nil
+	}
+	openFindings := func(in importer.Input) (importer.Reader, error) {
+		return importer.OpenFindings(in.Path, in.Format, readOpts)
+	}
+	opts.Required = cfg.Import.Required
+	opts.Create = true
+	if err := importScan(ctx, s, cfg.Tables.Source, filepath.Base(*filePath), *scanner, embedding, openFindings, opts, false); err != nil {
+		return fmt.Errorf("error uploading the vulnerabilities of %s: %w", what, err)
+	}
+	return nil
+}
+
+// defaultScanner returns the label of the scan of inputs: the header profile
+// a CSV file or workbook matched, or the format and the image, product or
+// repository the file covers. Inputs whose labels differ get none.
+func defaultScanner(inputs []importer.Input, open func(importer.Input) (importer.Reader, error)) (string, error) {
+	label := ""
+	for i, in := range inputs {
+		source, err := open(in)
+		if err != nil {
+			return "", err
+		}
+		current := ""
+		if detected := source.Detected(); detected != nil {
+			current = detected.Profile
+		} else if in.Format != "csv" {
+			current = strings.TrimSpace(in.Format + " " + source.Subject())
+		}
+		source.Close()
+		if i > 0 && current != label {
+			return "", nil
+		}
+		label = current
+	}
+	return label, nil
+}
+
+// importScan uploads the rows of inputs into table as one new scan, or in
+// place of every row when replace is set, and reports how it went. Inputs
+// that open with importer.ErrNoFindings are passed over, and no scan is
+// recorded when every input is. A failed scan is removed again.
+func importScan(ctx context.Context, s *sql.Store, table, sourceFile, scanner string, inputs []importer.Input, open func(importer.Input) (importer.Reader, error), opts sql.ImportOptions, replace bool) error {
+	result, files, err := importInputs(ctx, s, table, sourceFile, scanner, inputs, open, &opts, replace)
+	if err == nil && opts.Scan != nil {
+		err = opts.Scan.Commit(ctx, s)
+	}
+	if err != nil {
+		if opts.Scan != nil {
+			if abortErr := opts.Scan.Abort(context.Background(), s); abortErr != nil {
+				fmt.Fprintf(os.Stderr, "warning: unable to remove the partial scan: %v\n", abortErr)
+			}
+		}
+		return err
+	}
+	if files == 0 {
+		return nil
+	}
+	if len(result.Unmapped) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: skipped columns with no matching table column: %s\n", strings.Join(result.Unmapped, ", "))
+	}
+
+	uploaded := "File"
+	if files > 1 {
+		uploaded = fmt.Sprintf("%d files", files)
+	}
+	if opts.Scan != nil {
+		fmt.Printf("%s uploaded successfully into %s as scan %d: %s.\n", uploaded, table, opts.Scan.ID, formatThroughput(result))
+	} else {
+		fmt.Printf("%s uploaded successfully into %s: %s.\n", uploaded, table, formatThroughput(result))
+	}
+	return nil
+}
+
+// importInputs uploads the rows of every input into table and returns the
+// totals and the number of files loaded. The scan is begun in opts.Scan on
+// the first file to load; when replacing the table, its rows replace every
+// row and scan of the table once the scan commits.
+func importInputs(ctx context.Context, s *sql.Store, table, sourceFile, scanner string, inputs []importer.Input, open func(importer.Input) (importer.Reader, error), opts *sql.ImportOptions, replace bool) (sql.ImportResult, int, error) {
+	start := time.Now()
+	var total sql.ImportResult
+	unmapped := map[string]bool{}
+	files := 0
+	for _, in := range inputs {
+		source, err := open(in)
+		if errors.Is(err, importer.ErrNoFindings) {
+			continue
+		}
+		if err != nil {
+			return total, files, err
+		}
+
+		// Record the upload as a new scan, which replaces the rows and
+		// scans of the table when asked to once every input loaded.
+		if opts.Scan == nil {
+			begin := sql.BeginScan
+			if replace {
+				begin = sql.BeginReplace
+			}
+			if opts.Scan, err = begin(ctx, s, table, sourceFile, scanner); err != nil {
+				source.Close()
+				return total, files, err
+			}
+		}
+		fileOpts := *opts
+		fileOpts.AddColumns = in.Format != "csv"
+		fileOpts.Derived = source.Derived()
+		fileOpts.Append = opts.Append || files > 0
+		if in.Packed {
+			fileOpts.SourceFile = in.Name
+		}
+
+		// The columns of a recognized export map through its profile, under
+		// the mapping file, and like a scanner report it adds the fields the
+		// table lacks
+		if detected := source.Detected(); detected != nil {
+			fmt.Printf("Reading %s with the %s header profile.\n", in.Name, detected.Profile)
+			fileOpts.Mapping = detected.Under(opts.Mapping)
+			fileOpts.AddColumns = true
+		}
+
+		// Upload the rows to the database table.
+		result, err := sql.ImportRows(ctx, s, table, source, fileOpts)
+		source.Close()
+		if err != nil {
+			if in.Packed {
+				err = fmt.Errorf("%s: %w", in.Name, err)
+			}
+			return total, files, err
+		}
+		files++
+		total.Rows += result.Rows
+		total.Bytes += result.Bytes
+		for _, name := range result.Unmapped {
+			if !unmapped[name] {
+				unmapped[name] = true
+				total.Unmapped = append(total.Unmapped, name)
+			}
+		}
+	}
+	total.Duration = time.Since(start)
+	return total, files, nil
+}
+
+// printProgress reports the progress of a running import on stderr.
+func printProgress(progress sql.ImportResult) {
+	fmt.Fprintf(os.Stderr, "loaded %s\n", formatThroughput(progress))
+}
+
+func formatThroughput(r sql.ImportResult) string {
+	return fmt.Sprintf("%d rows, %.1f MB in %s (%.0f rows/s)",
+		r.Rows, float64(r.Bytes)/(1<<20), r.Duration.Round(time.Millisecond), r.RowsPerSecond())
+}
+
+func runReport(ctx context.Context, args []string) error {
+	fs := newFlagSet("report", "Run the report queries against a table and write the results into a copy\nof the Excel workbook.")
+	common := addCommonFlags(fs)
+	tableName := fs.String("table", "", "table to run the report queries on (default: source table from config)")
+	fileLocation := fs.String("workbook", "", "path to the Excel workbook template (default: from config)")
+	newFile := fs.String("output", "", "path of the populated workbook (default: from config, or Populated_ next to the template)")
+	assetsTable := fs.String("assets-table", "", "asset table for the open services sheet (default: from config)")
+	vexTable := fs.String("vex-table", "", "table of VEX statements whose not_affected findings are left out (default: from config)")
+	epssTable := fs.String("epss-table", "", "table of EPSS scores to rank CVEs and hosts by likely exploitation (default: from config)")
+	scanFlag := fs.String("scan", "latest", "scans to report on: latest (the latest scan of each scanner), a scan ID, or a YYYY-MM-DD date (the latest scan of each scanner up to that day)")
+	severity := fs.String("severity", "", "severity bands to count findings by: "+strings.Join(sql.BandPresets(), " or ")+" (default: from config, or "+sql.DefaultBandPreset+")")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	selector, err := sql.ParseSelector(*scanFlag)
+	if err != nil {
+		return newUsageError("invalid --scan: %v", err)
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		return err
+	}
+	setIfEmpty(tableName, cfg.Tables.Source)
+	setIfEmpty(fileLocation, cfg.Output.Workbook)
+	setIfEmpty(newFile, cfg.Output.Report)
+	setIfEmpty(assetsTable, cfg.Tables.Assets)
+	setIfEmpty(vexTable, cfg.Tables.VEX)
+	setIfEmpty(epssTable, cfg.Tables.EPSS)
+	if err := requireFlags(fs, "workbook"); err != nil {
+		return err
+	}
+	bands, err := severityBands(*severity, cfg.Severity)
+	if err != nil {
+		return err
+	}
+
+	s, err := common.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// Execute the SQL queries and populate the data structures.
+	report, err := sql.RunQueries(ctx, s, *tableName, selector, *vexTable, *epssTable, bands)
+	if err != nil {
+		return fmt.Errorf("error executing queries: %w", err)
+	}
+	for _, scan := range report.Scans {
+		fmt.Printf("Reporting on scan %d of %s (%s), imported %s.\n", scan.ID, *tableName, scanLabel(scan), scan.ImportedAt.Format(time.RFC3339))
+	}
+	if report.Suppressed > 0 {
+		fmt.Printf("Left out %d findings that VEX statements mark not affected.\n", report.Suppressed)
+	}
+	if len(report.LikelyExploitedVulns) > 0 && report.EPSSDate != "" {
+		fmt.Printf("Ranking by the EPSS scores of %s.\n", report.EPSSDate)
+	}
+
+	// List the open services when an asset inventory has been imported.
+	// Scan IDs belong to the findings table, so only a date carries over.
+	if exists, err := s.TableExists(ctx, *assetsTable); err != nil {
+		return err
+	} else if exists {
+		if report.OpenServices, err = sql.OpenServices(ctx, s, *assetsTable, sql.Selector{Date: selector.Date}); err != nil {
+			return fmt.Errorf("error listing open services: %w", err)
+		}
+	}
+
+	// Write the data to the Excel file.
+	if err := excel.WriteData(*fileLocation, *newFile, report); err != nil {
+		return fmt.Errorf("error writing data to Excel file: %w", err)
+	}
+
+	fmt.Println("Data written to Excel file successfully.")
+	return nil
+}
+
+// severityBands returns the bands of the preset named by the flag, or else
+// the configured bands or preset, or else the default preset.
+func severityBands(flag string, cfg config.Severity) (sql.SeverityBands, error) {
+	if flag != "" {
+		bands, err := sql.BandPreset(flag)
+		if err != nil {
+			return nil, newUsageError("invalid --severity: %v", err)
+		}
+		return bands, nil
+	}
+	if len(cfg.Bands) > 0 {
+		bands := make(sql.SeverityBands, 0, len(cfg.Bands))
+		for _, band := range cfg.Bands {
+			bands = append(bands, sql.SeverityBand{Name: band.Name, Min: band.Min})
+		}
+		if err := bands.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid severity bands in config: %w", err)
+		}
+		return bands, nil
+	}
+	if cfg.Preset != "" {
+		return sql.BandPreset(cfg.Preset)
+	}
+	return sql.BandPreset(sql.DefaultBandPreset)
+}
+
+func runLoadInput(ctx context.Context, args []string) error {
+	fs := newFlagSet("load-input", "Create the input table from the header of a CSV file when it does not exist,\nthen insert the CSV records into it.")
+	common := addCommonFlags(fs)
+	tableName := fs.String("table", "", "table to load (default: input table from config)")
+	csvFilePath := fs.String("csv", "", "path to the CSV file to load (required)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "csv"); err != nil {
+		return err
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		return err
+	}
+	setIfEmpty(tableName, cfg.Tables.Input)
+
+	s, err := common.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := sql.LoadInput(ctx, s, *tableName, *csvFilePath); err != nil {
+		return err
+	}
+
+	fmt.Println("Data insertion completed successfully.")
+	return nil
+}
+
+func runFixNulls(ctx context.Context, args []string) error {
+	fs := newFlagSet("fix-nulls", "Replace every NULL value in the text columns of a table with an empty string.")
+	common := addCommonFlags(fs)
+	tableName := fs.String("table", "", "table to fix (default: source table from config)")
+	scanFlag := fs.String("scan", "latest", "scans to fix: latest (the latest scan of each scanner), a scan ID, or a YYYY-MM-DD date (the latest scan of each scanner up to that day)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	selector, err := sql.ParseSelector(*scanFlag)
+	if err != nil {
+		return newUsageError("%v", err)
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		return err
+	}
+	setIfEmpty(tableName, cfg.Tables.Source)
+
+	s, err := common.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := sql.FixSql(ctx, s, *tableName, selector); err != nil {
+		return err
+	}
+
+	fmt.Println("Update operation completed successfully.")
+	return nil
+}
+
+func runMergeState(ctx context.Context, args []string) error {
+	fs := newFlagSet("merge-state", "Add a column to the source table and fill it with the state of the input\ntable row that has the same id.")
+	common := addCommonFlags(fs)
+	tableName := fs.String("table", "", "table to add the column to (default: source table from config)")
+	inputTable := fs.String("input-table", "", "table to read the state from (default: input table from config)")
+	columnName := fs.String("column", "", "name of the column to add (required)")
+	scanFlag := fs.String("scan", "latest", "scans to fill the column of: latest (the latest scan of each scanner), a scan ID, or a YYYY-MM-DD date (the latest scan of each scanner up to that day)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "column"); err != nil {
+		return err
+	}
+	selector, err := sql.ParseSelector(*scanFlag)
+	if err != nil {
+		return newUsageError("%v", err)
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		return err
+	}
+	setIfEmpty(tableName, cfg.Tables.Source)
+	setIfEmpty(inputTable, cfg.Tables.Input)
+
+	s, err := common.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := sql.MergeState(ctx, s, *tableName, *inputTable, *columnName, selector); err != nil {
+		return err
+	}
+
+	fmt.Println("Update operation completed successfully!")
+	return nil
+}
+
+func runCountByOS(ctx context.Context, args []string) error {
+	fs := newFlagSet("count-by-os", "Count the vulnerabilities of the source table by operating system, severity\nand state, print the counts and store them in the result table.")
+	common := addCommonFlags(fs)
+	tableName := fs.String("table", "", "table to count (default: source table from config)")
+	resultTable := fs.String("result-table", "", "table to store the counts in (default: result table from config)")
+	stateColumn := fs.String("state-column", "", "column holding the state (default: from config, or the last column of the table)")
+	assetsTable := fs.String("assets-table", "", "asset table that fills in missing operating systems by host (default: from config)")
+	scanFlag := fs.String("scan", "latest", "scans to count: latest (the latest scan of each scanner), a scan ID, or a YYYY-MM-DD date (the latest scan of each scanner up to that day)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	selector, err := sql.ParseSelector(*scanFlag)
+	if err != nil {
+		return newUsageError("%v", err)
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		return err
+	}
+	setIfEmpty(tableName, cfg.Tables.Source)
+	setIfEmpty(resultTable, cfg.Tables.Result)
+	setIfEmpty(stateColumn, cfg.Columns.State)
+	setIfEmpty(assetsTable, cfg.Tables.Assets)
+
+	s, err := common.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	vulnerabilities, err := sql.CountByOS(ctx, s, *tableName, *resultTable, *stateColumn, *assetsTable, selector)
+	if err != nil {
+		return err
+	}
+	for _, v := range vulnerabilities {
+		fmt.Printf("Operating System: %s, Severity: %s, State: %s, Count: %d\n", v.OperatingSystem, v.Severity, v.State, v.Count)
+	}
+	return nil
+}
+
+func runMatch(ctx context.Context, args []string) error {
+	fs := newFlagSet("match", "Set a column of the row with the given id to a value.")
+	common := addCommonFlags(fs)
+	tableName := fs.String("table", "", "table to update (default: source table from config)")
+	id := fs.String("id", "", "id of the row to update (required)")
+	columnName := fs.String("column", "", "column to update (required)")
+	value := fs.String("value", "", "value to store")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := requireFlags(fs, "id", "column"); err != nil {
+		return err
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		return err
+	}
+	setIfEmpty(tableName, cfg.Tables.Source)
+
+	s, err := common.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return sql.UpdateMatchStatus(ctx, s, *tableName, *id, *columnName, *value)
+}
+
+func runExport(ctx context.Context, args []string) error {
+	fs := newFlagSet("export", "Write the rows of a table as JSON Lines, one object per row with numbers,\nbooleans and nulls typed after the column types.")
+	common := addCommonFlags(fs)
+	tableName := fs.String("table", "", "table to export (default: source table from config)")
+	output := fs.String("output", "-", "path of the JSON Lines file to write, or - for standard output")
+	scanFlag := fs.String("scan", "latest", "scans to export: latest (the latest scan of each scanner), a scan ID, or a YYYY-MM-DD date (the latest scan of each scanner up to that day)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	selector, err := sql.ParseSelector(*scanFlag)
+	if err != nil {
+		return newUsageError("invalid --scan: %v", err)
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		return err
+	}
+	setIfEmpty(tableName, cfg.Tables.Source)
+
+	s, err := common.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if *output == "-" {
+		_, err := sql.ExportJSONL(ctx, s, *tableName, selector, os.Stdout)
+		return err
+	}
+
+	// Write to a temporary file first so a failed export leaves no partial file
+	file, err := os.CreateTemp(filepath.Dir(*output), "."+filepath.Base(*output)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	rows, err := sql.ExportJSONL(ctx, s, *tableName, selector, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), *output); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d rows of %s to %s.\n", rows, *tableName, *output)
+	return nil
+}
+
+func runScans(ctx context.Context, args []string) error {
+	fs := newFlagSet("scans", "List the scan snapshots of a table, oldest first.")
+	common := addCommonFlags(fs)
+	tableName := fs.String("table", "", "table to list the scans of (default: source table from config)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		return err
+	}
+	setIfEmpty(tableName, cfg.Tables.Source)
+
+	s, err := common.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	scans, err := sql.Scans(ctx, s, *tableName)
+	if err != nil {
+		return err
+	}
+	for _, scan := range scans {
+		fmt.Printf("%d\t%s\t%s\t%s\n", scan.ID, scan.ImportedAt.Format(time.RFC3339), scan.Scanner, scan.SourceFile)
+	}
+	return nil
+}
+
+// scanLabel names the scanner of a scan, or its source file when unlabelled.
+func scanLabel(scan sql.Scan) string {
+	if scan.Scanner != "" {
+		return scan.Scanner
+	}
+	return scan.SourceFile
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sentlab/update-db/sql"
+)
+
+// writeFiles writes files named by their keys into dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// tableHosts returns the Host column of table and the IDs of its scans.
+func tableHosts(t *testing.T, dsn string, table string) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+	s, err := sql.Open(ctx, "sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	rows, err := s.Query(ctx, fmt.Sprintf("SELECT %s FROM %s ORDER BY %s", s.Quote("Host"), s.Quote(table), s.Quote("Host")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var hosts []string
+	for rows.Next() {
+		var host string
+		if err := rows.Scan(&host); err != nil {
+			t.Fatal(err)
+		}
+		hosts = append(hosts, host)
+	}
+
+	scans, err := sql.Scans(ctx, s, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int64, len(scans))
+	for i, scan := range scans {
+		ids[i] = scan.ID
+	}
+	return fmt.Sprint(hosts), fmt.Sprint(ids)
+}
+
+func TestImportReplaceDirectory(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		files     map[string]string
+		fails     bool
+		wantHosts string
+		wantScans string
+	}{
+		{
+			name: "second file invalid",
+			files: map[string]string{
+				"1-new.csv": "Host,Name,CVE,CVSS\nnew1,n1,CVE-1,9.8\n",
+				"2-bad.csv": "Host,Name,CVE,CVSS\nnew2,n2,CVE-2,high\n",
+			},
+			fails:     true,
+			wantHosts: "[old1]",
+			wantScans: "[1]",
+		},
+		{
+			name: "both valid",
+			files: map[string]string{
+				"1-new.csv": "Host,Name,CVE,CVSS\nnew1,n1,CVE-1,9.8\n",
+				"2-new.csv": "Host,Name,CVE,CVSS\nnew2,n2,CVE-2,5.0\n",
+			},
+			wantHosts: "[new1 new2]",
+			wantScans: "[2]",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			dsn := filepath.Join(dir, "vulns.db")
+			writeFiles(t, dir, map[string]string{"old.csv": "Host,Name,CVE,CVSS\nold1,n0,CVE-0,5.0\n"})
+			if err := runImport(ctx, []string{"--dsn", dsn, "--table", "vulns", "--csv", filepath.Join(dir, "old.csv"), "--create", "--quiet"}); err != nil {
+				t.Fatal(err)
+			}
+
+			inputs := filepath.Join(dir, "inputs")
+			writeFiles(t, inputs, tt.files)
+			err := runImport(ctx, []string{"--dsn", dsn, "--table", "vulns", "--file", inputs, "--replace", "--quiet"})
+			if (err != nil) != tt.fails {
+				t.Fatalf("import --replace of the directory = %v, want failure %v", err, tt.fails)
+			}
+			hosts, scans := tableHosts(t, dsn, "vulns")
+			if hosts != tt.wantHosts || scans != tt.wantScans {
+				t.Errorf("after import --replace: hosts %s, scans %s; want hosts %s, scans %s", hosts, scans, tt.wantHosts, tt.wantScans)
+			}
+		})
+	}
+}
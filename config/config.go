@@ -0,0 +1,246 @@
+// Package config loads the update-db configuration file
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the configuration file read when no path is given.
+const DefaultPath = "update-db.yaml"
+
+// PathEnv names the environment variable that points at the configuration file.
+const PathEnv = "UPDATEDB_CONFIG"
+
+// Config holds the settings shared by every command.
+type Config struct {
+	Database Database `yaml:"database"`
+	Tables   Tables   `yaml:"tables"`
+	Columns  Columns  `yaml:"columns"`
+	Output   Output   `yaml:"output"`
+	Import   Import   `yaml:"import"`
+	Severity Severity `yaml:"severity"`
+}
+
+// Database selects the backend and how to reach it.
+type Database struct {
+	Driver string `yaml:"driver"`
+	DSN    string `yaml:"dsn"`
+}
+
+// Tables names the tables the commands read and write.
+type Tables struct {
+	Source     string `yaml:"source"`
+	Input      string `yaml:"input"`
+	Result     string `yaml:"result"`
+	Assets     string `yaml:"assets"`
+	Components string `yaml:"components"`
+	VEX        string `yaml:"vex"`
+	EPSS       string `yaml:"epss"`
+}
+
+// Columns names the columns with a special meaning.
+type Columns struct {
+	// State is the column of the source table that holds the finding state.
+	// When empty the last column of the source table is used.
+	State string `yaml:"state"`
+}
+
+// Output sets where the Excel workbooks are read from and written to.
+type Output struct {
+	Workbook string `yaml:"workbook"`
+	Report   string `yaml:"report"`
+}
+
+// Severity sets the CVSS score bands the reports count findings by.
+type Severity struct {
+	// Preset names built-in bands: first, the FIRST CVSS v3 scale, or
+	// five-band. It is ignored when Bands are set.
+	Preset string `yaml:"preset"`
+	// Bands are the bands, lowest first, each from its min score up to the
+	// min of the next.
+	Bands []Band `yaml:"bands"`
+}
+
+// Band names the CVSS scores from Min up to the Min of the next band.
+type Band struct {
+	Name string  `yaml:"name"`
+	Min  float64 `yaml:"min"`
+}
+
+// Import controls how source files are matched to table columns.
+type Import struct {
+	// Mapping is the path of a YAML file that maps source headers to table columns.
+	Mapping string `yaml:"mapping"`
+	// Profiles is the path of a YAML file of header profiles, tried before
+	// the built-in ones to recognize CSV files and workbooks.
+	Profiles string `yaml:"profiles"`
+	// Required lists the columns every import must provide.
+	Required []string `yaml:"required"`
+	// BatchSize is the number of rows sent per INSERT statement.
+	BatchSize int `yaml:"batch_size"`
+	// TxRows is the number of rows committed per transaction.
+	TxRows int `yaml:"tx_rows"`
+	// SampleRows is the number of rows inspected to infer the column types
+	// of a table created by the import.
+	SampleRows int `yaml:"sample_rows"`
+	// Scanner labels the scans recorded by the import.
+	Scanner string `yaml:"scanner"`
+	// SARIF tunes the import of static analysis results.
+	SARIF SARIF `yaml:"sarif"`
+	// JSONL tunes the import of JSON Lines findings.
+	JSONL JSONL `yaml:"jsonl"`
+}
+
+// JSONL tunes the import of JSON Lines files.
+type JSONL struct {
+	// Fields maps table columns to the dotted paths of the fields that fill
+	// them; every field is a column when empty.
+	Fields map[string]string `yaml:"fields"`
+}
+
+// SARIF tunes the import of SARIF files.
+type SARIF struct {
+	// Levels maps result levels (error, warning, note, none) to the severity
+	// and CVSS score their findings are reported with.
+	Levels map[string]Level `yaml:"levels"`
+	// Repository names the asset of results that lack version control
+	// details; the file name is used when empty.
+	Repository string `yaml:"repository"`
+}
+
+// Level is the severity and CVSS score of a SARIF result level.
+type Level struct {
+	Severity string  `yaml:"severity"`
+	CVSS     float64 `yaml:"cvss"`
+}
+
+// Profile recognizes the CSV export of a scanner by its header.
+type Profile struct {
+	Name string `yaml:"name"`
+	// Detect lists the headers that identify the export.
+	Detect []string `yaml:"detect"`
+	// Columns maps table columns to the headers that fill them, the
+	// preferred header first.
+	Columns map[string][]string `yaml:"columns"`
+	// Severities maps the values of the severity header to severity names.
+	Severities map[string]string `yaml:"severities"`
+}
+
+// Default returns the configuration used when no file or override sets a value.
+func Default() *Config {
+	return &Config{
+		Database: Database{DSN: "vulns.db"},
+		Tables: Tables{
+			Source:     "PopularBank",
+			Input:      "Data_Input",
+			Result:     "ResultTable",
+			Assets:     "assets",
+			Components: "components",
+			VEX:        "vex",
+			EPSS:       "epss",
+		},
+		Import: Import{
+			Required:   []string{"Host", "Name", "CVSS", "CVE"},
+			BatchSize:  500,
+			TxRows:     50000,
+			SampleRows: 1000,
+		},
+	}
+}
+
+// Load reads the configuration file at path on top of the defaults and then
+// applies the environment overrides. An empty path falls back to the file
+// named by UPDATEDB_CONFIG, then to DefaultPath when it exists.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv(PathEnv)
+		explicit = path != ""
+	}
+	if path == "" {
+		path = DefaultPath
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	case errors.Is(err, fs.ErrNotExist) && !explicit:
+		// No config file; run on defaults and environment only.
+	default:
+		return nil, fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	cfg.applyEnv()
+	return cfg, nil
+}
+
+// applyEnv overrides settings with the UPDATEDB_* environment variables.
+func (c *Config) applyEnv() {
+	overrides := []struct {
+		env   string
+		value *string
+	}{
+		{"UPDATEDB_DRIVER", &c.Database.Driver},
+		{"UPDATEDB_DSN", &c.Database.DSN},
+		{"UPDATEDB_SOURCE_TABLE", &c.Tables.Source},
+		{"UPDATEDB_INPUT_TABLE", &c.Tables.Input},
+		{"UPDATEDB_RESULT_TABLE", &c.Tables.Result},
+		{"UPDATEDB_ASSETS_TABLE", &c.Tables.Assets},
+		{"UPDATEDB_COMPONENTS_TABLE", &c.Tables.Components},
+		{"UPDATEDB_VEX_TABLE", &c.Tables.VEX},
+		{"UPDATEDB_EPSS_TABLE", &c.Tables.EPSS},
+		{"UPDATEDB_STATE_COLUMN", &c.Columns.State},
+		{"UPDATEDB_WORKBOOK", &c.Output.Workbook},
+		{"UPDATEDB_REPORT", &c.Output.Report},
+		{"UPDATEDB_MAPPING", &c.Import.Mapping},
+		{"UPDATEDB_PROFILES", &c.Import.Profiles},
+		{"UPDATEDB_SCANNER", &c.Import.Scanner},
+		{"UPDATEDB_SEVERITY_PRESET", &c.Severity.Preset},
+	}
+	for _, o := range overrides {
+		if v, ok := os.LookupEnv(o.env); ok {
+			*o.value = v
+		}
+	}
+}
+
+// LoadMapping reads a YAML file of "source header: table column" pairs.
+func LoadMapping(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read mapping file: %w", err)
+	}
+	mapping := map[string]string{}
+	if err := yaml.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("invalid mapping file %s: %w", path, err)
+	}
+	return mapping, nil
+}
+
+// LoadProfiles reads a YAML list of header profiles.
+func LoadProfiles(path string) ([]Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read profiles file: %w", err)
+	}
+	var profiles []Profile
+	if err := yaml.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid profiles file %s: %w", path, err)
+	}
+	for i, profile := range profiles {
+		if profile.Name == "" || len(profile.Detect) == 0 {
+			return nil, fmt.Errorf("invalid profiles file %s: profile %d needs a name and the headers to detect it by", path, i+1)
+		}
+	}
+	return profiles, nil
+}
@@ -5,7 +5,8 @@ import (
 	"os"
 )
 
-// ReadCSV reads a CSV file and returns the records.
+// ReadCSV reads a whole CSV file into memory and returns the records. Use
+// OpenReader for files of unbounded size.
 func ReadCSV(filePath string) ([][]string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -0,0 +1,67 @@
+package csv
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+)
+
+// Reader streams the records of a CSV file one at a time, so memory use does
+// not grow with the size of the file.
+type Reader struct {
+	closer  io.Closer
+	counter *countingReader
+	reader  *csv.Reader
+}
+
+// OpenReader opens the CSV file at filePath for streaming.
+func OpenReader(filePath string) (*Reader, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	reader := NewReader(file)
+	reader.closer = file
+	return reader, nil
+}
+
+// NewReader streams the CSV records of r.
+func NewReader(r io.Reader) *Reader {
+	counter := &countingReader{r: r}
+	reader := csv.NewReader(counter)
+	reader.FieldsPerRecord = -1 // Allow variable number of fields
+	reader.ReuseRecord = true
+	return &Reader{counter: counter, reader: reader}
+}
+
+// Read returns the next record. The returned slice is reused by the next
+// call, so callers that keep a record must copy it. It returns io.EOF at the
+// end of the file.
+func (r *Reader) Read() ([]string, error) {
+	return r.reader.Read()
+}
+
+// BytesRead returns the number of bytes consumed from the input so far.
+func (r *Reader) BytesRead() int64 {
+	return r.counter.n
+}
+
+// Close closes the file opened by OpenReader.
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
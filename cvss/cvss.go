@@ -0,0 +1,313 @@
+// Package cvss parses CVSS v2, v3.0, v3.1 and v4.0 vectors and computes
+// their scores.
+package cvss
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Versions of the specification a vector follows.
+const (
+	V2  = "2.0"
+	V30 = "3.0"
+	V31 = "3.1"
+	V40 = "4.0"
+)
+
+// Vector is a parsed CVSS vector.
+type Vector struct {
+	// Version is the version of the specification, e.g. V31.
+	Version string
+	// metrics maps metric abbreviations to their values as given.
+	metrics map[string]string
+	// order lists the metrics in the order they were given.
+	order []string
+}
+
+// metric is a metric of a version: its abbreviation, its values and
+// whether a vector must hold it.
+type metric struct {
+	name     string
+	values   []string
+	required bool
+}
+
+// Parse parses a CVSS vector. Versions 3.0, 3.1 and 4.0 are told by their
+// CVSS:3.0/, CVSS:3.1/ or CVSS:4.0/ prefix; vectors without one are v2, as
+// are those with the CVSS2# prefix Nessus writes. Surrounding parentheses
+// are ignored.
+func Parse(text string) (*Vector, error) {
+	body := strings.TrimSpace(text)
+	body = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(body, "("), ")"))
+	if body == "" {
+		return nil, fmt.Errorf("empty CVSS vector")
+	}
+
+	version := V2
+	switch {
+	case strings.HasPrefix(body, "CVSS2#"):
+		body = body[len("CVSS2#"):]
+	case strings.HasPrefix(body, "CVSS:"):
+		prefix, rest, _ := strings.Cut(body, "/")
+		version, body = strings.TrimPrefix(prefix, "CVSS:"), rest
+	}
+	metrics, ok := versionMetrics[version]
+	if !ok {
+		return nil, fmt.Errorf("invalid CVSS vector %q: unsupported version %s", text, version)
+	}
+
+	v := &Vector{Version: version, metrics: map[string]string{}}
+	for _, part := range strings.Split(body, "/") {
+		name, value, found := strings.Cut(part, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid CVSS vector %q: malformed metric %q", text, part)
+		}
+		m, known := findMetric(metrics, name)
+		if !known {
+			return nil, fmt.Errorf("invalid CVSS vector %q: unknown metric %s for version %s", text, name, version)
+		}
+		if _, seen := v.metrics[name]; seen {
+			return nil, fmt.Errorf("invalid CVSS vector %q: metric %s given twice", text, name)
+		}
+		if !contains(m.values, value) {
+			return nil, fmt.Errorf("invalid CVSS vector %q: invalid value %s for metric %s", text, value, name)
+		}
+		v.metrics[name] = value
+		v.order = append(v.order, name)
+	}
+	for _, m := range metrics {
+		if _, ok := v.metrics[m.name]; m.required && !ok {
+			return nil, fmt.Errorf("invalid CVSS vector %q: missing metric %s", text, m.name)
+		}
+	}
+	return v, nil
+}
+
+// CompareVersions compares two versions of the specification, such as V31
+// and V40, by their numbers. It returns -1, 0 or +1 as a is older than, the
+// same as or newer than b. Versions that are not numbers are older than any
+// that are.
+func CompareVersions(a, b string) int {
+	x, y := versionNumbers(a), versionNumbers(b)
+	for i := 0; i < len(x) || i < len(y); i++ {
+		var m, n int
+		if i < len(x) {
+			m = x[i]
+		}
+		if i < len(y) {
+			n = y[i]
+		}
+		switch {
+		case m < n:
+			return -1
+		case m > n:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionNumbers returns the numbers of a version, e.g. [3 1] for 3.1, or
+// [-1] when it is not made of numbers.
+func versionNumbers(version string) []int {
+	var numbers []int
+	for _, part := range strings.Split(strings.TrimSpace(version), ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return []int{-1}
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+// String returns the vector as text, with the prefix of its version.
+func (v *Vector) String() string {
+	parts := make([]string, 0, len(v.order)+1)
+	if v.Version != V2 {
+		parts = append(parts, "CVSS:"+v.Version)
+	}
+	for _, name := range v.order {
+		parts = append(parts, name+":"+v.metrics[name])
+	}
+	return strings.Join(parts, "/")
+}
+
+// Metric returns the value of the metric with the given abbreviation, e.g.
+// AV, as given, or an empty string when the vector does not hold it.
+func (v *Vector) Metric(name string) string {
+	return v.metrics[name]
+}
+
+// BaseScore returns the score of the base metrics.
+func (v *Vector) BaseScore() float64 {
+	switch v.Version {
+	case V2:
+		return v.v2Base()
+	case V40:
+		return v.v4Score(false, false)
+	}
+	return v.v3Base()
+}
+
+// TemporalScore returns the score of the base and temporal metrics, which
+// v4.0 calls the threat metrics. It is the base score when the vector holds
+// none of them.
+func (v *Vector) TemporalScore() float64 {
+	switch v.Version {
+	case V2:
+		return v.v2Temporal()
+	case V40:
+		return v.v4Score(true, false)
+	}
+	return v.v3Temporal()
+}
+
+// EnvironmentalScore returns the score of every metric of the vector.
+func (v *Vector) EnvironmentalScore() float64 {
+	switch v.Version {
+	case V2:
+		return v.v2Environmental()
+	case V40:
+		return v.v4Score(true, true)
+	}
+	return v.v3Environmental()
+}
+
+// Score returns the most specific score of the vector: the environmental
+// score when it holds environmental metrics, otherwise the temporal score.
+func (v *Vector) Score() float64 {
+	for _, name := range environmentalMetrics[v.Version] {
+		if v.defined(name) {
+			return v.EnvironmentalScore()
+		}
+	}
+	return v.TemporalScore()
+}
+
+// AttackVector names the attack vector: Network, Adjacent, Local or
+// Physical.
+func (v *Vector) AttackVector() string { return v.component("AV") }
+
+// AttackComplexity names the attack complexity: Low, Medium or High.
+func (v *Vector) AttackComplexity() string { return v.component("AC") }
+
+// PrivilegesRequired names the privileges required: None, Low or High. It
+// is empty for v2, which rates the authentication required instead.
+func (v *Vector) PrivilegesRequired() string { return v.component("PR") }
+
+// UserInteraction names the user interaction: None, Required, or for v4.0
+// Passive or Active. It is empty for v2.
+func (v *Vector) UserInteraction() string { return v.component("UI") }
+
+// Scope names the scope of v3 vectors: Unchanged or Changed. It is empty
+// for other versions.
+func (v *Vector) Scope() string { return v.component("S") }
+
+// Confidentiality names the confidentiality impact: None, Low or High, or
+// for v2 None, Partial or Complete. For v4.0 it is the impact on the
+// vulnerable system.
+func (v *Vector) Confidentiality() string { return v.impact("C", "VC") }
+
+// Integrity names the integrity impact, like Confidentiality.
+func (v *Vector) Integrity() string { return v.impact("I", "VI") }
+
+// Availability names the availability impact, like Confidentiality.
+func (v *Vector) Availability() string { return v.impact("A", "VA") }
+
+// impact names the value of the impact metric of the version.
+func (v *Vector) impact(name, v4Name string) string {
+	if v.Version == V40 {
+		return v.component(v4Name)
+	}
+	return v.component(name)
+}
+
+// component names the value of a base metric.
+func (v *Vector) component(name string) string {
+	if v.Version == V2 && (name == "PR" || name == "UI") {
+		return ""
+	}
+	if v.Version == V40 && name == "S" {
+		// S is the Safety supplemental metric of v4.0
+		return ""
+	}
+	value := v.metrics[name]
+	if v.Version == V2 {
+		if named, ok := v2Names[name][value]; ok {
+			return named
+		}
+	}
+	return valueNames[name][value]
+}
+
+// defined reports whether the vector holds the metric with a value other
+// than Not Defined.
+func (v *Vector) defined(name string) bool {
+	value := v.metrics[name]
+	return value != "" && value != "X" && value != "ND"
+}
+
+// valueNames name the values of the base metrics.
+var valueNames = map[string]map[string]string{
+	"AV": {"N": "Network", "A": "Adjacent", "L": "Local", "P": "Physical"},
+	"AC": {"L": "Low", "M": "Medium", "H": "High"},
+	"PR": {"N": "None", "L": "Low", "H": "High"},
+	"UI": {"N": "None", "R": "Required", "P": "Passive", "A": "Active"},
+	"S":  {"U": "Unchanged", "C": "Changed"},
+	"C":  {"N": "None", "L": "Low", "H": "High"},
+	"I":  {"N": "None", "L": "Low", "H": "High"},
+	"A":  {"N": "None", "L": "Low", "H": "High"},
+	"VC": {"N": "None", "L": "Low", "H": "High"},
+	"VI": {"N": "None", "L": "Low", "H": "High"},
+	"VA": {"N": "None", "L": "Low", "H": "High"},
+}
+
+// v2Names name the v2 values that differ from the later versions.
+var v2Names = map[string]map[string]string{
+	"C": {"N": "None", "P": "Partial", "C": "Complete"},
+	"I": {"N": "None", "P": "Partial", "C": "Complete"},
+	"A": {"N": "None", "P": "Partial", "C": "Complete"},
+}
+
+// versionMetrics lists the metrics of each version.
+var versionMetrics = map[string][]metric{
+	V2:  v2Metrics,
+	V30: v3Metrics,
+	V31: v3Metrics,
+	V40: v4Metrics,
+}
+
+// environmentalMetrics lists the environmental metrics of each version.
+var environmentalMetrics = map[string][]string{
+	V2:  {"CDP", "TD", "CR", "IR", "AR"},
+	V30: v3Environmental,
+	V31: v3Environmental,
+	V40: {"CR", "IR", "AR", "MAV", "MAC", "MAT", "MPR", "MUI", "MVC", "MVI", "MVA", "MSC", "MSI", "MSA"},
+}
+
+func findMetric(metrics []metric, name string) (metric, bool) {
+	for _, m := range metrics {
+		if m.name == name {
+			return m, true
+		}
+	}
+	return metric{}, false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// round1 rounds to one decimal, halves away from zero.
+func round1(x float64) float64 {
+	return math.Round(x*10) / 10
+}
@@ -0,0 +1,111 @@
+package cvss
+
+import "testing"
+
+func TestScores(t *testing.T) {
+	tests := []struct {
+		vector                        string
+		version                       string
+		base, temporal, environmental float64
+	}{
+		// CVSS v2, with the examples of the v2 guide
+		{"AV:N/AC:L/Au:N/C:C/I:C/A:C", V2, 10.0, 10.0, 10.0},
+		{"AV:N/AC:M/Au:N/C:P/I:P/A:P", V2, 6.8, 6.8, 6.8},
+		{"(AV:N/AC:L/Au:N/C:P/I:P/A:P)", V2, 7.5, 7.5, 7.5},
+		{"CVSS2#AV:N/AC:L/Au:N/C:N/I:N/A:C/E:F/RL:OF/RC:C/CDP:H/TD:H/CR:M/IR:M/AR:H", V2, 7.8, 6.4, 9.2},
+		{"AV:N/AC:L/Au:N/C:C/I:C/A:C/E:F/RL:OF/RC:C/CDP:H/TD:H/CR:M/IR:M/AR:L", V2, 10.0, 8.3, 9.0},
+
+		// CVSS v3.x, with the examples of the v3.1 specification
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", V31, 9.8, 9.8, 9.8},
+		// The modified impact of a changed scope follows another curve
+		{"CVSS:3.1/AV:N/AC:L/PR:L/UI:N/S:C/C:H/I:H/A:H", V31, 9.9, 9.9, 10.0},
+		{"CVSS:3.1/AV:L/AC:H/PR:H/UI:R/S:U/C:L/I:N/A:N", V31, 1.8, 1.8, 1.8},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", V31, 6.1, 6.1, 6.1},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N", V31, 7.5, 7.5, 7.5},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/E:P/RL:O/RC:C", V31, 9.8, 8.8, 8.8},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/MAV:L", V31, 9.8, 9.8, 8.4},
+		{"CVSS:3.0/AV:L/AC:L/PR:N/UI:R/S:U/C:H/I:H/A:H", V30, 7.8, 7.8, 7.8},
+
+		// CVSS v4.0, where a vector at the top of its macrovector scores
+		// the macrovector
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N", V40, 9.3, 9.3, 9.3},
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:H/SI:H/SA:H", V40, 10.0, 10.0, 10.0},
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:L/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N", V40, 8.7, 8.7, 8.7},
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:L/VI:L/VA:L/SC:N/SI:N/SA:N", V40, 6.9, 6.9, 6.9},
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:N/VI:N/VA:N/SC:N/SI:N/SA:N", V40, 0.0, 0.0, 0.0},
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N/E:U", V40, 9.3, 8.1, 8.1},
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N/MAV:P", V40, 9.3, 9.3, 7.0},
+		// Vectors below the top of their macrovector, as the FIRST
+		// calculator scores them
+		{"CVSS:4.0/AV:L/AC:L/AT:N/PR:L/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N", V40, 8.5, 8.5, 8.5},
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:N/VA:N/SC:N/SI:N/SA:N", V40, 8.7, 8.7, 8.7},
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:L/UI:N/VC:N/VI:N/VA:H/SC:N/SI:N/SA:N", V40, 7.1, 7.1, 7.1},
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:L/UI:N/VC:L/VI:N/VA:N/SC:N/SI:N/SA:N", V40, 5.3, 5.3, 5.3},
+		{"CVSS:4.0/AV:N/AC:L/AT:N/PR:L/UI:P/VC:N/VI:N/VA:N/SC:L/SI:L/SA:N", V40, 5.1, 5.1, 5.1},
+	}
+	for _, tt := range tests {
+		v, err := Parse(tt.vector)
+		if err != nil {
+			t.Errorf("Parse(%q) = %v", tt.vector, err)
+			continue
+		}
+		if v.Version != tt.version {
+			t.Errorf("Parse(%q).Version = %s, want %s", tt.vector, v.Version, tt.version)
+		}
+		if got := v.BaseScore(); got != tt.base {
+			t.Errorf("%s: BaseScore() = %.1f, want %.1f", tt.vector, got, tt.base)
+		}
+		if got := v.TemporalScore(); got != tt.temporal {
+			t.Errorf("%s: TemporalScore() = %.1f, want %.1f", tt.vector, got, tt.temporal)
+		}
+		if got := v.EnvironmentalScore(); got != tt.environmental {
+			t.Errorf("%s: EnvironmentalScore() = %.1f, want %.1f", tt.vector, got, tt.environmental)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{V2, V30, -1},
+		{V30, V31, -1},
+		{V31, V40, -1},
+		{V40, V31, 1},
+		{V31, V31, 0},
+		{"10.0", V40, 1},
+		{"4", V40, 0},
+		{"3.10", V31, 1},
+		{"", V2, -1},
+		{"x", "y", 0},
+	} {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, vector := range []string{
+		"",
+		"()",
+		"CVSS:5.0/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		"CVSS:3.1/AV:N/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		"CVSS:3.1/AV:X/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		"CVSS:3.1/AV:N/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/XX:Y",
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A",
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/",
+		"CVSS:4.0/AV:N/AC:L/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N",
+		"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N/E:P/E:A",
+		"AV:N/AC:L/Au:N/C:C/I:C",
+		"AV:N/AC:L/Au:N/C:C/I:C/A:X",
+		"AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+		"not a vector",
+	} {
+		if v, err := Parse(vector); err == nil {
+			t.Errorf("Parse(%q) = %s, want an error", vector, v)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package cvss
+
+import "math"
+
+var v2Metrics = []metric{
+	{name: "AV", values: []string{"L", "A", "N"}, required: true},
+	{name: "AC", values: []string{"H", "M", "L"}, required: true},
+	{name: "Au", values: []string{"M", "S", "N"}, required: true},
+	{name: "C", values: []string{"N", "P", "C"}, required: true},
+	{name: "I", values: []string{"N", "P", "C"}, required: true},
+	{name: "A", values: []string{"N", "P", "C"}, required: true},
+	{name: "E", values: []string{"U", "POC", "F", "H", "ND"}},
+	{name: "RL", values: []string{"OF", "TF", "W", "U", "ND"}},
+	{name: "RC", values: []string{"UC", "UR", "C", "ND"}},
+	{name: "CDP", values: []string{"N", "L", "LM", "MH", "H", "ND"}},
+	{name: "TD", values: []string{"N", "L", "M", "H", "ND"}},
+	{name: "CR", values: []string{"L", "M", "H", "ND"}},
+	{name: "IR", values: []string{"L", "M", "H", "ND"}},
+	{name: "AR", values: []string{"L", "M", "H", "ND"}},
+}
+
+// v2Weights are the numbers the v2 equations give metric values. Metrics
+// a vector leaves out weigh as Not Defined.
+var v2Weights = map[string]map[string]float64{
+	"AV":  {"L": 0.395, "A": 0.646, "N": 1.0},
+	"AC":  {"H": 0.35, "M": 0.61, "L": 0.71},
+	"Au":  {"M": 0.45, "S": 0.56, "N": 0.704},
+	"C":   {"N": 0, "P": 0.275, "C": 0.660},
+	"I":   {"N": 0, "P": 0.275, "C": 0.660},
+	"A":   {"N": 0, "P": 0.275, "C": 0.660},
+	"E":   {"U": 0.85, "POC": 0.9, "F": 0.95, "H": 1, "ND": 1, "": 1},
+	"RL":  {"OF": 0.87, "TF": 0.90, "W": 0.95, "U": 1, "ND": 1, "": 1},
+	"RC":  {"UC": 0.90, "UR": 0.95, "C": 1, "ND": 1, "": 1},
+	"CDP": {"N": 0, "L": 0.1, "LM": 0.3, "MH": 0.4, "H": 0.5, "ND": 0, "": 0},
+	"TD":  {"N": 0, "L": 0.25, "M": 0.75, "H": 1, "ND": 1, "": 1},
+	"CR":  {"L": 0.5, "M": 1, "H": 1.51, "ND": 1, "": 1},
+	"IR":  {"L": 0.5, "M": 1, "H": 1.51, "ND": 1, "": 1},
+	"AR":  {"L": 0.5, "M": 1, "H": 1.51, "ND": 1, "": 1},
+}
+
+func (v *Vector) v2Weight(name string) float64 {
+	return v2Weights[name][v.metrics[name]]
+}
+
+func (v *Vector) v2Base() float64 {
+	impact := 10.41 * (1 - (1-v.v2Weight("C"))*(1-v.v2Weight("I"))*(1-v.v2Weight("A")))
+	return v.v2Equation(impact)
+}
+
+// v2Equation returns the base score for an impact subscore.
+func (v *Vector) v2Equation(impact float64) float64 {
+	exploitability := 20 * v.v2Weight("AV") * v.v2Weight("AC") * v.v2Weight("Au")
+	if impact == 0 {
+		return 0
+	}
+	return round1((0.6*impact + 0.4*exploitability - 1.5) * 1.176)
+}
+
+// v2Adjust weighs a base score with the temporal metrics.
+func (v *Vector) v2Adjust(base float64) float64 {
+	return round1(base * v.v2Weight("E") * v.v2Weight("RL") * v.v2Weight("RC"))
+}
+
+func (v *Vector) v2Temporal() float64 {
+	return v.v2Adjust(v.v2Base())
+}
+
+func (v *Vector) v2Environmental() float64 {
+	impact := math.Min(10, 10.41*(1-
+		(1-v.v2Weight("C")*v.v2Weight("CR"))*
+			(1-v.v2Weight("I")*v.v2Weight("IR"))*
+			(1-v.v2Weight("A")*v.v2Weight("AR"))))
+	temporal := v.v2Adjust(v.v2Equation(impact))
+	return round1((temporal + (10-temporal)*v.v2Weight("CDP")) * v.v2Weight("TD"))
+}
@@ -0,0 +1,129 @@
+package cvss
+
+import "math"
+
+var v3Metrics = []metric{
+	{name: "AV", values: []string{"N", "A", "L", "P"}, required: true},
+	{name: "AC", values: []string{"L", "H"}, required: true},
+	{name: "PR", values: []string{"N", "L", "H"}, required: true},
+	{name: "UI", values: []string{"N", "R"}, required: true},
+	{name: "S", values: []string{"U", "C"}, required: true},
+	{name: "C", values: []string{"H", "L", "N"}, required: true},
+	{name: "I", values: []string{"H", "L", "N"}, required: true},
+	{name: "A", values: []string{"H", "L", "N"}, required: true},
+	{name: "E", values: []string{"X", "H", "F", "P", "U"}},
+	{name: "RL", values: []string{"X", "U", "W", "T", "O"}},
+	{name: "RC", values: []string{"X", "C", "R", "U"}},
+	{name: "CR", values: []string{"X", "H", "M", "L"}},
+	{name: "IR", values: []string{"X", "H", "M", "L"}},
+	{name: "AR", values: []string{"X", "H", "M", "L"}},
+	{name: "MAV", values: []string{"X", "N", "A", "L", "P"}},
+	{name: "MAC", values: []string{"X", "L", "H"}},
+	{name: "MPR", values: []string{"X", "N", "L", "H"}},
+	{name: "MUI", values: []string{"X", "N", "R"}},
+	{name: "MS", values: []string{"X", "U", "C"}},
+	{name: "MC", values: []string{"X", "H", "L", "N"}},
+	{name: "MI", values: []string{"X", "H", "L", "N"}},
+	{name: "MA", values: []string{"X", "H", "L", "N"}},
+}
+
+var v3Environmental = []string{"CR", "IR", "AR", "MAV", "MAC", "MPR", "MUI", "MS", "MC", "MI", "MA"}
+
+// v3Weights are the numbers the v3 equations give metric values. Metrics
+// a vector leaves out weigh as Not Defined. Privileges required weigh by
+// scope; see v3Privileges.
+var v3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"UI": {"N": 0.85, "R": 0.62},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+	"E":  {"X": 1, "H": 1, "F": 0.97, "P": 0.94, "U": 0.91, "": 1},
+	"RL": {"X": 1, "U": 1, "W": 0.97, "T": 0.96, "O": 0.95, "": 1},
+	"RC": {"X": 1, "C": 1, "R": 0.96, "U": 0.92, "": 1},
+	"CR": {"X": 1, "H": 1.5, "M": 1, "L": 0.5, "": 1},
+	"IR": {"X": 1, "H": 1.5, "M": 1, "L": 0.5, "": 1},
+	"AR": {"X": 1, "H": 1.5, "M": 1, "L": 0.5, "": 1},
+}
+
+// v3Privileges weigh privileges required when the scope is unchanged and
+// when it is changed.
+var v3Privileges = map[bool]map[string]float64{
+	false: {"N": 0.85, "L": 0.62, "H": 0.27},
+	true:  {"N": 0.85, "L": 0.68, "H": 0.5},
+}
+
+// modified returns the value of the modified form of a base metric, or of
+// the base metric when the modified one is not defined.
+func (v *Vector) modified(name string) string {
+	if value := v.metrics["M"+name]; value != "" && value != "X" {
+		return value
+	}
+	return v.metrics[name]
+}
+
+// roundUp rounds up to one decimal the way the version specifies. v3.1
+// works around floating point errors such as 4.000000000000001.
+func (v *Vector) roundUp(x float64) float64 {
+	if v.Version == V30 {
+		return math.Ceil(x*10) / 10
+	}
+	n := math.Round(x * 100000)
+	if math.Mod(n, 10000) == 0 {
+		return n / 100000
+	}
+	return (math.Floor(n/10000) + 1) / 10
+}
+
+func (v *Vector) v3Base() float64 {
+	changed := v.metrics["S"] == "C"
+	iss := 1 - (1-v3Weights["C"][v.metrics["C"]])*(1-v3Weights["I"][v.metrics["I"]])*(1-v3Weights["A"][v.metrics["A"]])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	exploitability := 8.22 * v3Weights["AV"][v.metrics["AV"]] * v3Weights["AC"][v.metrics["AC"]] *
+		v3Privileges[changed][v.metrics["PR"]] * v3Weights["UI"][v.metrics["UI"]]
+	if impact <= 0 {
+		return 0
+	}
+	if changed {
+		return v.roundUp(math.Min(1.08*(impact+exploitability), 10))
+	}
+	return v.roundUp(math.Min(impact+exploitability, 10))
+}
+
+// v3TemporalWeight is the product of the temporal weights.
+func (v *Vector) v3TemporalWeight() float64 {
+	return v3Weights["E"][v.metrics["E"]] * v3Weights["RL"][v.metrics["RL"]] * v3Weights["RC"][v.metrics["RC"]]
+}
+
+func (v *Vector) v3Temporal() float64 {
+	return v.roundUp(v.v3Base() * v.v3TemporalWeight())
+}
+
+func (v *Vector) v3Environmental() float64 {
+	changed := v.modified("S") == "C"
+	miss := math.Min(1-
+		(1-v3Weights["CR"][v.metrics["CR"]]*v3Weights["C"][v.modified("C")])*
+			(1-v3Weights["IR"][v.metrics["IR"]]*v3Weights["I"][v.modified("I")])*
+			(1-v3Weights["AR"][v.metrics["AR"]]*v3Weights["A"][v.modified("A")]), 0.915)
+	impact := 6.42 * miss
+	if changed {
+		if v.Version == V30 {
+			impact = 7.52*(miss-0.029) - 3.25*math.Pow(miss-0.02, 15)
+		} else {
+			impact = 7.52*(miss-0.029) - 3.25*math.Pow(miss*0.9731-0.02, 13)
+		}
+	}
+	exploitability := 8.22 * v3Weights["AV"][v.modified("AV")] * v3Weights["AC"][v.modified("AC")] *
+		v3Privileges[changed][v.modified("PR")] * v3Weights["UI"][v.modified("UI")]
+	if impact <= 0 {
+		return 0
+	}
+	if changed {
+		return v.roundUp(v.roundUp(math.Min(1.08*(impact+exploitability), 10)) * v.v3TemporalWeight())
+	}
+	return v.roundUp(v.roundUp(math.Min(impact+exploitability, 10)) * v.v3TemporalWeight())
+}
@@ -0,0 +1,280 @@
+package cvss
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+var v4Metrics = []metric{
+	{name: "AV", values: []string{"N", "A", "L", "P"}, required: true},
+	{name: "AC", values: []string{"L", "H"}, required: true},
+	{name: "AT", values: []string{"N", "P"}, required: true},
+	{name: "PR", values: []string{"N", "L", "H"}, required: true},
+	{name: "UI", values: []string{"N", "P", "A"}, required: true},
+	{name: "VC", values: []string{"H", "L", "N"}, required: true},
+	{name: "VI", values: []string{"H", "L", "N"}, required: true},
+	{name: "VA", values: []string{"H", "L", "N"}, required: true},
+	{name: "SC", values: []string{"H", "L", "N"}, required: true},
+	{name: "SI", values: []string{"H", "L", "N"}, required: true},
+	{name: "SA", values: []string{"H", "L", "N"}, required: true},
+	{name: "E", values: []string{"X", "A", "P", "U"}},
+	{name: "CR", values: []string{"X", "H", "M", "L"}},
+	{name: "IR", values: []string{"X", "H", "M", "L"}},
+	{name: "AR", values: []string{"X", "H", "M", "L"}},
+	{name: "MAV", values: []string{"X", "N", "A", "L", "P"}},
+	{name: "MAC", values: []string{"X", "L", "H"}},
+	{name: "MAT", values: []string{"X", "N", "P"}},
+	{name: "MPR", values: []string{"X", "N", "L", "H"}},
+	{name: "MUI", values: []string{"X", "N", "P", "A"}},
+	{name: "MVC", values: []string{"X", "H", "L", "N"}},
+	{name: "MVI", values: []string{"X", "H", "L", "N"}},
+	{name: "MVA", values: []string{"X", "H", "L", "N"}},
+	{name: "MSC", values: []string{"X", "H", "L", "N"}},
+	{name: "MSI", values: []string{"X", "S", "H", "L", "N"}},
+	{name: "MSA", values: []string{"X", "S", "H", "L", "N"}},
+	// Supplemental metrics describe the vulnerability without scoring it
+	{name: "S", values: []string{"X", "N", "P"}},
+	{name: "AU", values: []string{"X", "N", "Y"}},
+	{name: "R", values: []string{"X", "A", "U", "I"}},
+	{name: "V", values: []string{"X", "D", "C"}},
+	{name: "RE", values: []string{"X", "L", "M", "H"}},
+	{name: "U", values: []string{"X", "Clear", "Green", "Amber", "Red"}},
+}
+
+// v4Levels are the severity distances of metric values from the most
+// severe one, in tenths.
+var v4Levels = map[string]map[string]float64{
+	"AV": {"N": 0, "A": 0.1, "L": 0.2, "P": 0.3},
+	"PR": {"N": 0, "L": 0.1, "H": 0.2},
+	"UI": {"N": 0, "P": 0.1, "A": 0.2},
+	"AC": {"L": 0, "H": 0.1},
+	"AT": {"N": 0, "P": 0.1},
+	"VC": {"H": 0, "L": 0.1, "N": 0.2},
+	"VI": {"H": 0, "L": 0.1, "N": 0.2},
+	"VA": {"H": 0, "L": 0.1, "N": 0.2},
+	"SC": {"H": 0.1, "L": 0.2, "N": 0.3},
+	"SI": {"S": 0, "H": 0.1, "L": 0.2, "N": 0.3},
+	"SA": {"S": 0, "H": 0.1, "L": 0.2, "N": 0.3},
+	"CR": {"H": 0, "M": 0.1, "L": 0.2},
+	"IR": {"H": 0, "M": 0.1, "L": 0.2},
+	"AR": {"H": 0, "M": 0.1, "L": 0.2},
+}
+
+// v4Highest lists, per equivalence class and level, the most severe
+// vectors of the level. The third class is keyed by the levels of the
+// third and sixth equivalence classes, which are scored together.
+var v4Highest = struct {
+	eq1, eq2, eq4, eq5 map[int][]string
+	eq3eq6             map[[2]int][]string
+}{
+	eq1: map[int][]string{
+		0: {"AV:N/PR:N/UI:N"},
+		1: {"AV:A/PR:N/UI:N", "AV:N/PR:L/UI:N", "AV:N/PR:N/UI:P"},
+		2: {"AV:P/PR:N/UI:N", "AV:A/PR:L/UI:P"},
+	},
+	eq2: map[int][]string{
+		0: {"AC:L/AT:N"},
+		1: {"AC:H/AT:N", "AC:L/AT:P"},
+	},
+	eq3eq6: map[[2]int][]string{
+		{0, 0}: {"VC:H/VI:H/VA:H/CR:H/IR:H/AR:H"},
+		{0, 1}: {"VC:H/VI:H/VA:L/CR:M/IR:M/AR:H", "VC:H/VI:H/VA:H/CR:M/IR:M/AR:M"},
+		{1, 0}: {"VC:L/VI:H/VA:H/CR:H/IR:H/AR:H", "VC:H/VI:L/VA:H/CR:H/IR:H/AR:H"},
+		{1, 1}: {"VC:L/VI:H/VA:L/CR:H/IR:M/AR:H", "VC:L/VI:H/VA:H/CR:H/IR:M/AR:M", "VC:H/VI:L/VA:H/CR:M/IR:H/AR:M", "VC:H/VI:L/VA:L/CR:M/IR:H/AR:H", "VC:L/VI:L/VA:H/CR:H/IR:H/AR:M"},
+		{2, 1}: {"VC:L/VI:L/VA:L/CR:H/IR:H/AR:H"},
+	},
+	eq4: map[int][]string{
+		0: {"SC:H/SI:S/SA:S"},
+		1: {"SC:H/SI:H/SA:H"},
+		2: {"SC:L/SI:L/SA:L"},
+	},
+	eq5: map[int][]string{
+		0: {"E:A"},
+		1: {"E:P"},
+		2: {"E:U"},
+	},
+}
+
+// v4Depths are the severity distances, in tenths, from the most to the
+// least severe vector of each level of the equivalence classes.
+var v4Depths = struct {
+	eq1, eq2, eq4 map[int]float64
+	eq3eq6        map[[2]int]float64
+}{
+	eq1:    map[int]float64{0: 1, 1: 4, 2: 5},
+	eq2:    map[int]float64{0: 1, 1: 2},
+	eq3eq6: map[[2]int]float64{{0, 0}: 7, {0, 1}: 6, {1, 0}: 8, {1, 1}: 8, {2, 1}: 10},
+	eq4:    map[int]float64{0: 6, 1: 5, 2: 4},
+}
+
+// v4Effective returns the value of a metric that the score reads. Threat
+// and environmental metrics count when threat and environmental are set;
+// otherwise, and when not defined, the threat and security requirements
+// take their most severe value and the base metrics stand unmodified.
+func (v *Vector) v4Effective(name string, threat, environmental bool) string {
+	switch name {
+	case "E":
+		if value := v.metrics["E"]; threat && value != "" && value != "X" {
+			return value
+		}
+		return "A"
+	case "CR", "IR", "AR":
+		if value := v.metrics[name]; environmental && value != "" && value != "X" {
+			return value
+		}
+		return "H"
+	}
+	if environmental {
+		return v.modified(name)
+	}
+	return v.metrics[name]
+}
+
+// v4Score scores the vector as the v4.0 specification does: it looks up
+// the score of the macro vector the metrics fall into and lowers it by how
+// far the metrics are from the most severe vector of the macro vector.
+func (v *Vector) v4Score(threat, environmental bool) float64 {
+	m := func(name string) string { return v.v4Effective(name, threat, environmental) }
+
+	if m("VC") == "N" && m("VI") == "N" && m("VA") == "N" && m("SC") == "N" && m("SI") == "N" && m("SA") == "N" {
+		return 0
+	}
+
+	// Equivalence classes of the metrics
+	var eq1, eq2, eq3, eq4, eq5, eq6 int
+	switch av, pr, ui := m("AV"), m("PR"), m("UI"); {
+	case av == "N" && pr == "N" && ui == "N":
+		eq1 = 0
+	case (av == "N" || pr == "N" || ui == "N") && av != "P":
+		eq1 = 1
+	default:
+		eq1 = 2
+	}
+	if m("AC") != "L" || m("AT") != "N" {
+		eq2 = 1
+	}
+	switch {
+	case m("VC") == "H" && m("VI") == "H":
+		eq3 = 0
+	case m("VC") == "H" || m("VI") == "H" || m("VA") == "H":
+		eq3 = 1
+	default:
+		eq3 = 2
+	}
+	switch {
+	case m("SI") == "S" || m("SA") == "S":
+		eq4 = 0
+	case m("SC") == "H" || m("SI") == "H" || m("SA") == "H":
+		eq4 = 1
+	default:
+		eq4 = 2
+	}
+	eq5 = map[string]int{"A": 0, "P": 1, "U": 2}[m("E")]
+	if !(m("CR") == "H" && m("VC") == "H" || m("IR") == "H" && m("VI") == "H" || m("AR") == "H" && m("VA") == "H") {
+		eq6 = 1
+	}
+
+	value := v4MacroScore(eq1, eq2, eq3, eq4, eq5, eq6)
+
+	// Scores of the next lower macro vectors of each class, NaN when there
+	// is none
+	lowerEq1 := v4MacroScore(eq1+1, eq2, eq3, eq4, eq5, eq6)
+	lowerEq2 := v4MacroScore(eq1, eq2+1, eq3, eq4, eq5, eq6)
+	var lowerEq3Eq6 float64
+	switch {
+	case eq3 == 0 && eq6 == 0:
+		// Either class may be the one to go lower
+		lowerEq3Eq6 = math.Max(v4MacroScore(eq1, eq2, eq3, eq4, eq5, eq6+1), v4MacroScore(eq1, eq2, eq3+1, eq4, eq5, eq6))
+	case eq6 == 0:
+		lowerEq3Eq6 = v4MacroScore(eq1, eq2, eq3, eq4, eq5, eq6+1)
+	case eq3 < 2:
+		lowerEq3Eq6 = v4MacroScore(eq1, eq2, eq3+1, eq4, eq5, eq6)
+	default:
+		lowerEq3Eq6 = math.NaN()
+	}
+	lowerEq4 := v4MacroScore(eq1, eq2, eq3, eq4+1, eq5, eq6)
+	lowerEq5 := v4MacroScore(eq1, eq2, eq3, eq4, eq5+1, eq6)
+
+	// Find the most severe vector of the macro vector that the metrics are
+	// no more severe than
+	distance := func(name string, highest map[string]string) float64 {
+		return v4Levels[name][m(name)] - v4Levels[name][highest[name]]
+	}
+	var highest map[string]string
+	for _, candidate := range v4Candidates(eq1, eq2, eq3, eq4, eq5, eq6) {
+		highest = candidate
+		below := true
+		for name := range v4Levels {
+			if distance(name, highest) < 0 {
+				below = false
+				break
+			}
+		}
+		if below {
+			break
+		}
+	}
+	distanceEq1 := distance("AV", highest) + distance("PR", highest) + distance("UI", highest)
+	distanceEq2 := distance("AC", highest) + distance("AT", highest)
+	distanceEq3Eq6 := distance("VC", highest) + distance("VI", highest) + distance("VA", highest) +
+		distance("CR", highest) + distance("IR", highest) + distance("AR", highest)
+	distanceEq4 := distance("SC", highest) + distance("SI", highest) + distance("SA", highest)
+
+	// Lower the score by the mean of the proportional distances of the
+	// classes that have a lower macro vector
+	const step = 0.1
+	lowered, classes := 0.0, 0
+	for _, class := range []struct {
+		lower, distance, depth float64
+	}{
+		{lowerEq1, distanceEq1, v4Depths.eq1[eq1] * step},
+		{lowerEq2, distanceEq2, v4Depths.eq2[eq2] * step},
+		{lowerEq3Eq6, distanceEq3Eq6, v4Depths.eq3eq6[[2]int{eq3, eq6}] * step},
+		{lowerEq4, distanceEq4, v4Depths.eq4[eq4] * step},
+		// The threat class has no depth: each level is one vector
+		{lowerEq5, 0, 1},
+	} {
+		if math.IsNaN(class.lower) {
+			continue
+		}
+		classes++
+		lowered += (value - class.lower) * class.distance / class.depth
+	}
+	if classes > 0 {
+		value -= lowered / float64(classes)
+	}
+	return round1(math.Max(0, math.Min(10, value)))
+}
+
+// v4MacroScore returns the score of a macro vector, or NaN when there is
+// no such macro vector.
+func v4MacroScore(eq1, eq2, eq3, eq4, eq5, eq6 int) float64 {
+	if score, ok := v4MacroScores[fmt.Sprintf("%d%d%d%d%d%d", eq1, eq2, eq3, eq4, eq5, eq6)]; ok {
+		return score
+	}
+	return math.NaN()
+}
+
+// v4Candidates returns the most severe vectors of a macro vector, as metric
+// values, in the order the specification tries them.
+func v4Candidates(eq1, eq2, eq3, eq4, eq5, eq6 int) []map[string]string {
+	var candidates []map[string]string
+	for _, a := range v4Highest.eq1[eq1] {
+		for _, b := range v4Highest.eq2[eq2] {
+			for _, c := range v4Highest.eq3eq6[[2]int{eq3, eq6}] {
+				for _, d := range v4Highest.eq4[eq4] {
+					for _, e := range v4Highest.eq5[eq5] {
+						candidate := map[string]string{}
+						for _, part := range strings.Split(strings.Join([]string{a, b, c, d, e}, "/"), "/") {
+							name, value, _ := strings.Cut(part, ":")
+							candidate[name] = value
+						}
+						candidates = append(candidates, candidate)
+					}
+				}
+			}
+		}
+	}
+	return candidates
+}
@@ -2,8 +2,8 @@
 package excel
 
 import (
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strconv"
 
@@ -12,115 +12,391 @@ import (
 	"github.com/xuri/excelize/v2"
 )
 
-// Open the Excel Doc at the provided location
-func WriteData(fileLocation string, cvssBySeverity sql.VulnBySeverity, topTenVulnHosts []sql.TopTenVulnHosts, mostDangerousVulns []sql.MostDangerousVulns, vulnByType sql.VulnByType, countCVSSYear []sql.CountCVSSYear) {
+// Names of the report sheets the workbook template must contain.
+const (
+	SheetBySeverity = "CVSS By Severity"
+	SheetTopHosts   = "Top Vulnerable Hosts"
+	SheetMostCommon = "Most Common Vulnerabilities"
+	SheetByType     = "Vulnerabilities By Type"
+	SheetByYear     = "Vulnerabilities By Year"
+)
+
+// Sheets added to the workbook when the report has data for them.
+const (
+	SheetOpenServices    = "Open Services"
+	SheetContainerImages = "Container Images"
+	SheetByAttackVector  = "By Attack Vector"
+	SheetByPrivileges    = "By Privileges Required"
+	SheetLikelyExploited = "Most Likely Exploited"
+	SheetLikelyHosts     = "Most Likely Exploited Hosts"
+)
+
+// ErrMissingSheet is matched by a *SheetError through errors.Is.
+var ErrMissingSheet = errors.New("missing sheet")
+
+// SheetError reports a report sheet that the workbook does not contain.
+type SheetError struct {
+	File  string
+	Sheet string
+}
+
+func (e *SheetError) Error() string {
+	return fmt.Sprintf("workbook %s has no sheet %q", e.File, e.Sheet)
+}
+
+// Is makes a *SheetError match ErrMissingSheet.
+func (e *SheetError) Is(target error) bool { return target == ErrMissingSheet }
+
+// WriteData fills the report sheets of the workbook at fileLocation and saves
+// the result to newFile, or to OutputPath(fileLocation) when newFile is empty.
+// It fails with a *SheetError when the workbook lacks one of the report sheets.
+func WriteData(fileLocation string, newFile string, report sql.Report) error {
 	// Open the Excel Doc at the provided location
 	file, err := excelize.OpenFile(fileLocation)
-	// Handle any errors opening the DB
 	if err != nil {
-		fmt.Printf("Error opening Excel File. Error: %v\n", err)
-		os.Exit(2)
+		return fmt.Errorf("unable to open Excel file: %w", err)
+	}
+	defer file.Close()
+
+	for _, sheet := range []string{SheetBySeverity, SheetTopHosts, SheetMostCommon, SheetByType, SheetByYear} {
+		if index, err := file.GetSheetIndex(sheet); err != nil || index < 0 {
+			return &SheetError{File: fileLocation, Sheet: sheet}
+		}
 	}
-	writeCVSSBySev(file, "CVSS By Severity", cvssBySeverity)
-	writeTopTens(file, "Top Vulnerable Hosts", topTenVulnHosts)
-	writeMostDang(file, "Most Common Vulnerabilities", mostDangerousVulns)
-	writeVulnByType(file, "Vulnerabilities By Type", vulnByType)
-	writeByYear(file, "Vulnerabilities By Year", countCVSSYear)
 
-	newFile := ""
-	if filepath.Dir(fileLocation) == "." {
-		newFile = "Populated_" + filepath.Base(fileLocation)
-	} else {
-		newFile = filepath.Dir(fileLocation) + "Populated_" + filepath.Base(fileLocation)
+	writers := []func() error{
+		func() error { return writeCVSSBySev(file, SheetBySeverity, report.VulnBySeverity) },
+		func() error { return writeTopTens(file, SheetTopHosts, report.TopTenVulnHosts) },
+		func() error { return writeMostDang(file, SheetMostCommon, report.MostDangerousVulns) },
+		func() error { return writeVulnByType(file, SheetByType, report.VulnByType) },
+		func() error { return writeByYear(file, SheetByYear, report.CountCVSSYear) },
+	}
+	if len(report.OpenServices) > 0 {
+		writers = append(writers, func() error { return writeOpenServices(file, SheetOpenServices, report.OpenServices) })
+	}
+	if len(report.ContainerImages) > 0 {
+		writers = append(writers, func() error { return writeContainerImages(file, SheetContainerImages, report.ContainerImages) })
+	}
+	if len(report.ByAttackVector) > 0 {
+		writers = append(writers, func() error {
+			return writeByComponent(file, SheetByAttackVector, "Attack Vector", report.ByAttackVector)
+		})
+	}
+	if len(report.ByPrivileges) > 0 {
+		writers = append(writers, func() error {
+			return writeByComponent(file, SheetByPrivileges, "Privileges Required", report.ByPrivileges)
+		})
+	}
+	if len(report.LikelyExploitedVulns) > 0 {
+		writers = append(writers, func() error { return writeLikelyExploited(file, SheetLikelyExploited, report.LikelyExploitedVulns) })
+		writers = append(writers, func() error { return writeLikelyHosts(file, SheetLikelyHosts, report.LikelyExploitedHosts) })
+	}
+	for _, write := range writers {
+		if err := write(); err != nil {
+			return fmt.Errorf("unable to write report: %w", err)
+		}
+	}
+
+	if newFile == "" {
+		newFile = OutputPath(fileLocation)
 	}
 	if err := file.SaveAs(newFile); err != nil {
-		fmt.Println(err)
+		return fmt.Errorf("unable to save Excel file: %w", err)
+	}
+	return nil
+}
+
+// OutputPath returns the default location of the populated copy of a workbook.
+func OutputPath(fileLocation string) string {
+	return filepath.Join(filepath.Dir(fileLocation), "Populated_"+filepath.Base(fileLocation))
+}
+
+// cellWriter writes cells until the first error and remembers it.
+type cellWriter struct {
+	file  *excelize.File
+	sheet string
+	err   error
+}
+
+func (w *cellWriter) setInt(cell string, value int) {
+	if w.err == nil {
+		w.err = w.file.SetCellInt(w.sheet, cell, value)
 	}
 }
 
-func writeCVSSBySev(file *excelize.File, sheet string, values sql.VulnBySeverity) {
-	file.SetCellInt(sheet, "A2", values.CritTotal)
-	file.SetCellInt(sheet, "B2", values.SevTotal)
-	file.SetCellInt(sheet, "C2", values.HighTotal)
-	file.SetCellInt(sheet, "D2", values.MedTotal)
-	file.SetCellInt(sheet, "E2", values.LowTotal)
+func (w *cellWriter) setFloat(cell string, value float64) {
+	if w.err == nil {
+		w.err = w.file.SetCellFloat(w.sheet, cell, value, -1, 64)
+	}
+}
+
+func (w *cellWriter) setStr(cell string, value string) {
+	if w.err == nil {
+		w.err = w.file.SetCellStr(w.sheet, cell, value)
+	}
 }
 
-func writeTopTens(file *excelize.File, sheet string, values []sql.TopTenVulnHosts) {
+// writeCVSSBySev writes the names of the severity bands in the first row
+// and their counts in the second, highest band first.
+func writeCVSSBySev(file *excelize.File, sheet string, values sql.VulnBySeverity) error {
+	w := &cellWriter{file: file, sheet: sheet}
+	writeBands(w, 1, 1, values.Bands)
+	return w.err
+}
+
+func writeTopTens(file *excelize.File, sheet string, values []sql.TopTenVulnHosts) error {
 	for id, value := range values {
 		row := id + 2
-		writeTopTenVulnHosts(file, sheet, row, value)
+		if id == 0 {
+			// Label the band columns after the host and its CVSS total
+			w := &cellWriter{file: file, sheet: sheet}
+			writeBandNames(w, 3, 1, value.Bands)
+			if w.err != nil {
+				return w.err
+			}
+		}
+		if err := writeTopTenVulnHosts(file, sheet, row, value); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func writeTopTenVulnHosts(file *excelize.File, sheet string, row int, values sql.TopTenVulnHosts) {
+func writeTopTenVulnHosts(file *excelize.File, sheet string, row int, values sql.TopTenVulnHosts) error {
 	strRow := strconv.Itoa(row)
-	file.SetCellStr(sheet, "A"+strRow, values.MostVulnHost)
-	file.SetCellInt(sheet, "B"+strRow, values.CVSSTotal)
-	file.SetCellInt(sheet, "C"+strRow, values.CriticalTotal)
-	file.SetCellInt(sheet, "D"+strRow, values.SevereTotal)
-	file.SetCellInt(sheet, "E"+strRow, values.HighTotal)
-	file.SetCellInt(sheet, "F"+strRow, values.MediumTotal)
-	file.SetCellInt(sheet, "G"+strRow, values.LowTotal)
+	w := &cellWriter{file: file, sheet: sheet}
+	w.setStr("A"+strRow, values.MostVulnHost)
+	w.setInt("B"+strRow, values.CVSSTotal)
+	writeBandTotals(w, 3, row, values.Bands)
+	return w.err
+}
+
+// writeBands writes the names of bands in row and their counts in the
+// row below, from column col on.
+func writeBands(w *cellWriter, col int, row int, bands []sql.BandCount) {
+	writeBandNames(w, col, row, bands)
+	writeBandTotals(w, col, row+1, bands)
 }
 
-func writeMostDang(file *excelize.File, sheet string, values []sql.MostDangerousVulns) {
+func writeBandNames(w *cellWriter, col int, row int, bands []sql.BandCount) {
+	for i, band := range bands {
+		w.setStr(cellName(col+i, row), band.Band)
+	}
+}
+
+func writeBandTotals(w *cellWriter, col int, row int, bands []sql.BandCount) {
+	for i, band := range bands {
+		w.setInt(cellName(col+i, row), band.Total)
+	}
+}
+
+// cellName returns the name of the cell at a column and row, e.g. C2.
+func cellName(col int, row int) string {
+	name, _ := excelize.CoordinatesToCellName(col, row)
+	return name
+}
+
+func writeMostDang(file *excelize.File, sheet string, values []sql.MostDangerousVulns) error {
 	for id, value := range values {
 		row := id + 2
-		writeMostDangerousVulns(file, sheet, row, value)
+		if err := writeMostDangerousVulns(file, sheet, row, value); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func writeMostDangerousVulns(file *excelize.File, sheet string, row int, values sql.MostDangerousVulns) {
+func writeMostDangerousVulns(file *excelize.File, sheet string, row int, values sql.MostDangerousVulns) error {
 	strRow := strconv.Itoa(row)
-	file.SetCellStr(sheet, "A"+strRow, values.VulnName)
-	file.SetCellInt(sheet, "B"+strRow, values.CVSS)
-	file.SetCellInt(sheet, "C"+strRow, values.CVSSTotal)
+	w := &cellWriter{file: file, sheet: sheet}
+	w.setStr("A"+strRow, values.VulnName)
+	w.setFloat("B"+strRow, values.CVSS)
+	w.setInt("C"+strRow, values.CVSSTotal)
+	return w.err
 }
 
-func writeVulnByType(file *excelize.File, sheet string, values sql.VulnByType) {
-	file.SetCellInt(sheet, "A2", values.OracleCount)
-	file.SetCellInt(sheet, "B2", values.MicrosoftCount)
-	file.SetCellInt(sheet, "C2", values.SSLCount)
-	file.SetCellInt(sheet, "D2", values.FirefoxCount)
-	file.SetCellInt(sheet, "E2", values.SMBCount)
-	file.SetCellInt(sheet, "F2", values.ApacheCount)
-	file.SetCellInt(sheet, "G2", values.PHPCount)
-	file.SetCellInt(sheet, "H2", values.AdobeCount)
+func writeVulnByType(file *excelize.File, sheet string, values sql.VulnByType) error {
+	w := &cellWriter{file: file, sheet: sheet}
+	w.setInt("A2", values.OracleCount)
+	w.setInt("B2", values.MicrosoftCount)
+	w.setInt("C2", values.SSLCount)
+	w.setInt("D2", values.FirefoxCount)
+	w.setInt("E2", values.SMBCount)
+	w.setInt("F2", values.ApacheCount)
+	w.setInt("G2", values.PHPCount)
+	w.setInt("H2", values.AdobeCount)
+	return w.err
 }
 
-func writeByYear(file *excelize.File, sheet string, values []sql.CountCVSSYear) {
+func writeByYear(file *excelize.File, sheet string, values []sql.CountCVSSYear) error {
 	for id, value := range values {
 		row := id + 2
-		writeCountCVSSYear(file, sheet, row, value)
+		if err := writeCountCVSSYear(file, sheet, row, value); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func writeCountCVSSYear(file *excelize.File, sheet string, row int, values sql.CountCVSSYear) {
+func writeCountCVSSYear(file *excelize.File, sheet string, row int, values sql.CountCVSSYear) error {
 	strRow := strconv.Itoa(row)
-	file.SetCellInt(sheet, "A"+strRow, values.Year)
-	file.SetCellInt(sheet, "B"+strRow, values.Total)
+	w := &cellWriter{file: file, sheet: sheet}
+	w.setInt("A"+strRow, values.Year)
+	w.setInt("B"+strRow, values.Total)
+	return w.err
 }
 
-func writeRow(file *excelize.File, sheet string, rowID int, values []string) {
+func writeOpenServices(file *excelize.File, sheet string, values []sql.OpenService) error {
+	if err := ensureSheet(file, sheet, []string{"Port", "Protocol", "Service", "Product", "Hosts"}); err != nil {
+		return err
+	}
 	for id, value := range values {
-		cell := toCharStr(id+1) + strconv.Itoa(rowID)
-		if num, err := strconv.Atoi(value); err == nil {
-			file.SetCellInt(sheet, cell, num)
-		} else {
-			file.SetCellStr(sheet, cell, value)
+		row := id + 2
+		if err := writeOpenService(file, sheet, row, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeOpenService(file *excelize.File, sheet string, row int, values sql.OpenService) error {
+	strRow := strconv.Itoa(row)
+	w := &cellWriter{file: file, sheet: sheet}
+	w.setInt("A"+strRow, values.Port)
+	w.setStr("B"+strRow, values.Protocol)
+	w.setStr("C"+strRow, values.Service)
+	w.setStr("D"+strRow, values.Product)
+	w.setInt("E"+strRow, values.Hosts)
+	return w.err
+}
+
+func writeContainerImages(file *excelize.File, sheet string, values []sql.ContainerImage) error {
+	header := []string{"Image", "CVSS Total", "Findings"}
+	for _, band := range values[0].Bands {
+		header = append(header, band.Band)
+	}
+	header = append(header, "Fixable")
+	// Label the band columns of a template sheet too
+	if err := ensureSheet(file, sheet, nil); err != nil {
+		return err
+	}
+	if err := writeRow(file, sheet, 1, header); err != nil {
+		return err
+	}
+	for id, value := range values {
+		row := id + 2
+		if err := writeContainerImage(file, sheet, row, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeContainerImage(file *excelize.File, sheet string, row int, values sql.ContainerImage) error {
+	strRow := strconv.Itoa(row)
+	w := &cellWriter{file: file, sheet: sheet}
+	w.setStr("A"+strRow, values.Image)
+	w.setInt("B"+strRow, values.CVSSTotal)
+	w.setInt("C"+strRow, values.Findings)
+	writeBandTotals(w, 4, row, values.Bands)
+	w.setInt(cellName(4+len(values.Bands), row), values.Fixable)
+	return w.err
+}
+
+func writeByComponent(file *excelize.File, sheet string, component string, values []sql.VulnByComponent) error {
+	if err := ensureSheet(file, sheet, []string{component, "Findings", "Hosts", "Max CVSS"}); err != nil {
+		return err
+	}
+	for id, value := range values {
+		row := id + 2
+		if err := writeVulnByComponent(file, sheet, row, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeVulnByComponent(file *excelize.File, sheet string, row int, values sql.VulnByComponent) error {
+	strRow := strconv.Itoa(row)
+	w := &cellWriter{file: file, sheet: sheet}
+	w.setStr("A"+strRow, values.Value)
+	w.setInt("B"+strRow, values.Findings)
+	w.setInt("C"+strRow, values.Hosts)
+	w.setFloat("D"+strRow, values.MaxCVSS)
+	return w.err
+}
+
+func writeLikelyExploited(file *excelize.File, sheet string, values []sql.LikelyExploitedVuln) error {
+	header := []string{"CVE", "Name", "EPSS", "Percentile", "CVSS", "Hosts", "Findings"}
+	if err := ensureSheet(file, sheet, header); err != nil {
+		return err
+	}
+	for id, value := range values {
+		row := id + 2
+		if err := writeLikelyExploitedVuln(file, sheet, row, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeLikelyExploitedVuln(file *excelize.File, sheet string, row int, values sql.LikelyExploitedVuln) error {
+	strRow := strconv.Itoa(row)
+	w := &cellWriter{file: file, sheet: sheet}
+	w.setStr("A"+strRow, values.CVE)
+	w.setStr("B"+strRow, values.VulnName)
+	w.setFloat("C"+strRow, values.EPSS)
+	w.setFloat("D"+strRow, values.Percentile)
+	w.setFloat("E"+strRow, values.CVSS)
+	w.setInt("F"+strRow, values.Hosts)
+	w.setInt("G"+strRow, values.Findings)
+	return w.err
+}
+
+func writeLikelyHosts(file *excelize.File, sheet string, values []sql.LikelyExploitedHost) error {
+	if err := ensureSheet(file, sheet, []string{"Host", "Max EPSS", "CVE", "Scored CVEs"}); err != nil {
+		return err
+	}
+	for id, value := range values {
+		row := id + 2
+		if err := writeLikelyExploitedHost(file, sheet, row, value); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+func writeLikelyExploitedHost(file *excelize.File, sheet string, row int, values sql.LikelyExploitedHost) error {
+	strRow := strconv.Itoa(row)
+	w := &cellWriter{file: file, sheet: sheet}
+	w.setStr("A"+strRow, values.Host)
+	w.setFloat("B"+strRow, values.MaxEPSS)
+	w.setStr("C"+strRow, values.CVE)
+	w.setInt("D"+strRow, values.CVEs)
+	return w.err
 }
 
-func writeMultipleRow(file *excelize.File, sheet string, values [][]string) {
-	for rowID, row := range values {
-		adjRowID := rowID + 2
-		writeRow(file, sheet, adjRowID, row)
+// ensureSheet adds sheet with a header row when the template lacks it, as
+// older templates do.
+func ensureSheet(file *excelize.File, sheet string, header []string) error {
+	if index, err := file.GetSheetIndex(sheet); err == nil && index >= 0 {
+		return nil
 	}
+	if _, err := file.NewSheet(sheet); err != nil {
+		return err
+	}
+	return writeRow(file, sheet, 1, header)
 }
 
-func toCharStr(i int) string {
-	abc := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	return abc[i-1 : i]
+func writeRow(file *excelize.File, sheet string, rowID int, values []string) error {
+	w := &cellWriter{file: file, sheet: sheet}
+	for id, value := range values {
+		cell := cellName(id+1, rowID)
+		if num, err := strconv.Atoi(value); err == nil {
+			w.setInt(cell, num)
+		} else {
+			w.setStr(cell, value)
+		}
+	}
+	return w.err
 }
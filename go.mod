@@ -4,8 +4,9 @@ go 1.20
 
 require (
 	github.com/go-sql-driver/mysql v1.7.1
-	github.com/mattn/go-sqlite3 v1.14.16
+	github.com/lib/pq v1.10.9
 	github.com/xuri/excelize/v2 v2.7.1
+	gopkg.in/yaml.v3 v3.0.1
 	modernc.org/sqlite v1.22.1
 )
 
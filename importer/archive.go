@@ -0,0 +1,298 @@
+package importer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Input is a file to import: the file given, or one picked out of a
+// directory or an archive.
+type Input struct {
+	// Path is where the file is read from, extracted to a temporary
+	// directory when it came out of an archive.
+	Path string
+	// Name is the name rows are tagged with: the file name, or its path
+	// within the directory or archive after the name of either.
+	Name string
+	// Format is the name of the format of the file.
+	Format string
+	// Packed is set when the file came out of a directory or an archive.
+	Packed bool
+}
+
+// IsArchive reports whether path names an archive Expand unpacks.
+func IsArchive(path string) bool {
+	lower := strings.ToLower(path)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz", ".gz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand lists the files to import from path. A plain file is read in the
+// named format, or in the one DetectFormat tells. Directories and .zip, .tar,
+// .tar.gz, .tgz and .gz archives, nested or not, yield the files they hold
+// whose format DetectFormat tells, or every file when a format is named;
+// others are skipped and listed. Archives are extracted to a temporary
+// directory that cleanup removes.
+func Expand(name string, format string) (inputs []Input, skipped []string, cleanup func(), err error) {
+	e := &expander{format: format}
+	cleanup = func() {
+		if e.temp != "" {
+			os.RemoveAll(e.temp)
+		}
+	}
+	if format != "" {
+		if _, ok := Lookup(format); !ok {
+			return nil, nil, cleanup, fmt.Errorf("unknown format %q; choose one of %s", format, strings.Join(Formats(), ", "))
+		}
+	}
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, nil, cleanup, err
+	}
+	if !info.IsDir() && !IsArchive(name) {
+		if format == "" {
+			if format, err = DetectFormat(name); err != nil {
+				return nil, nil, cleanup, err
+			}
+		}
+		return []Input{{Path: name, Name: filepath.Base(name), Format: format}}, nil, cleanup, nil
+	}
+
+	if err := e.add(name, filepath.Base(filepath.Clean(name))); err != nil {
+		return nil, nil, cleanup, err
+	}
+	if len(e.inputs) == 0 {
+		return nil, e.skipped, cleanup, fmt.Errorf("%s holds no file of a known format; choose one of %s", filepath.Base(name), strings.Join(Formats(), ", "))
+	}
+	return e.inputs, e.skipped, cleanup, nil
+}
+
+// expander collects the files of directories and archives.
+type expander struct {
+	format  string
+	temp    string
+	inputs  []Input
+	skipped []string
+}
+
+// add adds the file, directory or archive at file, named name.
+func (e *expander) add(file, name string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.IsDir():
+		return e.addDir(file, name)
+	case isGzipOnly(file):
+		// A gzipped file stands for the file itself
+		unpacked, err := e.gunzip(file)
+		if err != nil {
+			return fmt.Errorf("unable to unpack %s: %w", name, err)
+		}
+		return e.add(unpacked, name[:len(name)-len(".gz")])
+	case IsArchive(file):
+		dir, err := e.extract(file)
+		if err != nil {
+			return fmt.Errorf("unable to unpack %s: %w", name, err)
+		}
+		return e.addDir(dir, name)
+	}
+
+	format := e.format
+	if format == "" {
+		if format, err = DetectFormat(file); err != nil {
+			e.skipped = append(e.skipped, name)
+			return nil
+		}
+	}
+	e.inputs = append(e.inputs, Input{Path: file, Name: name, Format: format, Packed: true})
+	return nil
+}
+
+// addDir adds the files of a directory in name order, leaving out hidden
+// files and the resource forks of macOS archives.
+func (e *expander) addDir(dir, name string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") || entry.Name() == "__MACOSX" {
+			continue
+		}
+		if err := e.add(filepath.Join(dir, entry.Name()), path.Join(name, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tempDir returns a new directory under the temporary directory of the
+// expander.
+func (e *expander) tempDir() (string, error) {
+	if e.temp == "" {
+		temp, err := os.MkdirTemp("", "update-db-")
+		if err != nil {
+			return "", err
+		}
+		e.temp = temp
+	}
+	return os.MkdirTemp(e.temp, "")
+}
+
+// extract unpacks a .zip, .tar, .tar.gz or .tgz archive into a temporary
+// directory.
+func (e *expander) extract(archive string) (string, error) {
+	dir, err := e.tempDir()
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		return dir, extractZip(archive, dir)
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if strings.HasSuffix(strings.ToLower(archive), ".tar") {
+		return dir, extractTar(f, dir)
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return "", err
+	}
+	defer gz.Close()
+	return dir, extractTar(gz, dir)
+}
+
+// gunzip unpacks a gzipped file into a temporary directory, under its name
+// without the .gz.
+func (e *expander) gunzip(file string) (string, error) {
+	dir, err := e.tempDir()
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return "", err
+	}
+	defer gz.Close()
+	base := filepath.Base(file)
+	target := filepath.Join(dir, base[:len(base)-len(".gz")])
+	return target, writeFile(target, gz)
+}
+
+// isGzipOnly reports whether archive is a gzipped file rather than a
+// gzipped tar archive.
+func isGzipOnly(archive string) bool {
+	lower := strings.ToLower(archive)
+	return strings.HasSuffix(lower, ".gz") && !strings.HasSuffix(lower, ".tar.gz")
+}
+
+func extractZip(archive, dir string) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		target, err := entryPath(dir, f.Name)
+		if err != nil {
+			return err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		err = writeFile(target, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// Links and devices are no scan exports
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		target, err := entryPath(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(target, tr); err != nil {
+			return fmt.Errorf("%s: %w", header.Name, err)
+		}
+	}
+}
+
+// entryPath returns where an archive entry extracts to under dir, refusing
+// names that would land outside it.
+func entryPath(dir, name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if clean == "." || clean == "/" {
+		return "", fmt.Errorf("invalid archive entry %q", name)
+	}
+	// Absolute names, drive letters and parent directories all escape
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || len(clean) > 1 && clean[1] == ':' {
+		return "", fmt.Errorf("archive entry %q leaves the archive", name)
+	}
+	target := filepath.Join(dir, filepath.FromSlash(clean))
+	if !strings.HasPrefix(target, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry %q leaves the archive", name)
+	}
+	return target, nil
+}
+
+// writeFile copies r into a new file at target, creating its directory.
+func writeFile(target string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fs.FileMode(0o600))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
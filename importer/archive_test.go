@@ -0,0 +1,235 @@
+package importer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// entry is a file of a test archive.
+type entry struct {
+	name    string
+	content string
+}
+
+func zipped(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, e := range entries {
+		f, err := w.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarred(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0o600, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipped(t *testing.T, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writeTree writes files under dir, creating their directories.
+func writeTree(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const (
+	csvFindings    = "Host,Name,CVSS\n10.0.0.1,Weak TLS,5.3\n"
+	nessusFindings = "<NessusClientData_v2><Report name=\"scan\"></Report></NessusClientData_v2>"
+	trivyFindings  = `{"SchemaVersion": 2, "ArtifactName": "nginx:1.25", "Results": []}`
+)
+
+func TestExpand(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		files   func(t *testing.T) map[string][]byte
+		input   string
+		format  string
+		want    []string
+		skipped []string
+	}{
+		{
+			name: "directory",
+			files: func(t *testing.T) map[string][]byte {
+				return map[string][]byte{
+					"scans/b.csv":             []byte(csvFindings),
+					"scans/a.nessus":          []byte(nessusFindings),
+					"scans/images/nginx.json": []byte(trivyFindings),
+					"scans/notes.txt":         []byte("not a scan"),
+					"scans/.hidden.csv":       []byte(csvFindings),
+				}
+			},
+			input:   "scans",
+			want:    []string{"scans/a.nessus nessus", "scans/b.csv csv", "scans/images/nginx.json trivy"},
+			skipped: []string{"scans/notes.txt"},
+		},
+		{
+			name: "nested archives",
+			files: func(t *testing.T) map[string][]byte {
+				inner := gzipped(t, tarred(t, entry{"b.csv", csvFindings}, entry{"sub/a.nessus", nessusFindings}))
+				return map[string][]byte{"outer.zip": zipped(t,
+					entry{"inner.tar.gz", string(inner)},
+					entry{"nginx.json.gz", string(gzipped(t, []byte(trivyFindings)))},
+					entry{"readme.md", "not a scan"},
+					entry{"__MACOSX/._b.csv", "resource fork"},
+				)}
+			},
+			input:   "outer.zip",
+			want:    []string{"outer.zip/inner.tar.gz/b.csv csv", "outer.zip/inner.tar.gz/sub/a.nessus nessus", "outer.zip/nginx.json trivy"},
+			skipped: []string{"outer.zip/readme.md"},
+		},
+		{
+			// A gzipped file stands for the file itself
+			name: "gzipped file",
+			files: func(t *testing.T) map[string][]byte {
+				return map[string][]byte{"findings.csv.gz": gzipped(t, []byte(csvFindings))}
+			},
+			input: "findings.csv.gz",
+			want:  []string{"findings.csv csv"},
+		},
+		{
+			name: "named format",
+			files: func(t *testing.T) map[string][]byte {
+				return map[string][]byte{"scans.tar": tarred(t, entry{"a.txt", csvFindings}, entry{"b.dat", csvFindings})}
+			},
+			input:  "scans.tar",
+			format: "csv",
+			want:   []string{"scans.tar/a.txt csv", "scans.tar/b.dat csv"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tt.files(t))
+
+			inputs, skipped, cleanup, err := Expand(filepath.Join(dir, tt.input), tt.format)
+			defer cleanup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, input := range inputs {
+				got = append(got, input.Name+" "+input.Format)
+				content, err := os.ReadFile(input.Path)
+				if err != nil || len(content) == 0 {
+					t.Errorf("%s reads %d bytes, %v", input.Name, len(content), err)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("inputs = %v, want %v", got, tt.want)
+			}
+			if fmt.Sprint(skipped) != fmt.Sprint(tt.skipped) {
+				t.Errorf("skipped = %v, want %v", skipped, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestExpandRefusesEscapingEntries(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		archive func(t *testing.T) []byte
+		file    string
+	}{
+		{"zip parent", func(t *testing.T) []byte {
+			return zipped(t, entry{"ok.csv", csvFindings}, entry{"../evil.csv", csvFindings})
+		}, "scans.zip"},
+		{"zip absolute", func(t *testing.T) []byte { return zipped(t, entry{"/tmp/evil.csv", csvFindings}) }, "scans.zip"},
+		{"zip backslashes", func(t *testing.T) []byte { return zipped(t, entry{`..\..\evil.csv`, csvFindings}) }, "scans.zip"},
+		{"zip drive", func(t *testing.T) []byte { return zipped(t, entry{`C:\evil.csv`, csvFindings}) }, "scans.zip"},
+		{"tar parent", func(t *testing.T) []byte { return tarred(t, entry{"scans/../../evil.csv", csvFindings}) }, "scans.tar"},
+		{"tar absolute", func(t *testing.T) []byte { return tarred(t, entry{"/evil.csv", csvFindings}) }, "scans.tar"},
+		{"tar.gz parent", func(t *testing.T) []byte { return gzipped(t, tarred(t, entry{"../evil.csv", csvFindings})) }, "scans.tar.gz"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, map[string][]byte{tt.file: tt.archive(t)})
+
+			_, _, cleanup, err := Expand(filepath.Join(dir, tt.file), "")
+			defer cleanup()
+			if err == nil || !strings.Contains(err.Error(), "leaves the archive") {
+				t.Errorf("Expand = %v, want an entry that leaves the archive", err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "evil.csv")); err == nil {
+				t.Error("an entry was written next to the archive")
+			}
+		})
+	}
+}
+
+func TestExpandCleanup(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string][]byte{"scans.zip": zipped(t, entry{"a.csv", csvFindings})})
+
+	inputs, _, cleanup, err := Expand(filepath.Join(dir, "scans.zip"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup()
+	if _, err := os.Stat(inputs[0].Path); !os.IsNotExist(err) {
+		t.Errorf("the extracted file is left after cleanup: %v", err)
+	}
+}
+
+func TestExpandNothingKnown(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string][]byte{"scans/notes.txt": []byte("not a scan")})
+
+	_, skipped, cleanup, err := Expand(filepath.Join(dir, "scans"), "")
+	defer cleanup()
+	if err == nil {
+		t.Error("a directory without scans expanded")
+	}
+	if fmt.Sprint(skipped) != "[scans/notes.txt]" {
+		t.Errorf("skipped = %v, want [scans/notes.txt]", skipped)
+	}
+}
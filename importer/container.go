@@ -0,0 +1,314 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sentlab/update-db/cvss"
+)
+
+func init() {
+	register(Format{Name: "trivy", open: newTrivyReader})
+	register(Format{Name: "grype", open: newGrypeReader})
+}
+
+// ColumnAssetType tells container findings from host findings; the
+// container importers set it to AssetContainerImage.
+const (
+	ColumnAssetType     = "asset_type"
+	AssetContainerImage = "container_image"
+)
+
+// containerLayout is the header of a container image import. The image
+// reference is the Host, and the operating system is the image distribution.
+var containerLayout = newRowLayout(
+	ColumnHost, ColumnName, ColumnCVSS, ColumnCVE, ColumnSeverity, ColumnOS, ColumnAssetType,
+	"vulnerability_id", "package", "package_type", "purl", "installed_version", "fixed_version",
+	"cvss_vector", "target", "url",
+)
+
+// containerFinding is one vulnerable package of an image.
+type containerFinding struct {
+	Image, Distro                  string
+	ID, CVE, Title, Severity       string
+	Package, PackageType, PURL     string
+	InstalledVersion, FixedVersion string
+	Score, Vector, Target, URL     string
+}
+
+func (f containerFinding) row() []string {
+	row := containerLayout.row()
+	row.set(ColumnHost, f.Image)
+	row.set(ColumnName, firstNonEmpty(f.Title, f.ID+" ("+f.Package+")"))
+	row.set(ColumnCVSS, f.Score)
+	row.set(ColumnCVE, f.CVE)
+	row.set(ColumnSeverity, namedSeverity(f.Severity))
+	row.set(ColumnOS, f.Distro)
+	row.set(ColumnAssetType, AssetContainerImage)
+
+	row.set("vulnerability_id", f.ID)
+	row.set("package", f.Package)
+	row.set("package_type", f.PackageType)
+	row.set("purl", f.PURL)
+	row.set("installed_version", f.InstalledVersion)
+	row.set("fixed_version", f.FixedVersion)
+	row.set("cvss_vector", f.Vector)
+	row.set("target", f.Target)
+	row.set("url", f.URL)
+	return row.values
+}
+
+// sliceReader returns a header and rows prepared in memory.
+type sliceReader struct {
+	header     []string
+	rows       [][]string
+	headerSent bool
+	// about is the subject of the rows, if they have a single one.
+	about string
+}
+
+func (r *sliceReader) subject() string { return r.about }
+
+func (r *sliceReader) Read() ([]string, error) {
+	if !r.headerSent {
+		r.headerSent = true
+		return r.header, nil
+	}
+	if len(r.rows) == 0 {
+		return nil, io.EOF
+	}
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	return row, nil
+}
+
+// trivyReport is the JSON report of trivy image --format json (schema 2).
+type trivyReport struct {
+	ArtifactName string `json:"ArtifactName"`
+	Metadata     struct {
+		OS struct {
+			Family string `json:"Family"`
+			Name   string `json:"Name"`
+		} `json:"OS"`
+	} `json:"Metadata"`
+	Results []struct {
+		Target          string `json:"Target"`
+		Type            string `json:"Type"`
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"VulnerabilityID"`
+			PkgName         string `json:"PkgName"`
+			PkgIdentifier   struct {
+				PURL string `json:"PURL"`
+			} `json:"PkgIdentifier"`
+			InstalledVersion string `json:"InstalledVersion"`
+			FixedVersion     string `json:"FixedVersion"`
+			Severity         string `json:"Severity"`
+			SeveritySource   string `json:"SeveritySource"`
+			Title            string `json:"Title"`
+			PrimaryURL       string `json:"PrimaryURL"`
+			CVSS             map[string]struct {
+				V2Vector string  `json:"V2Vector"`
+				V3Vector string  `json:"V3Vector"`
+				V2Score  float64 `json:"V2Score"`
+				V3Score  float64 `json:"V3Score"`
+			} `json:"CVSS"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+func newTrivyReader(r io.Reader, _ Options) (rowSource, error) {
+	var report trivyReport
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, fmt.Errorf("invalid Trivy JSON: %w", err)
+	}
+
+	distro := strings.TrimSpace(report.Metadata.OS.Family + " " + report.Metadata.OS.Name)
+	reader := &sliceReader{header: containerLayout.header, about: report.ArtifactName}
+	for _, result := range report.Results {
+		for _, v := range result.Vulnerabilities {
+			f := containerFinding{
+				Image:            report.ArtifactName,
+				Distro:           distro,
+				ID:               v.VulnerabilityID,
+				CVE:              cveOf(v.VulnerabilityID),
+				Title:            v.Title,
+				Severity:         v.Severity,
+				Package:          v.PkgName,
+				PURL:             v.PkgIdentifier.PURL,
+				PackageType:      result.Type,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+				Target:           result.Target,
+				URL:              v.PrimaryURL,
+			}
+			// Prefer NVD, then the source Trivy took the severity from,
+			// then any vendor; CVSS v3 over v2
+			for _, source := range append([]string{"nvd", v.SeveritySource}, sortedKeys(v.CVSS)...) {
+				cvss, ok := v.CVSS[source]
+				if !ok {
+					continue
+				}
+				if cvss.V3Score > 0 {
+					f.Score, f.Vector = formatScore(cvss.V3Score), cvss.V3Vector
+					break
+				}
+				if cvss.V2Score > 0 && f.Score == "" {
+					f.Score, f.Vector = formatScore(cvss.V2Score), cvss.V2Vector
+				}
+			}
+			reader.rows = append(reader.rows, f.row())
+		}
+	}
+	return reader, nil
+}
+
+// grypeCVSS is a CVSS entry of a Grype vulnerability.
+type grypeCVSS struct {
+	Version string `json:"version"`
+	Vector  string `json:"vector"`
+	Metrics struct {
+		BaseScore float64 `json:"baseScore"`
+	} `json:"metrics"`
+}
+
+// grypeVulnerability is a vulnerability or related vulnerability of a match.
+type grypeVulnerability struct {
+	ID       string      `json:"id"`
+	Severity string      `json:"severity"`
+	URLs     []string    `json:"urls"`
+	CVSS     []grypeCVSS `json:"cvss"`
+	Fix      struct {
+		Versions []string `json:"versions"`
+	} `json:"fix"`
+}
+
+// grypeReport is the JSON report of grype -o json.
+type grypeReport struct {
+	Matches []struct {
+		Vulnerability          grypeVulnerability   `json:"vulnerability"`
+		RelatedVulnerabilities []grypeVulnerability `json:"relatedVulnerabilities"`
+		Artifact               struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+			Type    string `json:"type"`
+			PURL    string `json:"purl"`
+		} `json:"artifact"`
+	} `json:"matches"`
+	Source struct {
+		Type   string          `json:"type"`
+		Target json.RawMessage `json:"target"`
+	} `json:"source"`
+	Distro struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"distro"`
+}
+
+func newGrypeReader(r io.Reader, _ Options) (rowSource, error) {
+	var report grypeReport
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, fmt.Errorf("invalid Grype JSON: %w", err)
+	}
+
+	image := grypeTarget(report.Source.Target)
+	distro := strings.TrimSpace(report.Distro.Name + " " + report.Distro.Version)
+	reader := &sliceReader{header: containerLayout.header, about: image}
+	for _, m := range report.Matches {
+		v := m.Vulnerability
+		f := containerFinding{
+			Image:            image,
+			Distro:           distro,
+			ID:               v.ID,
+			CVE:              cveOf(v.ID),
+			Severity:         v.Severity,
+			Package:          m.Artifact.Name,
+			PackageType:      m.Artifact.Type,
+			PURL:             m.Artifact.PURL,
+			InstalledVersion: m.Artifact.Version,
+			FixedVersion:     strings.Join(v.Fix.Versions, ", "),
+			Target:           report.Source.Type,
+		}
+		if len(v.URLs) > 0 {
+			f.URL = v.URLs[0]
+		}
+		// GitHub advisories carry their CVE and NVD scores as related entries
+		candidates := append([]grypeVulnerability{v}, m.RelatedVulnerabilities...)
+		for _, related := range candidates {
+			if f.CVE == "" {
+				f.CVE = cveOf(related.ID)
+			}
+			if f.Score == "" {
+				f.Score, f.Vector = grypeScore(related.CVSS)
+			}
+		}
+		reader.rows = append(reader.rows, f.row())
+	}
+	return reader, nil
+}
+
+// grypeTarget returns the image reference a Grype source points at. The
+// target is the user input string for directories and an object for images.
+func grypeTarget(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var image struct {
+		UserInput string   `json:"userInput"`
+		Tags      []string `json:"tags"`
+	}
+	if err := json.Unmarshal(raw, &image); err == nil {
+		if image.UserInput != "" {
+			return image.UserInput
+		}
+		if len(image.Tags) > 0 {
+			return image.Tags[0]
+		}
+	}
+	return ""
+}
+
+// grypeScore returns the highest version CVSS base score of a list.
+func grypeScore(entries []grypeCVSS) (string, string) {
+	var best *grypeCVSS
+	for i := range entries {
+		e := &entries[i]
+		if e.Metrics.BaseScore <= 0 {
+			continue
+		}
+		if best == nil || cvss.CompareVersions(e.Version, best.Version) > 0 {
+			best = e
+		}
+	}
+	if best == nil {
+		return "", ""
+	}
+	return formatScore(best.Metrics.BaseScore), best.Vector
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// cveOf returns id when it is a CVE identifier.
+func cveOf(id string) string {
+	if strings.HasPrefix(strings.ToUpper(id), "CVE-") {
+		return strings.ToUpper(id)
+	}
+	return ""
+}
+
+// formatScore prints a CVSS score with one decimal.
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', 1, 64)
+}
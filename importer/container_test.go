@@ -0,0 +1,65 @@
+package importer
+
+import "testing"
+
+func TestContainerScans(t *testing.T) {
+	for _, tt := range []struct {
+		file string
+		want []map[string]string
+	}{
+		{
+			file: "trivy.json",
+			want: []map[string]string{
+				// NVD scores win over those of other sources
+				{ColumnHost: "nginx:1.25", ColumnName: "HTTP/2 Rapid Reset", ColumnCVSS: "7.5", ColumnCVE: "CVE-2023-44487",
+					ColumnSeverity: "High", ColumnOS: "debian 12.5", ColumnAssetType: AssetContainerImage, "package": "libnghttp2-14",
+					"package_type": "debian", "installed_version": "1.52.0-1", "fixed_version": "1.52.0-1+deb12u1",
+					"cvss_vector": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H", "target": "nginx:1.25 (debian 12.5)"},
+				{ColumnName: "CVE-2011-3374 (apt)", ColumnCVSS: "4.3", ColumnSeverity: "Low", "cvss_vector": "AV:N/AC:M/Au:N/C:N/I:P/A:N"},
+				{ColumnCVSS: "5.3", ColumnCVE: "", ColumnSeverity: "Medium", "vulnerability_id": "GHSA-m425-mq94-257g",
+					"package_type": "gobinary", "target": "usr/local/bin/app"},
+			},
+		},
+		{
+			file: "grype.json",
+			want: []map[string]string{
+				// GitHub advisories take the CVE and score of their related entry
+				{ColumnHost: "nginx:1.25", ColumnName: "GHSA-m425-mq94-257g (google.golang.org/grpc)", ColumnCVSS: "7.5",
+					ColumnCVE: "CVE-2023-44487", ColumnSeverity: "High", ColumnOS: "debian 12", ColumnAssetType: AssetContainerImage,
+					"fixed_version": "1.56.3, 1.57.1", "purl": "pkg:golang/google.golang.org/grpc@v1.56.2", "target": "image",
+					"url": "https://github.com/advisories/GHSA-m425-mq94-257g"},
+				{ColumnCVSS: "", ColumnCVE: "CVE-2011-3374", ColumnSeverity: "Info", "package": "apt", "fixed_version": ""},
+			},
+		},
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			header, rows := readRows(t, tt.file, "", Options{})
+			checkHeader(t, header)
+			checkRows(t, rows, tt.want)
+		})
+	}
+}
+
+func TestGrypeScore(t *testing.T) {
+	entry := func(version string, score float64) grypeCVSS {
+		e := grypeCVSS{Version: version, Vector: "v" + version}
+		e.Metrics.BaseScore = score
+		return e
+	}
+	for _, tt := range []struct {
+		entries    []grypeCVSS
+		wantScore  string
+		wantVector string
+	}{
+		{[]grypeCVSS{entry("2.0", 10), entry("3.1", 9.8), entry("3.0", 9.1)}, "9.8", "v3.1"},
+		{[]grypeCVSS{entry("10.0", 4.2), entry("4.0", 8.7)}, "4.2", "v10.0"},
+		// Entries without a score are passed over
+		{[]grypeCVSS{entry("3.1", 0), entry("2.0", 5.0)}, "5.0", "v2.0"},
+		{nil, "", ""},
+	} {
+		score, vector := grypeScore(tt.entries)
+		if score != tt.wantScore || vector != tt.wantVector {
+			t.Errorf("grypeScore = %s %s, want %s %s", score, vector, tt.wantScore, tt.wantVector)
+		}
+	}
+}
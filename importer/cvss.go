@@ -0,0 +1,131 @@
+package importer
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sentlab/update-db/cvss"
+)
+
+// Columns filled in from the CVSS vector of a finding. The reports group
+// findings by attack vector and privileges required.
+const (
+	ColumnCVSSVersion        = "cvss_version"
+	ColumnScoreBase          = "cvss_score_base"
+	ColumnScoreTemporal      = "cvss_score_temporal"
+	ColumnScoreEnvironmental = "cvss_score_environmental"
+	ColumnAttackVector       = "cvss_attack_vector"
+	ColumnAttackComplexity   = "cvss_attack_complexity"
+	ColumnPrivileges         = "cvss_privileges_required"
+	ColumnUserInteraction    = "cvss_user_interaction"
+	ColumnScope              = "cvss_scope"
+	ColumnConfidentiality    = "cvss_confidentiality"
+	ColumnIntegrity          = "cvss_integrity"
+	ColumnAvailability       = "cvss_availability"
+)
+
+// vectorColumns are the columns filled in from a CVSS vector, in order.
+var vectorColumns = []string{
+	ColumnCVSSVersion, ColumnScoreBase, ColumnScoreTemporal, ColumnScoreEnvironmental,
+	ColumnAttackVector, ColumnAttackComplexity, ColumnPrivileges, ColumnUserInteraction,
+	ColumnScope, ColumnConfidentiality, ColumnIntegrity, ColumnAvailability,
+}
+
+// vectorSource adds the scores and components of the CVSS vectors of the
+// rows of source as columns. Of rows with several vectors, such as the v2
+// and v3 ones of Nessus, the latest version counts. A row without a CVSS
+// score gets the score of its vector. Vectors that do not parse are left
+// out.
+type vectorSource struct {
+	source     rowSource
+	header     []string
+	headerErr  error
+	headerSent bool
+	// vectors are the indexes of the vector columns; none when the file
+	// has no vector or holds the components already, as an export does.
+	vectors []int
+	// score is the index of the CVSS column, or -1.
+	score int
+}
+
+func withVectors(source rowSource) *vectorSource {
+	v := &vectorSource{source: source, score: -1}
+	if v.header, v.headerErr = source.Read(); v.headerErr != nil {
+		return v
+	}
+	for _, name := range v.header {
+		if headerKey(name) == headerKey(ColumnCVSSVersion) {
+			return v
+		}
+	}
+	for i, name := range v.header {
+		if isVectorHeader(name) {
+			v.vectors = append(v.vectors, i)
+		}
+		if headerKey(name) == headerKey(ColumnCVSS) && v.score < 0 {
+			v.score = i
+		}
+	}
+	if len(v.vectors) > 0 {
+		v.header = append(append([]string(nil), v.header...), vectorColumns...)
+	}
+	return v
+}
+
+// isVectorHeader reports whether a header names a CVSS vector, like
+// cvss_vector, CVSS3 Vector or Vector.
+func isVectorHeader(name string) bool {
+	key := headerKey(name)
+	return key == "vector" || strings.HasPrefix(key, "cvss") && strings.HasSuffix(key, "vector") &&
+		key != headerKey(ColumnAttackVector)
+}
+
+func (v *vectorSource) Read() ([]string, error) {
+	if !v.headerSent {
+		v.headerSent = true
+		return v.header, v.headerErr
+	}
+	row, err := v.source.Read()
+	if err != nil || len(v.vectors) == 0 {
+		return row, err
+	}
+
+	var vector *cvss.Vector
+	for _, i := range v.vectors {
+		if i >= len(row) || strings.TrimSpace(row[i]) == "" {
+			continue
+		}
+		if parsed, err := cvss.Parse(row[i]); err == nil && (vector == nil || cvss.CompareVersions(parsed.Version, vector.Version) > 0) {
+			vector = parsed
+		}
+	}
+	values := make([]string, len(vectorColumns))
+	if vector != nil {
+		values = []string{
+			vector.Version, formatVectorScore(vector.BaseScore()), formatVectorScore(vector.TemporalScore()),
+			formatVectorScore(vector.EnvironmentalScore()), vector.AttackVector(), vector.AttackComplexity(),
+			vector.PrivilegesRequired(), vector.UserInteraction(), vector.Scope(),
+			vector.Confidentiality(), vector.Integrity(), vector.Availability(),
+		}
+		if v.score >= 0 && v.score < len(row) && strings.TrimSpace(row[v.score]) == "" {
+			row[v.score] = formatVectorScore(vector.Score())
+		}
+	}
+	// Short rows are padded so the columns line up with the header
+	for len(row) < len(v.header)-len(vectorColumns) {
+		row = append(row, "")
+	}
+	return append(row, values...), nil
+}
+
+// derived returns the columns the source adds to those of the file.
+func (v *vectorSource) derived() []string {
+	if len(v.vectors) == 0 {
+		return nil
+	}
+	return vectorColumns
+}
+
+func formatVectorScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', 1, 64)
+}
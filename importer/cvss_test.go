@@ -0,0 +1,41 @@
+package importer
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestVectorSourceLatestVersion(t *testing.T) {
+	v := withVectors(csv.NewReader(strings.NewReader("Host,CVSS,CVSS v4 Vector,CVSS v3 Vector,CVSS v2 Vector\n" +
+		"10.0.0.1,,CVSS:4.0/AV:N/AC:L/AT:N/PR:L/UI:N/VC:N/VI:N/VA:H/SC:N/SI:N/SA:N,CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H,AV:N/AC:L/Au:N/C:C/I:C/A:C\n" +
+		"10.0.0.2,5.0,,CVSS:3.0/AV:L/AC:L/PR:N/UI:R/S:U/C:H/I:H/A:H,AV:N/AC:L/Au:N/C:C/I:C/A:C\n" +
+		"10.0.0.3,,not a vector,,AV:N/AC:M/Au:N/C:P/I:P/A:P\n")))
+	header, err := v.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]string
+	for {
+		record, err := v.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		row := map[string]string{}
+		for i, column := range header {
+			row[column] = record[i]
+		}
+		rows = append(rows, row)
+	}
+	checkRows(t, rows, []map[string]string{
+		// The v4.0 vector beats the later columns, and scores the row
+		{ColumnCVSS: "7.1", ColumnCVSSVersion: "4.0", ColumnScoreBase: "7.1", ColumnAttackVector: "Network", ColumnPrivileges: "Low"},
+		// A given score stays
+		{ColumnCVSS: "5.0", ColumnCVSSVersion: "3.0", ColumnScoreBase: "7.8", ColumnAttackVector: "Local"},
+		{ColumnCVSS: "6.8", ColumnCVSSVersion: "2.0", ColumnScoreBase: "6.8"},
+	})
+}
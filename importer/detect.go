@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"bufio"
+	stdcsv "encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+)
+
+// sniffLimit bounds how much of a file sniffFormat reads.
+const sniffLimit = 64 << 10
+
+// xmlRoots names the formats by the root element of their files.
+var xmlRoots = map[string]string{
+	"NessusClientData_v2":  "nessus",
+	"nmaprun":              "nmap",
+	"SCAN":                 "qualys-xml",
+	"ASSET_DATA_REPORT":    "qualys-xml",
+	"report":               "openvas",
+	"get_reports_response": "openvas",
+	"NexposeReport":        "rapid7",
+	"bom":                  "cyclonedx",
+}
+
+// jsonKeys names the formats by a top level key of their files.
+var jsonKeys = map[string]string{
+	"SchemaVersion": "trivy",
+	"ArtifactName":  "trivy",
+	"matches":       "grype",
+	"runs":          "sarif",
+	"bomFormat":     "cyclonedx",
+	"spdxVersion":   "spdx",
+}
+
+// sniffFormat tells the format of the file at path from its start: the root
+// element of XML, the top level keys of a JSON object, or the column header
+// of CSV. It returns "" when the content tells none.
+func sniffFormat(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	head := bufio.NewReader(io.LimitReader(f, sniffLimit))
+	for {
+		r, _, err := head.ReadRune()
+		if err != nil {
+			return ""
+		}
+		if r == '\ufeff' || strings.ContainsRune(" \t\r\n", r) {
+			continue
+		}
+		head.UnreadRune()
+		switch r {
+		case '<':
+			return sniffXML(head)
+		case '{':
+			return sniffJSON(head)
+		}
+		return sniffCSV(head)
+	}
+}
+
+func sniffXML(r io.Reader) string {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return xmlRoots[start.Name.Local]
+		}
+	}
+}
+
+// sniffJSON returns the format of the first telling key of a JSON object,
+// skipping the values of the others.
+func sniffJSON(r io.Reader) string {
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return ""
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		key, _ := token.(string)
+		if format, ok := jsonKeys[key]; ok {
+			return format
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return ""
+		}
+		// SARIF and CycloneDX files may start with their schema
+		if key == "$schema" {
+			schema := strings.ToLower(string(value))
+			switch {
+			case strings.Contains(schema, "sarif"):
+				return "sarif"
+			case strings.Contains(schema, "cyclonedx"):
+				return "cyclonedx"
+			}
+		}
+	}
+	return ""
+}
+
+// sniffCSV tells Qualys reports, whose column header follows the report
+// metadata, and EPSS scores from their first rows.
+func sniffCSV(r io.Reader) string {
+	reader := stdcsv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	for i := 0; i < 50; i++ {
+		record, err := reader.Read()
+		if err != nil {
+			return ""
+		}
+		keys := make(map[string]bool, len(record))
+		for _, field := range record {
+			keys[headerKey(field)] = true
+		}
+		switch {
+		case i == 0 && strings.HasPrefix(record[0], "#model_version"):
+			return "epss"
+		case keys["cve"] && keys["epss"] && keys["percentile"]:
+			return "epss"
+		case keys["ip"] && keys["qid"]:
+			return "qualys-csv"
+		}
+	}
+	return ""
+}
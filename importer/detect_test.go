@@ -0,0 +1,49 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"scan.nessus", "", "nessus"},
+		{"results.sarif.json", `{"version": "2.1.0"}`, "sarif"},
+		{"findings.csv", "Host,Name,CVSS\n10.0.0.1,Weak TLS,5.3\n", "csv"},
+		{"report.xml", `<?xml version="1.0"?><!DOCTYPE nmaprun><nmaprun scanner="nmap"></nmaprun>`, "nmap"},
+		{"nessus.xml", "<NessusClientData_v2><Report/></NessusClientData_v2>", "nessus"},
+		{"scan.xml", "\ufeff<SCAN value=\"scan/1\"></SCAN>", "qualys-xml"},
+		{"vm", "<ASSET_DATA_REPORT></ASSET_DATA_REPORT>", "qualys-xml"},
+		{"gvm.xml", `<report id="1"><report id="1"></report></report>`, "openvas"},
+		{"nexpose.xml", `<NexposeReport version="2.0"></NexposeReport>`, "rapid7"},
+		{"sbom.xml", `<bom xmlns="http://cyclonedx.org/schema/bom/1.5"></bom>`, "cyclonedx"},
+		{"image.json", `{"SchemaVersion": 2, "ArtifactName": "nginx:1.25"}`, "trivy"},
+		{"grype.json", `{"matches": [{"vulnerability": {"id": "CVE-2023-0001"}}], "source": {}}`, "grype"},
+		{"code.json", `{"$schema": "https://json.schemastore.org/sarif-2.1.0.json", "version": "2.1.0", "runs": []}`, "sarif"},
+		{"sbom.json", `{"$schema": "http://cyclonedx.org/schema/bom-1.5.schema.json", "bomFormat": "CycloneDX"}`, "cyclonedx"},
+		{"spdx.json", `{"SPDXID": "SPDXRef-DOCUMENT", "spdxVersion": "SPDX-2.3"}`, "spdx"},
+		{"qualys.csv", "\"Scan Results\",\"scan/1\"\n\n\"IP\",\"DNS\",\"QID\",\"Title\"\n", "qualys-csv"},
+		{"epss_scores-2024-05-06.csv", "#model_version:v2023.03.01,score_date:2024-05-06T00:00:00+0000\ncve,epss,percentile\n", "epss"},
+		{"scores.csv", "cve,epss,percentile\nCVE-2020-5902,0.97565,0.99990\n", "epss"},
+		{"notes.txt", "nothing to import", ""},
+		{"other.xml", "<catalog></catalog>", ""},
+		{"other.json", `{"name": "package", "version": "1.0.0"}`, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := DetectFormat(path)
+			if got != tt.want || (err != nil) != (tt.want == "") {
+				t.Errorf("DetectFormat = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
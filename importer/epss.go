@@ -0,0 +1,115 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	register(Format{Name: "epss", Kind: Scores, open: newEPSSReader})
+}
+
+// Columns of an EPSS import, filled from the daily EPSS CSV of FIRST.
+const (
+	ColumnEPSS       = "epss"
+	ColumnPercentile = "percentile"
+)
+
+// epssLayout is the header of an EPSS import: one row per CVE, with the
+// model and the day of the scores that the file starts with.
+var epssLayout = newRowLayout(ColumnCVE, ColumnEPSS, ColumnPercentile, "model_version", "score_date")
+
+// epssReader reads the EPSS CSV file FIRST publishes every day, e.g.
+//
+//	#model_version:v2023.03.01,score_date:2023-03-05T00:00:00+0000
+//	cve,epss,percentile
+//	CVE-2020-5902,0.97565,0.99990
+type epssReader struct {
+	r *csv.Reader
+	// columns are the indexes of the cve, epss and percentile fields.
+	columns    [3]int
+	model      string
+	date       string
+	headerSent bool
+}
+
+func newEPSSReader(r io.Reader, _ Options) (rowSource, error) {
+	reader := &epssReader{}
+
+	// The comment lines name the model and the day of the scores
+	buffered := bufio.NewReader(r)
+	for {
+		next, err := buffered.Peek(1)
+		if err != nil || next[0] != '#' {
+			break
+		}
+		line, err := buffered.ReadString('\n')
+		for _, field := range strings.Split(strings.TrimSpace(strings.TrimPrefix(line, "#")), ",") {
+			key, value, _ := strings.Cut(field, ":")
+			switch strings.TrimSpace(key) {
+			case "model_version":
+				reader.model = strings.TrimSpace(value)
+			case "score_date":
+				reader.date = strings.TrimSpace(value)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	reader.r = csv.NewReader(buffered)
+	reader.r.FieldsPerRecord = -1
+	header, err := reader.r.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("file has no header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for i, name := range []string{"cve", "epss", "percentile"} {
+		column, ok := index[name]
+		if !ok {
+			return nil, fmt.Errorf("header has no %s column; not an EPSS file", name)
+		}
+		reader.columns[i] = column
+	}
+	return reader, nil
+}
+
+func (r *epssReader) Read() ([]string, error) {
+	if !r.headerSent {
+		r.headerSent = true
+		return epssLayout.header, nil
+	}
+	for {
+		fields, err := r.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		field := func(i int) string {
+			if r.columns[i] < len(fields) {
+				return fields[r.columns[i]]
+			}
+			return ""
+		}
+		cve := strings.ToUpper(strings.TrimSpace(field(0)))
+		if cve == "" {
+			continue
+		}
+		row := epssLayout.row()
+		row.set(ColumnCVE, cve)
+		row.set(ColumnEPSS, field(1))
+		row.set(ColumnPercentile, field(2))
+		row.set("model_version", r.model)
+		row.set("score_date", r.date)
+		return row.values, nil
+	}
+}
@@ -0,0 +1,59 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEPSS(t *testing.T) {
+	header, rows := readRows(t, "epss_scores-2024-03-04.csv", "", Options{})
+	if got := strings.Join(header, " "); got != "CVE epss percentile model_version score_date" {
+		t.Errorf("header = %s", got)
+	}
+	checkRows(t, rows, []map[string]string{
+		{ColumnCVE: "CVE-2020-5902", ColumnEPSS: "0.97565", ColumnPercentile: "0.99990",
+			"model_version": "v2023.03.01", "score_date": "2024-03-04T00:00:00+0000"},
+		{ColumnCVE: "CVE-2021-44228", ColumnEPSS: "0.97554"},
+		// Rows without a CVE are skipped, and short rows read empty
+		{ColumnCVE: "CVE-2024-0001", ColumnEPSS: "0.00043", ColumnPercentile: "", "model_version": "v2023.03.01"},
+	})
+}
+
+func TestEPSSHeader(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+		want    []map[string]string
+		wantErr string
+	}{
+		{
+			// Files without the comment line name neither model nor day
+			name:    "reordered",
+			content: "Percentile,CVE,EPSS\n0.5,CVE-2023-0001,0.01\n",
+			want:    []map[string]string{{ColumnCVE: "CVE-2023-0001", ColumnEPSS: "0.01", ColumnPercentile: "0.5", "model_version": "", "score_date": ""}},
+		},
+		{name: "missing column", content: "cve,epss\nCVE-2023-0001,0.01\n", wantErr: "no percentile column"},
+		{name: "empty", content: "#model_version:v2023.03.01\n", wantErr: "no header row"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scores.csv")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			r, err := Open(path, "epss", Options{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Open = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, rows := collectRows(t, "scores.csv", r)
+			checkRows(t, rows, tt.want)
+		})
+	}
+}
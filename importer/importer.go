@@ -0,0 +1,352 @@
+// Package importer reads scanner output as rows that sql.ImportRows can load.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sentlab/update-db/csv"
+)
+
+// Standard columns filled in by the importers. The report queries read Host,
+// Name, CVSS and CVE; the OS counts read asset_operating_system and Severity.
+const (
+	ColumnHost     = "Host"
+	ColumnName     = "Name"
+	ColumnCVSS     = "CVSS"
+	ColumnCVE      = "CVE"
+	ColumnSeverity = "Severity"
+	ColumnPort     = "Port"
+	ColumnProtocol = "Protocol"
+	ColumnOS       = "asset_operating_system"
+)
+
+// Reader yields a header row followed by data rows. Read returns io.EOF
+// after the last row.
+type Reader interface {
+	Read() ([]string, error)
+	BytesRead() int64
+	Close() error
+	// Subject names the image, product or repository the file covers,
+	// or is empty when it covers many hosts.
+	Subject() string
+	// Detected returns the header profile the file matched, or nil.
+	Detected() *Detection
+	// Derived lists the columns the reader adds to those of the file, such
+	// as the components of CVSS vectors.
+	Derived() []string
+}
+
+// Format is a kind of input file the importer understands.
+type Format struct {
+	// Name selects the format, e.g. on the command line.
+	Name string
+	// Extensions are the file name suffixes the format is detected by.
+	Extensions []string
+	// Kind is the kind of table the format loads into.
+	Kind Kind
+	// open reads the rows of r.
+	open func(r io.Reader, opts Options) (rowSource, error)
+	// findings reads the vulnerability findings embedded in a file of
+	// another kind, such as an SBOM; nil when the format has none.
+	findings func(r io.Reader, opts Options) (rowSource, error)
+	// profiled formats have their header matched with the header profiles.
+	profiled bool
+}
+
+// Kind tells which table a format loads into.
+type Kind int
+
+// The kinds of table formats load into.
+const (
+	// Findings are vulnerabilities found on hosts, images or code, loaded
+	// into the source table.
+	Findings Kind = iota
+	// Assets describe hosts, their operating system and open ports, and are
+	// loaded into the assets table.
+	Assets
+	// Components list the software a product ships, and are loaded into
+	// the components table.
+	Components
+	// Statements are VEX statements on whether a product is affected by a
+	// vulnerability, and are loaded into the VEX table.
+	Statements
+	// Scores rate vulnerabilities, such as the EPSS probability that they
+	// are exploited, and are loaded into the EPSS table.
+	Scores
+)
+
+// EmbedsFindings reports whether files of the format may also carry
+// findings, read with OpenFindings.
+func (f Format) EmbedsFindings() bool { return f.findings != nil }
+
+// rowSource is the format specific part of a Reader.
+type rowSource interface {
+	Read() ([]string, error)
+}
+
+// subjecter is implemented by the row sources of files about one subject.
+type subjecter interface {
+	subject() string
+}
+
+// formats lists the supported formats by name.
+var formats = map[string]Format{}
+
+func register(format Format) {
+	formats[format.Name] = format
+}
+
+func init() {
+	register(Format{
+		Name:       "csv",
+		Extensions: []string{".csv"},
+		profiled:   true,
+		open: func(r io.Reader, _ Options) (rowSource, error) {
+			return csv.NewReader(r), nil
+		},
+	})
+}
+
+// Options tunes how formats turn their input into rows. The zero value
+// uses the defaults of every format.
+type Options struct {
+	// Levels maps SARIF result levels (error, warning, note, none) to a
+	// severity and CVSS score, over DefaultLevels.
+	Levels map[string]Level
+	// Asset names the asset of findings whose file does not, such as the
+	// repository of a SARIF file without version control details.
+	Asset string
+	// Sheet is the workbook sheet to read: its name, AllSheets, or empty
+	// for the first sheet.
+	Sheet string
+	// HeaderRow is the row number of the workbook header, or 0 to find it.
+	HeaderRow int
+	// Profile names the header profile of CSV files and workbooks, or is
+	// NoProfile to match none; empty detects it from the header.
+	Profile string
+	// Profiles are header profiles tried before BuiltinProfiles.
+	Profiles []Profile
+	// Fields maps table columns to the dotted paths of the JSON Lines
+	// fields that fill them, e.g. asset.ipv4 or cves.0. Without paths
+	// every field is a column.
+	Fields map[string]string
+}
+
+// Formats returns the names of the supported formats, sorted.
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the named format.
+func Lookup(name string) (Format, bool) {
+	format, ok := formats[strings.ToLower(name)]
+	return format, ok
+}
+
+// DetectFormat returns the format of the file at path: the one whose
+// extension path ends with, or else the one its content tells. The content
+// of .csv files is checked too, for the formats of their own.
+func DetectFormat(path string) (string, error) {
+	byName := ""
+	lower := strings.ToLower(path)
+	for _, name := range Formats() {
+		for _, ext := range formats[name].Extensions {
+			if byName == "" && strings.HasSuffix(lower, ext) {
+				byName = name
+			}
+		}
+	}
+	if byName == "" || byName == "csv" {
+		if sniffed := sniffFormat(path); sniffed != "" {
+			return sniffed, nil
+		}
+	}
+	if byName != "" {
+		return byName, nil
+	}
+	return "", fmt.Errorf("unable to detect the format of %s; choose one of %s", filepath.Base(path), strings.Join(Formats(), ", "))
+}
+
+// Open opens the file at path in the named format. An empty format is
+// detected with DetectFormat.
+func Open(path string, format string, opts Options) (Reader, error) {
+	if format == "" {
+		detected, err := DetectFormat(path)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+	f, ok := Lookup(format)
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q; choose one of %s", format, strings.Join(Formats(), ", "))
+	}
+	return openFile(path, f, f.open, opts)
+}
+
+// ErrNoFindings is returned by OpenFindings for a file without findings.
+var ErrNoFindings = errors.New("file carries no findings")
+
+// OpenFindings opens the findings embedded in the file at path, which is
+// in the named format. It fails with ErrNoFindings when there are none.
+func OpenFindings(path string, format string, opts Options) (Reader, error) {
+	f, ok := Lookup(format)
+	if !ok || f.findings == nil {
+		return nil, fmt.Errorf("format %q carries no findings", format)
+	}
+	return openFile(path, f, f.findings, opts)
+}
+
+func openFile(path string, f Format, open func(io.Reader, Options) (rowSource, error), opts Options) (Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	counter := &countingReader{r: file}
+	source, err := open(counter, opts)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("unable to read %s as %s: %w", filepath.Base(path), f.Name, err)
+	}
+	if f.profiled {
+		if source, err = withProfile(source, opts); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("unable to read %s as %s: %w", filepath.Base(path), f.Name, err)
+		}
+	}
+	return &reader{source: source, vectors: withVectors(source), counter: counter, closer: file}, nil
+}
+
+// reader ties a row source to the file it reads.
+type reader struct {
+	source rowSource
+	// vectors reads the rows of source with their CVSS vectors scored.
+	vectors *vectorSource
+	counter *countingReader
+	closer  io.Closer
+}
+
+func (r *reader) Read() ([]string, error) { return r.vectors.Read() }
+
+func (r *reader) BytesRead() int64 { return r.counter.n }
+
+func (r *reader) Close() error { return r.closer.Close() }
+
+func (r *reader) Subject() string {
+	if s, ok := r.source.(subjecter); ok {
+		return s.subject()
+	}
+	return ""
+}
+
+func (r *reader) Detected() *Detection {
+	if d, ok := r.source.(detector); ok {
+		return d.detected()
+	}
+	return nil
+}
+
+func (r *reader) Derived() []string { return r.vectors.derived() }
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Seek moves within the file, for formats read twice, and counts from there.
+func (c *countingReader) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := c.r.(io.Seeker)
+	if !ok {
+		return 0, errors.New("input cannot be read twice")
+	}
+	n, err := seeker.Seek(offset, whence)
+	if err == nil {
+		c.n = n
+	}
+	return n, err
+}
+
+// rewind moves r back to its start, for formats that read their input once
+// for what the rows refer to and again for the rows.
+func rewind(r io.Reader) error {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return errors.New("input cannot be read twice")
+	}
+	_, err := seeker.Seek(0, io.SeekStart)
+	return err
+}
+
+// rowLayout builds records for a fixed header.
+type rowLayout struct {
+	header []string
+	index  map[string]int
+}
+
+func newRowLayout(header ...string) *rowLayout {
+	layout := &rowLayout{header: header, index: make(map[string]int, len(header))}
+	for i, name := range header {
+		layout.index[name] = i
+	}
+	return layout
+}
+
+// row returns an empty record for the layout.
+func (l *rowLayout) row() record {
+	return record{layout: l, values: make([]string, len(l.header))}
+}
+
+// record is a row under construction.
+type record struct {
+	layout *rowLayout
+	values []string
+}
+
+// set stores value in the named column. Unknown columns are a programming error.
+func (r record) set(column string, value string) {
+	i, ok := r.layout.index[column]
+	if !ok {
+		panic("importer: unknown column " + column)
+	}
+	r.values[i] = strings.TrimSpace(value)
+}
+
+// get returns the value of the named column.
+func (r record) get(column string) string {
+	return r.values[r.layout.index[column]]
+}
+
+// severityName turns the 0-4 severity scale most scanners use into a name.
+func severityName(level string) string {
+	switch strings.TrimSpace(level) {
+	case "0":
+		return "Info"
+	case "1":
+		return "Low"
+	case "2":
+		return "Medium"
+	case "3":
+		return "High"
+	case "4":
+		return "Critical"
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// readRows opens a file of testdata in format and returns its header and
+// its rows keyed by column.
+func readRows(t *testing.T, name string, format string, opts Options) ([]string, []map[string]string) {
+	t.Helper()
+	r, err := Open(filepath.Join("testdata", name), format, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return collectRows(t, name, r)
+}
+
+// readFindings is readRows for the findings embedded in a file.
+func readFindings(t *testing.T, name string, format string) ([]string, []map[string]string) {
+	t.Helper()
+	r, err := OpenFindings(filepath.Join("testdata", name), format, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return collectRows(t, name, r)
+}
+
+// collectRows reads the header and rows of the file name until its end.
+func collectRows(t *testing.T, name string, r Reader) ([]string, []map[string]string) {
+	t.Helper()
+	defer r.Close()
+
+	header, err := r.Read()
+	if err != nil {
+		t.Fatalf("%s: header: %v", name, err)
+	}
+	header = append([]string(nil), header...)
+	var rows []map[string]string
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return header, rows
+		}
+		if err != nil {
+			t.Fatalf("%s: row %d: %v", name, len(rows)+2, err)
+		}
+		if len(record) != len(header) {
+			t.Fatalf("%s: row %d has %d values for %d columns", name, len(rows)+2, len(record), len(header))
+		}
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			row[column] = record[i]
+		}
+		rows = append(rows, row)
+	}
+}
+
+// checkRows compares the named columns of rows with want, row by row.
+func checkRows(t *testing.T, rows []map[string]string, want []map[string]string) {
+	t.Helper()
+	if len(rows) != len(want) {
+		t.Errorf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i := 0; i < len(rows) && i < len(want); i++ {
+		for column, value := range want[i] {
+			if rows[i][column] != value {
+				t.Errorf("row %d: %s = %q, want %q", i+1, column, rows[i][column], value)
+			}
+		}
+	}
+}
+
+// checkHeader checks that header starts with the standard columns of
+// findings.
+func checkHeader(t *testing.T, header []string) {
+	t.Helper()
+	standard := []string{ColumnHost, ColumnName, ColumnCVSS, ColumnCVE, ColumnSeverity}
+	for i, column := range standard {
+		if i >= len(header) || header[i] != column {
+			t.Fatalf("header %v does not start with %v", header, standard)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	register(Format{Name: "jsonl", Extensions: []string{".jsonl", ".ndjson"}, profiled: true, open: newJSONLReader})
+}
+
+// jsonlSampleLines is the number of lines whose fields make up the header
+// when no field paths are set.
+const jsonlSampleLines = 1000
+
+// jsonlReader reads one finding object per line. With field paths, each
+// path fills its column; without, every field is a column named by its
+// dotted path, e.g. asset.ipv4, as the first lines hold them.
+type jsonlReader struct {
+	r          *bufio.Reader
+	line       int
+	header     []string
+	headerSent bool
+	// paths are the field paths of the header columns.
+	paths [][]string
+	// buffered are the objects read ahead to find the header.
+	buffered []map[string]interface{}
+}
+
+func newJSONLReader(r io.Reader, opts Options) (rowSource, error) {
+	reader := &jsonlReader{r: bufio.NewReader(r)}
+	if len(opts.Fields) > 0 {
+		for _, column := range sortedKeys(opts.Fields) {
+			reader.header = append(reader.header, column)
+			reader.paths = append(reader.paths, strings.Split(opts.Fields[column], "."))
+		}
+		return reader, nil
+	}
+
+	// Without paths, the fields of the first lines are the columns
+	seen := map[string]bool{}
+	for len(reader.buffered) < jsonlSampleLines {
+		object, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		reader.buffered = append(reader.buffered, object)
+		for _, name := range flattenKeys(object, "") {
+			if !seen[name] {
+				seen[name] = true
+				reader.header = append(reader.header, name)
+				reader.paths = append(reader.paths, strings.Split(name, "."))
+			}
+		}
+	}
+	return reader, nil
+}
+
+// next decodes the object of the next line that is not blank.
+func (r *jsonlReader) next() (map[string]interface{}, error) {
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return nil, err
+		}
+		r.line++
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON object: %w", r.line, err)
+		}
+		return object, nil
+	}
+}
+
+func (r *jsonlReader) Read() ([]string, error) {
+	if !r.headerSent {
+		r.headerSent = true
+		return r.header, nil
+	}
+	var object map[string]interface{}
+	if len(r.buffered) > 0 {
+		object, r.buffered = r.buffered[0], r.buffered[1:]
+	} else {
+		var err error
+		if object, err = r.next(); err != nil {
+			return nil, err
+		}
+	}
+	row := make([]string, len(r.paths))
+	for i, path := range r.paths {
+		row[i] = jsonText(lookup(object, path))
+	}
+	return row, nil
+}
+
+// flattenKeys returns the dotted paths of the fields of object that hold
+// values rather than objects.
+func flattenKeys(object map[string]interface{}, prefix string) []string {
+	var keys []string
+	for _, key := range sortedKeys(object) {
+		if nested, ok := object[key].(map[string]interface{}); ok && len(nested) > 0 {
+			keys = append(keys, flattenKeys(nested, prefix+key+".")...)
+			continue
+		}
+		keys = append(keys, prefix+key)
+	}
+	return keys
+}
+
+// lookup follows path into value. A numeric step indexes an array; other
+// steps through an array collect the field of each element.
+func lookup(value interface{}, path []string) interface{} {
+	for i, step := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[step]
+		case []interface{}:
+			if index, err := strconv.Atoi(step); err == nil {
+				if index < 0 || index >= len(v) {
+					return nil
+				}
+				value = v[index]
+				continue
+			}
+			var values []interface{}
+			for _, element := range v {
+				if found := lookup(element, path[i:]); found != nil {
+					values = append(values, found)
+				}
+			}
+			return values
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// jsonText writes a JSON value the way a column holds it: lists joined like
+// the CVE column, objects as JSON and null as an empty value.
+func jsonText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if _, ok := item.(map[string]interface{}); ok {
+				data, _ := json.Marshal(v)
+				return string(data)
+			}
+			items = append(items, jsonText(item))
+		}
+		return joinList(items)
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
@@ -0,0 +1,68 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONL(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		opts       Options
+		wantHeader string
+		want       []map[string]string
+	}{
+		{
+			// Every field of the first lines is a column, and the header
+			// matches the Tenable export profile
+			name: "fields",
+			wantHeader: "asset.hostname asset.ipv4 asset.operating_system plugin.cve plugin.cvss3_base_score plugin.id plugin.name " +
+				"ports severity state fixed output.lines output.text",
+			want: []map[string]string{
+				{"asset.ipv4": "10.0.0.1", "asset.operating_system": "Ubuntu 22.04", "plugin.cve": "CVE-2016-2183, CVE-2016-6329",
+					"plugin.cvss3_base_score": "7.5", "plugin.id": "42873", "severity": "High",
+					"ports": `[{"port":443,"protocol":"tcp"},{"port":8443,"protocol":"tcp"}]`, "fixed": "", "output.text": ""},
+				{"asset.ipv4": "10.0.0.2", "plugin.cve": "", "plugin.cvss3_base_score": "", "severity": "Info", "ports": "",
+					"fixed": "false", "output.lines": "3", "output.text": "Scan took 12 minutes"},
+			},
+		},
+		{
+			name: "paths",
+			opts: Options{Fields: map[string]string{
+				ColumnHost: "asset.ipv4",
+				ColumnCVE:  "plugin.cve.0",
+				ColumnPort: "ports.port",
+				ColumnOS:   "asset.operating_system.0",
+				"missing":  "plugin.solution",
+				"beyond":   "plugin.cve.5",
+			}},
+			wantHeader: "CVE Host Port asset_operating_system beyond missing",
+			want: []map[string]string{
+				// A path through an array collects the field of each element
+				{ColumnHost: "10.0.0.1", ColumnCVE: "CVE-2016-2183", ColumnPort: "443, 8443", ColumnOS: "Ubuntu 22.04", "beyond": "", "missing": ""},
+				{ColumnHost: "10.0.0.2", ColumnCVE: "", ColumnPort: "", ColumnOS: "Microsoft Windows Server 2019"},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			header, rows := readRows(t, "findings.jsonl", "", tt.opts)
+			if got := strings.Join(header, " "); got != tt.wantHeader {
+				t.Errorf("header = %s, want %s", got, tt.wantHeader)
+			}
+			checkRows(t, rows, tt.want)
+		})
+	}
+}
+
+func TestJSONLInvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "findings.jsonl")
+	if err := os.WriteFile(path, []byte("{\"Host\": \"10.0.0.1\"}\n\n[1, 2]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Open(path, "", Options{})
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Open = %v, want an invalid line 3", err)
+	}
+}
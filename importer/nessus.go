@@ -0,0 +1,175 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	register(Format{
+		Name:       "nessus",
+		Extensions: []string{".nessus"},
+		open:       newNessusReader,
+	})
+}
+
+// nessusLayout is the header of a .nessus import: the standard columns
+// followed by the plugin and host details Nessus adds.
+var nessusLayout = newRowLayout(
+	ColumnHost, ColumnName, ColumnCVSS, ColumnCVE, ColumnSeverity, ColumnPort, ColumnProtocol, ColumnOS,
+	"service", "plugin_id", "plugin_family", "risk_factor",
+	"cvss_base_score", "cvss_vector", "cvss3_base_score", "cvss3_vector",
+	"synopsis", "description", "solution", "see_also", "plugin_output", "exploit_available",
+	"host_ip", "host_fqdn", "netbios_name", "mac_address",
+)
+
+// nessusItem is a ReportItem: one plugin result on one port of a host.
+type nessusItem struct {
+	Port             string   `xml:"port,attr"`
+	Service          string   `xml:"svc_name,attr"`
+	Protocol         string   `xml:"protocol,attr"`
+	Severity         string   `xml:"severity,attr"`
+	PluginID         string   `xml:"pluginID,attr"`
+	PluginName       string   `xml:"pluginName,attr"`
+	PluginFamily     string   `xml:"pluginFamily,attr"`
+	RiskFactor       string   `xml:"risk_factor"`
+	CVSSBaseScore    string   `xml:"cvss_base_score"`
+	CVSSVector       string   `xml:"cvss_vector"`
+	CVSS3BaseScore   string   `xml:"cvss3_base_score"`
+	CVSS3Vector      string   `xml:"cvss3_vector"`
+	CVEs             []string `xml:"cve"`
+	Synopsis         string   `xml:"synopsis"`
+	Description      string   `xml:"description"`
+	Solution         string   `xml:"solution"`
+	SeeAlso          string   `xml:"see_also"`
+	PluginOutput     string   `xml:"plugin_output"`
+	ExploitAvailable string   `xml:"exploit_available"`
+}
+
+// nessusHostProperties holds the <tag name="..."> values of a ReportHost.
+type nessusHostProperties struct {
+	Tags []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"tag"`
+}
+
+// nessusReader streams the ReportItems of a .nessus v2 file, one row each.
+type nessusReader struct {
+	decoder    *xml.Decoder
+	headerSent bool
+	host       string
+	properties map[string]string
+}
+
+func newNessusReader(r io.Reader, _ Options) (rowSource, error) {
+	return &nessusReader{decoder: xml.NewDecoder(r)}, nil
+}
+
+func (r *nessusReader) Read() ([]string, error) {
+	if !r.headerSent {
+		r.headerSent = true
+		return nessusLayout.header, nil
+	}
+
+	for {
+		token, err := r.decoder.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid Nessus XML: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "ReportHost":
+			r.host = attr(start, "name")
+			r.properties = map[string]string{}
+		case "HostProperties":
+			var properties nessusHostProperties
+			if err := r.decoder.DecodeElement(&properties, &start); err != nil {
+				return nil, fmt.Errorf("invalid HostProperties of %s: %w", r.host, err)
+			}
+			for _, tag := range properties.Tags {
+				r.properties[tag.Name] = strings.TrimSpace(tag.Value)
+			}
+		case "ReportItem":
+			var item nessusItem
+			if err := r.decoder.DecodeElement(&item, &start); err != nil {
+				return nil, fmt.Errorf("invalid ReportItem of %s: %w", r.host, err)
+			}
+			return r.row(item), nil
+		}
+	}
+}
+
+// row flattens a ReportItem and the properties of its host.
+func (r *nessusReader) row(item nessusItem) []string {
+	row := nessusLayout.row()
+	row.set(ColumnHost, r.host)
+	row.set(ColumnName, item.PluginName)
+	row.set(ColumnCVSS, firstNonEmpty(item.CVSS3BaseScore, item.CVSSBaseScore))
+	row.set(ColumnCVE, joinList(item.CVEs))
+	row.set(ColumnSeverity, nessusSeverity(item))
+	row.set(ColumnPort, item.Port)
+	row.set(ColumnProtocol, item.Protocol)
+	row.set(ColumnOS, r.properties["operating-system"])
+
+	row.set("service", item.Service)
+	row.set("plugin_id", item.PluginID)
+	row.set("plugin_family", item.PluginFamily)
+	row.set("risk_factor", item.RiskFactor)
+	row.set("cvss_base_score", item.CVSSBaseScore)
+	row.set("cvss_vector", item.CVSSVector)
+	row.set("cvss3_base_score", item.CVSS3BaseScore)
+	row.set("cvss3_vector", item.CVSS3Vector)
+	row.set("synopsis", item.Synopsis)
+	row.set("description", item.Description)
+	row.set("solution", item.Solution)
+	row.set("see_also", item.SeeAlso)
+	row.set("plugin_output", item.PluginOutput)
+	row.set("exploit_available", item.ExploitAvailable)
+	row.set("host_ip", r.properties["host-ip"])
+	row.set("host_fqdn", r.properties["host-fqdn"])
+	row.set("netbios_name", r.properties["netbios-name"])
+	row.set("mac_address", r.properties["mac-address"])
+	return row.values
+}
+
+// nessusSeverity prefers the risk factor, which follows the CVSS version
+// Nessus scored with, over the numeric severity attribute.
+func nessusSeverity(item nessusItem) string {
+	switch risk := strings.TrimSpace(item.RiskFactor); {
+	case strings.EqualFold(risk, "None"):
+		return "Info"
+	case risk != "":
+		return risk
+	}
+	return severityName(item.Severity)
+}
+
+// attr returns the value of the named attribute of an element.
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// firstNonEmpty returns the first of values that is not blank.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
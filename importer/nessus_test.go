@@ -0,0 +1,20 @@
+package importer
+
+import "testing"
+
+func TestNessus(t *testing.T) {
+	header, rows := readRows(t, "scan.nessus", "", Options{})
+	checkHeader(t, header)
+	checkRows(t, rows, []map[string]string{
+		{ColumnHost: "10.0.0.1", ColumnName: "Apache 2.4.49 < 2.4.51 Path Traversal", ColumnCVSS: "9.8",
+			ColumnCVE: "CVE-2021-41773, CVE-2021-42013", ColumnSeverity: "Critical", ColumnPort: "443", ColumnProtocol: "tcp",
+			ColumnOS: "Linux Kernel 5.15 on Ubuntu 22.04", "service": "www", "plugin_id": "153584",
+			"cvss_base_score": "7.5", "plugin_output": "Installed version : 2.4.49", "exploit_available": "true",
+			"host_fqdn": "web1.example.com", "mac_address": "00:50:56:aa:bb:cc"},
+		// A risk factor of None is informational
+		{ColumnHost: "10.0.0.1", ColumnName: "Nessus Scan Information", ColumnCVSS: "", ColumnSeverity: "Info", ColumnPort: "0"},
+		// Without a risk factor the severity attribute counts
+		{ColumnHost: "db1.example.com", ColumnCVSS: "5.3", ColumnSeverity: "Medium", ColumnOS: "Microsoft Windows Server 2019",
+			"host_ip": "10.0.0.2", "netbios_name": "DB1", "host_fqdn": ""},
+	})
+}
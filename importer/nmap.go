@@ -0,0 +1,168 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	register(Format{Name: "nmap", Kind: Assets, open: newNmapReader})
+}
+
+// nmapLayout is the header of an nmap import: one row per open port of a
+// host, or a single row for a host with no open port.
+var nmapLayout = newRowLayout(
+	ColumnHost, "hostname", "mac_address", "mac_vendor",
+	ColumnOS, "os_accuracy", "os_family",
+	ColumnPort, ColumnProtocol, "state", "service", "product", "version", "extra_info",
+)
+
+// nmapHost is a <host> of nmap -oX output.
+type nmapHost struct {
+	Status struct {
+		State string `xml:"state,attr"`
+	} `xml:"status"`
+	Addresses []struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+		Vendor   string `xml:"vendor,attr"`
+	} `xml:"address"`
+	Hostnames []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"hostnames>hostname"`
+	Ports []struct {
+		Protocol string `xml:"protocol,attr"`
+		PortID   string `xml:"portid,attr"`
+		State    struct {
+			State string `xml:"state,attr"`
+		} `xml:"state"`
+		Service struct {
+			Name      string `xml:"name,attr"`
+			Product   string `xml:"product,attr"`
+			Version   string `xml:"version,attr"`
+			ExtraInfo string `xml:"extrainfo,attr"`
+			OSType    string `xml:"ostype,attr"`
+		} `xml:"service"`
+	} `xml:"ports>port"`
+	OSMatches []struct {
+		Name      string `xml:"name,attr"`
+		Accuracy  string `xml:"accuracy,attr"`
+		OSClasses []struct {
+			Family string `xml:"osfamily,attr"`
+		} `xml:"osclass"`
+	} `xml:"os>osmatch"`
+}
+
+// nmapReader streams the hosts of nmap XML output.
+type nmapReader struct {
+	decoder    *xml.Decoder
+	headerSent bool
+	pending    [][]string
+}
+
+func newNmapReader(r io.Reader, _ Options) (rowSource, error) {
+	return &nmapReader{decoder: xml.NewDecoder(r)}, nil
+}
+
+func (r *nmapReader) Read() ([]string, error) {
+	if !r.headerSent {
+		r.headerSent = true
+		return nmapLayout.header, nil
+	}
+
+	for len(r.pending) == 0 {
+		token, err := r.decoder.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid nmap XML: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "host" {
+			continue
+		}
+		var host nmapHost
+		if err := r.decoder.DecodeElement(&host, &start); err != nil {
+			return nil, fmt.Errorf("invalid host: %w", err)
+		}
+		r.pending = nmapRows(host)
+	}
+
+	row := r.pending[0]
+	r.pending = r.pending[1:]
+	return row, nil
+}
+
+// nmapRows flattens a host that is up into one row per open port.
+func nmapRows(host nmapHost) [][]string {
+	if host.Status.State != "" && host.Status.State != "up" {
+		return nil
+	}
+
+	base := nmapLayout.row()
+	for _, a := range host.Addresses {
+		switch a.AddrType {
+		case "ipv4", "ipv6":
+			if base.get(ColumnHost) == "" {
+				base.set(ColumnHost, a.Addr)
+			}
+		case "mac":
+			base.set("mac_address", a.Addr)
+			base.set("mac_vendor", a.Vendor)
+		}
+	}
+	if len(host.Hostnames) > 0 {
+		base.set("hostname", host.Hostnames[0].Name)
+	}
+
+	// Take the most accurate OS match; nmap lists them best first
+	best := -1
+	for i, match := range host.OSMatches {
+		if best < 0 || atoi(match.Accuracy) > atoi(host.OSMatches[best].Accuracy) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		match := host.OSMatches[best]
+		base.set(ColumnOS, match.Name)
+		base.set("os_accuracy", match.Accuracy)
+		if len(match.OSClasses) > 0 {
+			base.set("os_family", match.OSClasses[0].Family)
+		}
+	}
+
+	var rows [][]string
+	for _, port := range host.Ports {
+		if !strings.HasPrefix(port.State.State, "open") {
+			continue
+		}
+		row := nmapLayout.row()
+		copy(row.values, base.values)
+		if row.get(ColumnOS) == "" {
+			// Fall back on the OS that service detection reported
+			row.set(ColumnOS, port.Service.OSType)
+		}
+		row.set(ColumnPort, port.PortID)
+		row.set(ColumnProtocol, port.Protocol)
+		row.set("state", port.State.State)
+		row.set("service", port.Service.Name)
+		row.set("product", port.Service.Product)
+		row.set("version", port.Service.Version)
+		row.set("extra_info", port.Service.ExtraInfo)
+		rows = append(rows, row.values)
+	}
+	if len(rows) == 0 {
+		rows = append(rows, base.values)
+	}
+	return rows
+}
+
+// atoi parses a number, treating anything else as zero.
+func atoi(value string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(value))
+	return n
+}
@@ -0,0 +1,23 @@
+package importer
+
+import "testing"
+
+func TestNmap(t *testing.T) {
+	header, rows := readRows(t, "nmap.xml", "nmap", Options{})
+	if len(header) == 0 || header[0] != ColumnHost {
+		t.Fatalf("header %v does not start with %s", header, ColumnHost)
+	}
+	// A host has a row per open port, and a single row without one; hosts
+	// that are down have none
+	checkRows(t, rows, []map[string]string{
+		{ColumnHost: "10.0.0.1", "hostname": "web1.example.com", "mac_address": "00:50:56:AA:BB:CC", "mac_vendor": "VMware",
+			ColumnOS: "Linux 5.0 - 5.14", "os_accuracy": "98", "os_family": "Linux",
+			ColumnPort: "22", ColumnProtocol: "tcp", "state": "open", "service": "ssh", "product": "OpenSSH",
+			"version": "8.9p1 Ubuntu 3ubuntu0.6", "extra_info": "Ubuntu Linux; protocol 2.0"},
+		{ColumnHost: "10.0.0.1", ColumnOS: "Linux 5.0 - 5.14", ColumnPort: "443", "service": "http", "product": "Apache httpd", "version": "2.4.49"},
+		{ColumnHost: "10.0.0.1", ColumnPort: "161", ColumnProtocol: "udp", "state": "open|filtered", "service": "snmp"},
+		// Without an OS match the OS of service detection counts
+		{ColumnHost: "10.0.0.2", ColumnOS: "Windows", ColumnPort: "445", "service": "microsoft-ds"},
+		{ColumnHost: "10.0.0.3", ColumnOS: "", ColumnPort: "", "state": ""},
+	})
+}
@@ -0,0 +1,246 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	register(Format{Name: "openvas", open: newOpenVASReader})
+}
+
+// openvasLayout is the header of an OpenVAS/Greenbone import: the standard
+// columns followed by the NVT details and the texts of its tags.
+var openvasLayout = newRowLayout(
+	ColumnHost, ColumnName, ColumnCVSS, ColumnCVE, ColumnSeverity, ColumnPort, ColumnProtocol, ColumnOS,
+	"hostname", "nvt_oid", "nvt_family", "threat", "cvss_vector", "qod",
+	"summary", "insight", "impact", "solution", "solution_type", "description",
+)
+
+// openvasResult is a <result>: one NVT finding on one port of a host.
+type openvasResult struct {
+	Name string `xml:"name"`
+	Host struct {
+		IP       string `xml:",chardata"`
+		Hostname string `xml:"hostname"`
+	} `xml:"host"`
+	Port string `xml:"port"`
+	NVT  struct {
+		OID        string `xml:"oid,attr"`
+		Name       string `xml:"name"`
+		Family     string `xml:"family"`
+		CVE        string `xml:"cve"`
+		Tags       string `xml:"tags"`
+		Severities []struct {
+			Value string `xml:"value"`
+		} `xml:"severities>severity"`
+		Solution struct {
+			Type string `xml:"type,attr"`
+			Text string `xml:",chardata"`
+		} `xml:"solution"`
+		Refs []struct {
+			Type string `xml:"type,attr"`
+			ID   string `xml:"id,attr"`
+		} `xml:"refs>ref"`
+	} `xml:"nvt"`
+	Threat      string `xml:"threat"`
+	Severity    string `xml:"severity"`
+	QoD         string `xml:"qod>value"`
+	Description string `xml:"description"`
+}
+
+// openvasHost is a report level <host> with the details gathered about it.
+type openvasHost struct {
+	IP      string `xml:"ip"`
+	Details []struct {
+		Name  string `xml:"name"`
+		Value string `xml:"value"`
+	} `xml:"detail"`
+}
+
+// openvasReader reads a Greenbone report. The host details, and with them
+// the operating systems, follow the results in the report, so they are read
+// in a first pass over the file and the results are streamed in a second.
+type openvasReader struct {
+	decoder    *xml.Decoder
+	headerSent bool
+	os         map[string]string
+}
+
+func newOpenVASReader(r io.Reader, _ Options) (rowSource, error) {
+	os, err := readOpenVASHosts(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := rewind(r); err != nil {
+		return nil, err
+	}
+	return &openvasReader{decoder: xml.NewDecoder(r), os: os}, nil
+}
+
+// readOpenVASHosts reads the operating system of each host from the report
+// level host details.
+func readOpenVASHosts(r io.Reader) (map[string]string, error) {
+	os := map[string]string{}
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return os, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid OpenVAS XML: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "result":
+			// Results name their host too, without its details
+			if err := decoder.Skip(); err != nil {
+				return nil, fmt.Errorf("invalid OpenVAS XML: %w", err)
+			}
+		case "host":
+			var host openvasHost
+			if err := decoder.DecodeElement(&host, &start); err != nil {
+				return nil, fmt.Errorf("invalid host: %w", err)
+			}
+			for _, detail := range host.Details {
+				if detail.Name == "best_os_txt" {
+					os[strings.TrimSpace(host.IP)] = strings.TrimSpace(detail.Value)
+				}
+			}
+		}
+	}
+}
+
+func (r *openvasReader) Read() ([]string, error) {
+	if !r.headerSent {
+		r.headerSent = true
+		return openvasLayout.header, nil
+	}
+
+	for {
+		token, err := r.decoder.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid OpenVAS XML: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "result":
+			var result openvasResult
+			if err := r.decoder.DecodeElement(&result, &start); err != nil {
+				return nil, fmt.Errorf("invalid result: %w", err)
+			}
+			return r.row(result), nil
+		case "host":
+			// Read in the first pass
+			if err := r.decoder.Skip(); err != nil {
+				return nil, fmt.Errorf("invalid OpenVAS XML: %w", err)
+			}
+		}
+	}
+}
+
+// row flattens a result and the operating system of its host.
+func (r *openvasReader) row(result openvasResult) []string {
+	host := strings.TrimSpace(result.Host.IP)
+	tags := openvasTags(result.NVT.Tags)
+	port, protocol := splitPort(result.Port)
+
+	var cves []string
+	for _, ref := range result.NVT.Refs {
+		if strings.EqualFold(ref.Type, "cve") {
+			cves = append(cves, ref.ID)
+		}
+	}
+	// Older reports list the CVEs in a single element
+	if len(cves) == 0 && !strings.EqualFold(strings.TrimSpace(result.NVT.CVE), "NOCVE") {
+		cves = splitList(result.NVT.CVE)
+	}
+
+	vector := tags["cvss_base_vector"]
+	for _, severity := range result.NVT.Severities {
+		if severity.Value != "" {
+			vector = severity.Value
+			break
+		}
+	}
+
+	row := openvasLayout.row()
+	row.set(ColumnHost, host)
+	row.set(ColumnName, firstNonEmpty(result.NVT.Name, result.Name))
+	row.set(ColumnCVSS, openvasScore(result.Severity))
+	row.set(ColumnCVE, joinList(cves))
+	row.set(ColumnSeverity, openvasSeverity(result.Threat))
+	row.set(ColumnPort, port)
+	row.set(ColumnProtocol, protocol)
+	row.set(ColumnOS, r.os[host])
+
+	row.set("hostname", result.Host.Hostname)
+	row.set("nvt_oid", result.NVT.OID)
+	row.set("nvt_family", result.NVT.Family)
+	row.set("threat", result.Threat)
+	row.set("cvss_vector", vector)
+	row.set("qod", result.QoD)
+	row.set("summary", tags["summary"])
+	row.set("insight", tags["insight"])
+	row.set("impact", tags["impact"])
+	row.set("solution", firstNonEmpty(result.NVT.Solution.Text, tags["solution"]))
+	row.set("solution_type", firstNonEmpty(result.NVT.Solution.Type, tags["solution_type"]))
+	row.set("description", result.Description)
+	return row.values
+}
+
+// openvasTags splits the "key=value|key=value" tags of an NVT.
+func openvasTags(text string) map[string]string {
+	tags := map[string]string{}
+	for _, pair := range strings.Split(text, "|") {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			tags[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return tags
+}
+
+// openvasScore drops the negative scores Greenbone gives logs and errors.
+func openvasScore(value string) string {
+	value = score(value)
+	if f, err := strconv.ParseFloat(value, 64); err != nil || f < 0 {
+		return ""
+	}
+	return value
+}
+
+// openvasSeverity maps the threat level onto the standard severity names.
+func openvasSeverity(threat string) string {
+	switch strings.ToLower(strings.TrimSpace(threat)) {
+	case "log", "debug":
+		return "Info"
+	case "false positive":
+		return ""
+	}
+	return strings.TrimSpace(threat)
+}
+
+// splitPort splits a port such as "443/tcp" or "general/tcp" into its
+// number and protocol.
+func splitPort(value string) (port string, protocol string) {
+	port, protocol, _ = strings.Cut(strings.TrimSpace(value), "/")
+	if _, err := strconv.Atoi(port); err != nil {
+		port = ""
+	}
+	return port, protocol
+}
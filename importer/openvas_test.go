@@ -0,0 +1,52 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOpenVAS(t *testing.T) {
+	header, rows := readRows(t, "openvas.xml", "openvas", Options{})
+	checkHeader(t, header)
+	// The operating systems come from the host details after the results
+	checkRows(t, rows, []map[string]string{
+		{ColumnHost: "10.0.0.1", ColumnName: "Apache HTTP Server 2.4.49 Path Traversal", ColumnCVSS: "9.8",
+			ColumnCVE: "CVE-2021-41773, CVE-2021-42013", ColumnSeverity: "High", ColumnPort: "443", ColumnProtocol: "tcp",
+			ColumnOS: "Ubuntu 22.04", "hostname": "web1.example.com", "cvss_vector": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+			"qod": "80", "summary": "Apache 2.4.49 allows path traversal.", "solution": "Update to version 2.4.51 or later.",
+			"solution_type": "VendorFix"},
+		{ColumnHost: "10.0.0.2", ColumnCVSS: "0.0", ColumnCVE: "", ColumnSeverity: "Info", ColumnPort: "", ColumnProtocol: "tcp",
+			ColumnOS: "Microsoft Windows Server 2019", "cvss_vector": "AV:N/AC:L/Au:N/C:N/I:N/A:N"},
+		{ColumnHost: "10.0.0.3", ColumnCVSS: "", ColumnCVE: "CVE-2020-0001, CVE-2020-0002", ColumnSeverity: "Error", ColumnOS: ""},
+	})
+}
+
+func TestOpenVASStreamsResults(t *testing.T) {
+	var doc strings.Builder
+	doc.WriteString("<report><report><results>")
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&doc, "<result><name>Weak TLS</name><host>10.0.%d.%d</host><port>443/tcp</port><threat>Medium</threat></result>", i/256, i%256)
+	}
+	doc.WriteString("</results><host><ip>10.0.0.0</ip><detail><name>best_os_txt</name><value>Debian 12</value></detail></host>")
+	doc.WriteString("</report></report>")
+
+	counter := &countingReader{r: strings.NewReader(doc.String())}
+	source, err := newOpenVASReader(counter, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Read(); err != nil {
+		t.Fatal(err)
+	}
+	row, err := source.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host, os := row[0], row[7]; host != "10.0.0.0" || os != "Debian 12" {
+		t.Errorf("first row is on %s running %q, want 10.0.0.0 running Debian 12", host, os)
+	}
+	if read := counter.n; read > int64(doc.Len()/10) {
+		t.Errorf("read %d of %d bytes for the first result", read, doc.Len())
+	}
+}
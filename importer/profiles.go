@@ -0,0 +1,302 @@
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NoProfile turns the detection of header profiles off.
+const NoProfile = "none"
+
+// Profile describes the CSV or workbook export of a scanner by its header:
+// the headers that identify it, the headers that fill each table column and
+// how its severities read.
+type Profile struct {
+	// Name labels the scans of files the profile matches.
+	Name string `yaml:"name"`
+	// Detect lists the headers that identify the export; all must be present.
+	Detect []string `yaml:"detect"`
+	// Columns maps table columns to the headers that fill them, the
+	// preferred header first, e.g. CVSS: [CVSS v3.0 Base Score, CVSS].
+	Columns map[string][]string `yaml:"columns"`
+	// Severities maps the values of the header that fills the Severity
+	// column, in any case, to severity names. Other values are read as
+	// Critical, High, Medium, Low or Info when they name one.
+	Severities map[string]string `yaml:"severities"`
+}
+
+// BuiltinProfiles describes the CSV exports of the common scanners.
+var BuiltinProfiles = []Profile{
+	{
+		Name:   "nessus",
+		Detect: []string{"Plugin ID", "Risk"},
+		Columns: map[string][]string{
+			ColumnHost:     {"Host", "IP Address", "DNS Name"},
+			ColumnName:     {"Name", "Plugin Name"},
+			ColumnCVSS:     {"CVSS v3.0 Base Score", "CVSS v2.0 Base Score", "CVSS"},
+			ColumnCVE:      {"CVE"},
+			ColumnSeverity: {"Risk"},
+			ColumnPort:     {"Port"},
+			ColumnProtocol: {"Protocol"},
+			"plugin_id":    {"Plugin ID"},
+		},
+		Severities: map[string]string{"none": "Info"},
+	},
+	{
+		Name:   "tenable-vm",
+		Detect: []string{"plugin.id", "asset.operating_system"},
+		Columns: map[string][]string{
+			ColumnHost:     {"asset.ipv4", "asset.hostname", "asset.fqdn"},
+			ColumnName:     {"plugin.name"},
+			ColumnCVSS:     {"plugin.cvss3_base_score", "plugin.cvss_base_score"},
+			ColumnCVE:      {"plugin.cve"},
+			ColumnSeverity: {"severity"},
+			ColumnPort:     {"port.port"},
+			ColumnProtocol: {"port.protocol"},
+			ColumnOS:       {"asset.operating_system"},
+			"plugin_id":    {"plugin.id"},
+		},
+	},
+	{
+		Name:   "qualys",
+		Detect: []string{"QID", "Severity"},
+		Columns: map[string][]string{
+			ColumnHost:     {"IP", "DNS", "NetBIOS"},
+			ColumnName:     {"Title"},
+			ColumnCVSS:     {"CVSS3.1 Base", "CVSS3 Base", "CVSS Base"},
+			ColumnCVE:      {"CVE ID"},
+			ColumnSeverity: {"Severity"},
+			ColumnPort:     {"Port"},
+			ColumnProtocol: {"Protocol"},
+			ColumnOS:       {"OS"},
+			"qid":          {"QID"},
+		},
+		// Qualys rates 5 Urgent, 4 Critical, 3 Serious, 2 Medium, 1 Minimal
+		Severities: map[string]string{"1": "Info", "2": "Low", "3": "Medium", "4": "High", "5": "Critical"},
+	},
+	{
+		Name:   "openvas",
+		Detect: []string{"NVT Name", "NVT OID"},
+		Columns: map[string][]string{
+			ColumnHost:     {"IP", "Hostname"},
+			ColumnName:     {"NVT Name"},
+			ColumnCVSS:     {"CVSS"},
+			ColumnCVE:      {"CVEs"},
+			ColumnSeverity: {"Severity"},
+			ColumnPort:     {"Port"},
+			ColumnProtocol: {"Port Protocol"},
+			"nvt_oid":      {"NVT OID"},
+		},
+		Severities: map[string]string{"log": "Info"},
+	},
+	{
+		Name:   "rapid7",
+		Detect: []string{"Asset IP Address", "Vulnerability Title"},
+		Columns: map[string][]string{
+			ColumnHost:     {"Asset IP Address", "Asset Names"},
+			ColumnName:     {"Vulnerability Title"},
+			ColumnCVSS:     {"Vulnerability CVSSv3 Score", "Vulnerability CVSS Score"},
+			ColumnCVE:      {"Vulnerability CVE IDs"},
+			ColumnSeverity: {"Vulnerability Severity Level"},
+			ColumnPort:     {"Service Port"},
+			ColumnProtocol: {"Service Protocol"},
+			ColumnOS:       {"Asset OS Name"},
+		},
+		// The console rates 8-10 Critical, 4-7 Severe and 1-3 Moderate
+		Severities: map[string]string{
+			"1": "Medium", "2": "Medium", "3": "Medium",
+			"4": "High", "5": "High", "6": "High", "7": "High",
+			"8": "Critical", "9": "Critical", "10": "Critical",
+		},
+	},
+}
+
+// Detection is a profile matched to the header of a file.
+type Detection struct {
+	// Profile is the name of the profile.
+	Profile string
+	// Mapping maps the headers of the file to table columns. Headers that a
+	// preferred header of the same column supersedes map to "", which
+	// leaves them out.
+	Mapping map[string]string
+}
+
+// Under returns the detected mapping with the entries of mapping laid over
+// it, so that a mapping file wins over the profile.
+func (d *Detection) Under(mapping map[string]string) map[string]string {
+	merged := make(map[string]string, len(d.Mapping)+len(mapping))
+	overridden := map[string]bool{}
+	for source, target := range mapping {
+		merged[source] = target
+		overridden[headerKey(source)] = true
+	}
+	for source, target := range d.Mapping {
+		if !overridden[headerKey(source)] {
+			merged[source] = target
+		}
+	}
+	return merged
+}
+
+// detector is implemented by the row sources that detect a header profile.
+type detector interface {
+	detected() *Detection
+}
+
+// profiledSource reads the header of a CSV file or workbook ahead, to match
+// it with a profile, and normalizes the severities of the matched profile.
+type profiledSource struct {
+	source     rowSource
+	header     []string
+	headerErr  error
+	headerSent bool
+	detection  *Detection
+	// severity is the index of the header that fills the Severity column,
+	// or -1.
+	severity   int
+	severities map[string]string
+}
+
+// withProfile matches the header of source with the profile opts name or,
+// when it names none, with the first of the profiles of opts and then the
+// built-in ones that has the most of its headers.
+func withProfile(source rowSource, opts Options) (rowSource, error) {
+	if opts.Profile == NoProfile {
+		return source, nil
+	}
+	header, err := source.Read()
+	if err != nil {
+		// Leave an empty or unreadable file to the import to report
+		return &profiledSource{source: source, headerErr: err, severity: -1}, nil
+	}
+	p := &profiledSource{source: source, header: header, severity: -1}
+
+	// Profiles of the same name as a built-in one replace it
+	profiles := append([]Profile(nil), opts.Profiles...)
+	for _, builtin := range BuiltinProfiles {
+		if !hasProfile(opts.Profiles, builtin.Name) {
+			profiles = append(profiles, builtin)
+		}
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		if _, ok := index[headerKey(name)]; !ok {
+			index[headerKey(name)] = i
+		}
+	}
+	var profile *Profile
+	if opts.Profile != "" {
+		for i := range profiles {
+			if strings.EqualFold(profiles[i].Name, opts.Profile) {
+				profile = &profiles[i]
+			}
+		}
+		if profile == nil {
+			return nil, fmt.Errorf("unknown header profile %q; choose one of %s", opts.Profile, strings.Join(profileNames(profiles), ", "))
+		}
+	} else {
+		best := 0
+		for i := range profiles {
+			if n := detects(profiles[i], index); n > best {
+				profile, best = &profiles[i], n
+			}
+		}
+		if profile == nil {
+			return p, nil
+		}
+	}
+
+	p.detection = &Detection{Profile: profile.Name, Mapping: map[string]string{}}
+	for _, column := range sortedKeys(profile.Columns) {
+		chosen := -1
+		for _, candidate := range profile.Columns[column] {
+			if i, ok := index[headerKey(candidate)]; ok {
+				chosen = i
+				break
+			}
+		}
+		if chosen < 0 {
+			continue
+		}
+		p.detection.Mapping[header[chosen]] = column
+		if column == ColumnSeverity {
+			p.severity = chosen
+		}
+	}
+	// A header named like a filled column but not preferred for it would
+	// fill the column twice
+	filled := map[string]bool{}
+	for _, column := range p.detection.Mapping {
+		filled[column] = true
+	}
+	for _, column := range sortedKeys(filled) {
+		if i, ok := index[headerKey(column)]; ok {
+			if _, mapped := p.detection.Mapping[header[i]]; !mapped {
+				p.detection.Mapping[header[i]] = ""
+			}
+		}
+	}
+	p.severities = make(map[string]string, len(profile.Severities))
+	for value, name := range profile.Severities {
+		p.severities[strings.ToLower(strings.TrimSpace(value))] = name
+	}
+	return p, nil
+}
+
+// detects returns the number of headers that identify profile, or 0 when
+// index lacks one of them.
+func detects(profile Profile, index map[string]int) int {
+	for _, name := range profile.Detect {
+		if _, ok := index[headerKey(name)]; !ok {
+			return 0
+		}
+	}
+	return len(profile.Detect)
+}
+
+func (p *profiledSource) Read() ([]string, error) {
+	if !p.headerSent {
+		p.headerSent = true
+		return p.header, p.headerErr
+	}
+	row, err := p.source.Read()
+	if err != nil || p.severity < 0 || p.severity >= len(row) {
+		return row, err
+	}
+	value := strings.TrimSpace(row[p.severity])
+	if name, ok := p.severities[strings.ToLower(value)]; ok {
+		row[p.severity] = name
+	} else if name := namedSeverity(value); name != "" {
+		row[p.severity] = name
+	}
+	return row, nil
+}
+
+func (p *profiledSource) detected() *Detection { return p.detection }
+
+// hasProfile reports whether profiles has one of the given name.
+func hasProfile(profiles []Profile, name string) bool {
+	for _, profile := range profiles {
+		if strings.EqualFold(profile.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// profileNames returns the names of profiles, sorted.
+func profileNames(profiles []Profile) []string {
+	names := make([]string, 0, len(profiles))
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileNames returns the names of the built-in profiles, sorted.
+func ProfileNames() []string {
+	return profileNames(BuiltinProfiles)
+}
@@ -0,0 +1,154 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCSV writes CSV text to a file of the test.
+func writeCSV(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "export.csv")
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProfileDetection(t *testing.T) {
+	for _, tt := range []struct {
+		csv          string
+		profile      string
+		mapping      map[string]string
+		wantSeverity string
+	}{
+		{
+			// The v3 score is preferred, and the header named CVSS is left
+			// out rather than fill the column twice
+			csv:     "Plugin ID,CVE,CVSS,CVSS v2.0 Base Score,CVSS v3.0 Base Score,Risk,Host,Protocol,Port,Name\n10863,,5.0,5.0,5.3,None,10.0.0.1,tcp,443,SSL Certificate\n",
+			profile: "nessus",
+			mapping: map[string]string{"Plugin ID": "plugin_id", "CVSS v3.0 Base Score": ColumnCVSS, "CVSS": "",
+				"Risk": ColumnSeverity, "Host": ColumnHost, "Name": ColumnName},
+			wantSeverity: "Info",
+		},
+		{
+			csv:     "asset.ipv4,asset.hostname,asset.operating_system,plugin.id,plugin.name,plugin.cvss3_base_score,plugin.cve,severity,port.port,port.protocol\n10.0.0.2,db01,Ubuntu 22.04,19506,Nessus Scan Information,,,info,0,tcp\n",
+			profile: "tenable-vm",
+			mapping: map[string]string{"asset.ipv4": ColumnHost, "asset.operating_system": ColumnOS, "plugin.cvss3_base_score": ColumnCVSS,
+				"severity": ColumnSeverity, "port.port": ColumnPort},
+			wantSeverity: "Info",
+		},
+		{
+			csv:          "IP,DNS,OS,QID,Title,Severity,Port,Protocol,CVE ID,CVSS3.1 Base\n10.0.0.3,web03,Windows,38170,SSL Certificate - Expired,4,443,tcp,,6.5\n",
+			profile:      "qualys",
+			mapping:      map[string]string{"IP": ColumnHost, "QID": "qid", "CVE ID": ColumnCVE, "CVSS3.1 Base": ColumnCVSS, "OS": ColumnOS},
+			wantSeverity: "High",
+		},
+		{
+			csv:          "IP,Hostname,Port,Port Protocol,CVSS,Severity,NVT Name,NVT OID,CVEs\n10.0.0.4,,general/tcp,,0.0,Log,OS Detection,1.3.6.1.4.1.25623.1.0.105937,\n",
+			profile:      "openvas",
+			mapping:      map[string]string{"IP": ColumnHost, "NVT Name": ColumnName, "Port Protocol": ColumnProtocol, "CVEs": ColumnCVE},
+			wantSeverity: "Info",
+		},
+		{
+			csv:          "Asset IP Address,Asset Names,Asset OS Name,Service Port,Service Protocol,Vulnerability Title,Vulnerability CVE IDs,Vulnerability CVSSv3 Score,Vulnerability Severity Level\n10.0.0.5,web01,Ubuntu Linux,443,tcp,TLS 3DES,CVE-2016-2183,7.5,5\n",
+			profile:      "rapid7",
+			mapping:      map[string]string{"Asset IP Address": ColumnHost, "Vulnerability CVSSv3 Score": ColumnCVSS, "Asset OS Name": ColumnOS},
+			wantSeverity: "High",
+		},
+	} {
+		t.Run(tt.profile, func(t *testing.T) {
+			r, err := Open(writeCSV(t, tt.csv), "csv", Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			header, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			row, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := r.Detected()
+			if d == nil || d.Profile != tt.profile {
+				t.Fatalf("detected %+v, want %s", d, tt.profile)
+			}
+			for source, column := range tt.mapping {
+				if got, ok := d.Mapping[source]; !ok || got != column {
+					t.Errorf("%s maps to %q, want %q", source, got, column)
+				}
+			}
+			for i, name := range header {
+				if d.Mapping[name] == ColumnSeverity && row[i] != tt.wantSeverity {
+					t.Errorf("severity %s reads %q, want %q", name, row[i], tt.wantSeverity)
+				}
+			}
+		})
+	}
+}
+
+func TestProfileOptions(t *testing.T) {
+	const export = "Plugin ID,Risk,Host,Name\n10863,Medium,10.0.0.1,SSL Certificate\n"
+	custom := Profile{
+		Name:       "nessus",
+		Detect:     []string{"Plugin ID"},
+		Columns:    map[string][]string{ColumnHost: {"Host"}, ColumnSeverity: {"Risk"}},
+		Severities: map[string]string{"medium": "Low"},
+	}
+	for _, tt := range []struct {
+		name         string
+		opts         Options
+		wantProfile  string
+		wantSeverity string
+		wantErr      string
+	}{
+		{name: "detected", wantProfile: "nessus", wantSeverity: "Medium"},
+		{name: "off", opts: Options{Profile: NoProfile}, wantSeverity: "Medium"},
+		{name: "named", opts: Options{Profile: "Qualys"}, wantProfile: "qualys", wantSeverity: "Medium"},
+		// A profile of the same name replaces the built-in one
+		{name: "custom", opts: Options{Profiles: []Profile{custom}}, wantProfile: "nessus", wantSeverity: "Low"},
+		{name: "unknown", opts: Options{Profile: "acunetix"}, wantErr: "choose one of nessus, openvas, qualys, rapid7, tenable-vm"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Open(writeCSV(t, export), "csv", tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Open = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if _, err := r.Read(); err != nil {
+				t.Fatal(err)
+			}
+			row, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var profile string
+			if d := r.Detected(); d != nil {
+				profile = d.Profile
+			}
+			if profile != tt.wantProfile || row[1] != tt.wantSeverity {
+				t.Errorf("profile %q severity %q, want %q %q", profile, row[1], tt.wantProfile, tt.wantSeverity)
+			}
+		})
+	}
+}
+
+func TestDetectionUnder(t *testing.T) {
+	d := &Detection{Profile: "nessus", Mapping: map[string]string{"Host": ColumnHost, "Risk": ColumnSeverity, "CVSS": ""}}
+	got := d.Under(map[string]string{"risk": "rating", "Synopsis": "summary"})
+	want := map[string]string{"Host": ColumnHost, "CVSS": "", "risk": "rating", "Synopsis": "summary"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Under = %v, want %v", got, want)
+	}
+}
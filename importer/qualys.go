@@ -0,0 +1,434 @@
+package importer
+
+import (
+	stdcsv "encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+func init() {
+	register(Format{Name: "qualys-csv", open: newQualysCSVReader})
+	register(Format{Name: "qualys-xml", open: newQualysXMLReader})
+}
+
+// qualysLayout is the header of a Qualys import: the standard columns
+// followed by the QID, host names and the Qualys scores and texts.
+var qualysLayout = newRowLayout(
+	ColumnHost, ColumnName, ColumnCVSS, ColumnCVE, ColumnSeverity, ColumnPort, ColumnProtocol, ColumnOS,
+	"qid", "vuln_type", "dns", "netbios", "qualys_severity",
+	"cvss_base", "cvss_temporal", "cvss3_base", "cvss3_temporal",
+	"category", "threat", "impact", "solution", "results",
+)
+
+// qualysSeverity maps the Qualys 1-5 severity onto the standard names:
+// 5 Urgent, 4 Critical, 3 Serious, 2 Medium and 1 Minimal.
+func qualysSeverity(level string) string {
+	switch strings.TrimSpace(level) {
+	case "1":
+		return "Info"
+	case "2":
+		return "Low"
+	case "3":
+		return "Medium"
+	case "4":
+		return "High"
+	case "5":
+		return "Critical"
+	}
+	return ""
+}
+
+// qualysFinding is one detection, from either report format.
+type qualysFinding struct {
+	IP, DNS, NetBIOS, OS       string
+	QID, Type, Title, Severity string
+	Port, Protocol             string
+	CVEs                       []string
+	CVSSBase, CVSSTemporal     string
+	CVSS3Base, CVSS3Temporal   string
+	Category, Threat, Impact   string
+	Solution, Results          string
+}
+
+func (f qualysFinding) row() []string {
+	row := qualysLayout.row()
+	row.set(ColumnHost, firstNonEmpty(f.IP, f.DNS))
+	row.set(ColumnName, f.Title)
+	row.set(ColumnCVSS, firstNonEmpty(score(f.CVSS3Base), score(f.CVSSBase)))
+	row.set(ColumnCVE, joinList(f.CVEs))
+	row.set(ColumnSeverity, qualysSeverity(f.Severity))
+	row.set(ColumnPort, f.Port)
+	row.set(ColumnProtocol, f.Protocol)
+	row.set(ColumnOS, f.OS)
+
+	row.set("qid", f.QID)
+	row.set("vuln_type", f.Type)
+	row.set("dns", f.DNS)
+	row.set("netbios", f.NetBIOS)
+	row.set("qualys_severity", f.Severity)
+	row.set("cvss_base", score(f.CVSSBase))
+	row.set("cvss_temporal", score(f.CVSSTemporal))
+	row.set("cvss3_base", score(f.CVSS3Base))
+	row.set("cvss3_temporal", score(f.CVSS3Temporal))
+	row.set("category", f.Category)
+	row.set("threat", f.Threat)
+	row.set("impact", f.Impact)
+	row.set("solution", f.Solution)
+	row.set("results", f.Results)
+	return row.values
+}
+
+// qualysCSVReader reads a Qualys CSV report, skipping the report metadata
+// rows that come before the column header.
+type qualysCSVReader struct {
+	reader *stdcsv.Reader
+	header map[string]int
+}
+
+func newQualysCSVReader(r io.Reader, _ Options) (rowSource, error) {
+	reader := stdcsv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return &qualysCSVReader{reader: reader}, nil
+}
+
+func (r *qualysCSVReader) Read() ([]string, error) {
+	if r.header == nil {
+		if err := r.skipPreamble(); err != nil {
+			return nil, err
+		}
+		return qualysLayout.header, nil
+	}
+
+	for {
+		record, err := r.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		// Blank lines and footers have no QID
+		if r.field(record, "qid") == "" {
+			continue
+		}
+		return qualysFinding{
+			IP:            r.field(record, "ip"),
+			DNS:           r.field(record, "dns"),
+			NetBIOS:       r.field(record, "netbios"),
+			OS:            r.field(record, "os", "operatingsystem"),
+			QID:           r.field(record, "qid"),
+			Type:          r.field(record, "type"),
+			Title:         r.field(record, "title"),
+			Severity:      r.field(record, "severity"),
+			Port:          r.field(record, "port"),
+			Protocol:      r.field(record, "protocol"),
+			CVEs:          splitList(r.field(record, "cveid", "cve")),
+			CVSSBase:      r.field(record, "cvssbase", "cvss"),
+			CVSSTemporal:  r.field(record, "cvsstemporal"),
+			CVSS3Base:     r.field(record, "cvss31base", "cvss3base", "cvssv3base"),
+			CVSS3Temporal: r.field(record, "cvss31temporal", "cvss3temporal", "cvssv3temporal"),
+			Category:      r.field(record, "category"),
+			Threat:        r.field(record, "threat"),
+			Impact:        r.field(record, "impact"),
+			Solution:      r.field(record, "solution"),
+			Results:       r.field(record, "results"),
+		}.row(), nil
+	}
+}
+
+// skipPreamble reads up to the row that names both the IP and QID columns.
+func (r *qualysCSVReader) skipPreamble() error {
+	for {
+		record, err := r.reader.Read()
+		if err == io.EOF {
+			return fmt.Errorf("no Qualys column header with IP and QID found")
+		}
+		if err != nil {
+			return err
+		}
+		header := make(map[string]int, len(record))
+		for i, name := range record {
+			key := headerKey(name)
+			if _, seen := header[key]; !seen {
+				header[key] = i
+			}
+		}
+		_, hasIP := header["ip"]
+		_, hasQID := header["qid"]
+		if hasIP && hasQID {
+			r.header = header
+			return nil
+		}
+	}
+}
+
+// field returns the first of the named columns the report has.
+func (r *qualysCSVReader) field(record []string, keys ...string) string {
+	for _, key := range keys {
+		if i, ok := r.header[key]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+	}
+	return ""
+}
+
+// Elements of a Qualys scan report (<SCAN>): IP > CAT > VULN|PRACTICE|INFO.
+type qualysScanItem struct {
+	Number        string   `xml:"number,attr"`
+	Severity      string   `xml:"severity,attr"`
+	Title         string   `xml:"TITLE"`
+	CVSSBase      string   `xml:"CVSS_BASE"`
+	CVSSTemporal  string   `xml:"CVSS_TEMPORAL"`
+	CVSS3Base     string   `xml:"CVSS3_BASE"`
+	CVSS3Temporal string   `xml:"CVSS3_TEMPORAL"`
+	CVEs          []string `xml:"CVE_ID_LIST>CVE_ID>ID"`
+	Diagnosis     string   `xml:"DIAGNOSIS"`
+	Consequence   string   `xml:"CONSEQUENCE"`
+	Solution      string   `xml:"SOLUTION"`
+	Result        string   `xml:"RESULT"`
+}
+
+// Elements of a Qualys VM report (<ASSET_DATA_REPORT>): the detections
+// under HOST_LIST refer by QID to VULN_DETAILS in the trailing GLOSSARY.
+type qualysHost struct {
+	IP      string `xml:"IP"`
+	DNS     string `xml:"DNS"`
+	NetBIOS string `xml:"NETBIOS"`
+	OS      string `xml:"OPERATING_SYSTEM"`
+	Vulns   []struct {
+		QID      string `xml:"QID"`
+		Type     string `xml:"TYPE"`
+		Port     string `xml:"PORT"`
+		Protocol string `xml:"PROTOCOL"`
+		Result   string `xml:"RESULT"`
+	} `xml:"VULN_INFO_LIST>VULN_INFO"`
+}
+
+type qualysVulnDetails struct {
+	QID           string   `xml:"QID"`
+	Title         string   `xml:"TITLE"`
+	Severity      string   `xml:"SEVERITY"`
+	Category      string   `xml:"CATEGORY"`
+	CVSSBase      string   `xml:"CVSS_SCORE>CVSS_BASE"`
+	CVSSTemporal  string   `xml:"CVSS_SCORE>CVSS_TEMPORAL"`
+	CVSS3Base     string   `xml:"CVSS3_SCORE>CVSS3_BASE"`
+	CVSS3Temporal string   `xml:"CVSS3_SCORE>CVSS3_TEMPORAL"`
+	CVEs          []string `xml:"CVE_ID_LIST>CVE_ID>ID"`
+	Threat        string   `xml:"THREAT"`
+	Impact        string   `xml:"IMPACT"`
+	Solution      string   `xml:"SOLUTION"`
+}
+
+// qualysXMLReader reads both Qualys XML layouts. Scan reports are streamed.
+// The detections of a VM report refer to a glossary at its end, so the
+// glossary is read first and the detections of each host are then returned
+// as the host ends.
+type qualysXMLReader struct {
+	decoder    *xml.Decoder
+	headerSent bool
+
+	// Scan report state
+	host           qualysFinding
+	port, protocol string
+
+	// VM report state
+	pending  [][]string
+	glossary map[string]qualysVulnDetails
+}
+
+func newQualysXMLReader(r io.Reader, _ Options) (rowSource, error) {
+	glossary, err := readQualysGlossary(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := rewind(r); err != nil {
+		return nil, err
+	}
+	return &qualysXMLReader{decoder: xml.NewDecoder(r), glossary: glossary}, nil
+}
+
+// readQualysGlossary reads the vulnerability details of a VM report, or
+// nothing from a scan report.
+func readQualysGlossary(r io.Reader) (map[string]qualysVulnDetails, error) {
+	glossary := map[string]qualysVulnDetails{}
+	decoder := xml.NewDecoder(r)
+	root := true
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return glossary, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid Qualys XML: %w", err)
+		}
+		t, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root {
+			root = false
+			if t.Name.Local != "ASSET_DATA_REPORT" {
+				return glossary, nil
+			}
+			continue
+		}
+
+		switch t.Name.Local {
+		case "HOST_LIST":
+			if err := decoder.Skip(); err != nil {
+				return nil, fmt.Errorf("invalid Qualys XML: %w", err)
+			}
+		case "VULN_DETAILS":
+			var details qualysVulnDetails
+			if err := decoder.DecodeElement(&details, &t); err != nil {
+				return nil, fmt.Errorf("invalid VULN_DETAILS: %w", err)
+			}
+			glossary[strings.TrimSpace(details.QID)] = details
+		}
+	}
+}
+
+func (r *qualysXMLReader) Read() ([]string, error) {
+	if !r.headerSent {
+		r.headerSent = true
+		return qualysLayout.header, nil
+	}
+
+	for len(r.pending) == 0 {
+		token, err := r.decoder.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid Qualys XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			row, err := r.start(t)
+			if err != nil || row != nil {
+				return row, err
+			}
+		case xml.EndElement:
+			if t.Name.Local == "CAT" {
+				r.port, r.protocol = "", ""
+			}
+		}
+	}
+	row := r.pending[0]
+	r.pending = r.pending[1:]
+	return row, nil
+}
+
+// start handles an opening element and returns a row when it completes one.
+// The rows of a VM report host are queued in pending instead.
+func (r *qualysXMLReader) start(t xml.StartElement) ([]string, error) {
+	switch t.Name.Local {
+	case "IP":
+		// Only the scan report has IP elements with attributes
+		if value := attr(t, "value"); value != "" {
+			r.host = qualysFinding{IP: value, DNS: attr(t, "name")}
+		}
+	case "OS":
+		var os string
+		if err := r.decoder.DecodeElement(&os, &t); err != nil {
+			return nil, err
+		}
+		r.host.OS = strings.TrimSpace(os)
+	case "NETBIOS_HOSTNAME":
+		var name string
+		if err := r.decoder.DecodeElement(&name, &t); err != nil {
+			return nil, err
+		}
+		r.host.NetBIOS = strings.TrimSpace(name)
+	case "CAT":
+		r.port, r.protocol = attr(t, "port"), attr(t, "protocol")
+	case "VULN", "PRACTICE", "INFO":
+		var item qualysScanItem
+		if err := r.decoder.DecodeElement(&item, &t); err != nil {
+			return nil, fmt.Errorf("invalid %s of %s: %w", t.Name.Local, r.host.IP, err)
+		}
+		f := r.host
+		f.QID = item.Number
+		f.Type = strings.ToLower(t.Name.Local)
+		f.Title = item.Title
+		f.Severity = item.Severity
+		f.Port, f.Protocol = r.port, r.protocol
+		f.CVEs = item.CVEs
+		f.CVSSBase, f.CVSSTemporal = item.CVSSBase, item.CVSSTemporal
+		f.CVSS3Base, f.CVSS3Temporal = item.CVSS3Base, item.CVSS3Temporal
+		f.Threat, f.Impact = item.Diagnosis, item.Consequence
+		f.Solution, f.Results = item.Solution, item.Result
+		return f.row(), nil
+	case "HOST":
+		var host qualysHost
+		if err := r.decoder.DecodeElement(&host, &t); err != nil {
+			return nil, fmt.Errorf("invalid HOST: %w", err)
+		}
+		for _, v := range host.Vulns {
+			r.pending = append(r.pending, r.described(qualysFinding{
+				IP: host.IP, DNS: host.DNS, NetBIOS: host.NetBIOS, OS: host.OS,
+				QID: strings.TrimSpace(v.QID), Type: v.Type, Port: v.Port, Protocol: v.Protocol, Results: v.Result,
+			}).row())
+		}
+	case "GLOSSARY":
+		// Read before the hosts
+		return nil, r.decoder.Skip()
+	}
+	return nil, nil
+}
+
+// described fills in a VM report detection from the glossary.
+func (r *qualysXMLReader) described(f qualysFinding) qualysFinding {
+	if d, ok := r.glossary[f.QID]; ok {
+		f.Title, f.Severity, f.Category = d.Title, d.Severity, d.Category
+		f.CVEs = d.CVEs
+		f.CVSSBase, f.CVSSTemporal = d.CVSSBase, d.CVSSTemporal
+		f.CVSS3Base, f.CVSS3Temporal = d.CVSS3Base, d.CVSS3Temporal
+		f.Threat, f.Impact, f.Solution = d.Threat, d.Impact, d.Solution
+	}
+	return f
+}
+
+// score returns the leading number of a score such as "7.5 (AV:N/AC:L/...)",
+// or "" when there is none.
+func score(value string) string {
+	value = strings.TrimSpace(value)
+	end := strings.IndexFunc(value, func(r rune) bool { return r != '.' && !unicode.IsDigit(r) })
+	if end >= 0 {
+		value = value[:end]
+	}
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return ""
+	}
+	return value
+}
+
+// splitList splits a comma or whitespace separated list.
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+}
+
+// joinList joins list items the way the CVE column holds them.
+func joinList(values []string) string {
+	var items []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			items = append(items, v)
+		}
+	}
+	return strings.Join(items, ", ")
+}
+
+// headerKey folds a column header to lower case letters and digits.
+func headerKey(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
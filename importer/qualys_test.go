@@ -0,0 +1,89 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestQualys(t *testing.T) {
+	for _, tt := range []struct {
+		file   string
+		format string
+		want   []map[string]string
+	}{
+		{
+			// The report metadata before the column header is skipped,
+			// and so are the blank lines and the footer
+			file:   "qualys.csv",
+			format: "qualys-csv",
+			want: []map[string]string{
+				{ColumnHost: "10.0.0.1", ColumnName: "Apache HTTP Server Multiple Vulnerabilities", ColumnCVSS: "9.8",
+					ColumnCVE: "CVE-2021-41773, CVE-2021-42013", ColumnSeverity: "Critical", ColumnPort: "443", ColumnOS: "Ubuntu 22.04",
+					"qid": "86763", "cvss_base": "7.5", "cvss3_temporal": "9.1", "category": "Web server"},
+				{ColumnHost: "10.0.0.2", ColumnName: "SMB Signing Disabled", ColumnCVSS: "5.3", ColumnCVE: "",
+					ColumnSeverity: "Low", ColumnPort: "445", ColumnOS: "Windows Server 2019", "vuln_type": "Potential Vuln"},
+			},
+		},
+		{
+			file:   "qualys-scan.xml",
+			format: "qualys-xml",
+			want: []map[string]string{
+				{ColumnHost: "10.0.0.1", ColumnName: "Apache HTTP Server Multiple Vulnerabilities", ColumnCVSS: "9.8",
+					ColumnCVE: "CVE-2021-41773", ColumnSeverity: "High", ColumnPort: "443", ColumnProtocol: "tcp",
+					ColumnOS: "Ubuntu 22.04", "dns": "web1.example.com", "netbios": "WEB1", "vuln_type": "vuln"},
+				{ColumnHost: "10.0.0.1", ColumnName: "DNS Host Name", ColumnCVSS: "", ColumnSeverity: "Info",
+					ColumnPort: "", "vuln_type": "info"},
+			},
+		},
+		{
+			// The detections are described from the glossary at the end
+			file:   "qualys-vm.xml",
+			format: "qualys-xml",
+			want: []map[string]string{
+				{ColumnHost: "10.0.0.1", ColumnName: "SSL Certificate - Signature Verification Failed Vulnerability",
+					ColumnCVSS: "5.3", ColumnSeverity: "Low", ColumnPort: "443", ColumnOS: "Ubuntu 22.04",
+					"cvss_base": "9.4", "category": "General remote services", "netbios": "WEB1"},
+				{ColumnHost: "10.0.0.1", ColumnName: "OpenSSL Multiple Vulnerabilities", ColumnCVSS: "7.5",
+					ColumnCVE: "CVE-2022-3602, CVE-2022-3786", ColumnSeverity: "High", ColumnPort: "", "vuln_type": "Practice"},
+				{ColumnHost: "10.0.0.2", ColumnName: "SSL Certificate - Signature Verification Failed Vulnerability",
+					ColumnPort: "3389", ColumnOS: "Windows Server 2019", "results": ""},
+			},
+		},
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			header, rows := readRows(t, tt.file, tt.format, Options{})
+			checkHeader(t, header)
+			checkRows(t, rows, tt.want)
+		})
+	}
+}
+
+func TestQualysVMReportStreamsHosts(t *testing.T) {
+	var doc strings.Builder
+	doc.WriteString("<ASSET_DATA_REPORT><HOST_LIST>")
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&doc, "<HOST><IP>10.0.%d.%d</IP><VULN_INFO_LIST><VULN_INFO><QID>38173</QID></VULN_INFO></VULN_INFO_LIST></HOST>", i/256, i%256)
+	}
+	doc.WriteString("</HOST_LIST><GLOSSARY><VULN_DETAILS_LIST><VULN_DETAILS><QID>38173</QID><TITLE>Weak TLS</TITLE>")
+	doc.WriteString("</VULN_DETAILS></VULN_DETAILS_LIST></GLOSSARY></ASSET_DATA_REPORT>")
+
+	counter := &countingReader{r: strings.NewReader(doc.String())}
+	source, err := newQualysXMLReader(counter, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Read(); err != nil {
+		t.Fatal(err)
+	}
+	row, err := source.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host, name := row[0], row[1]; host != "10.0.0.0" || name != "Weak TLS" {
+		t.Errorf("first row is %s %q, want 10.0.0.0 \"Weak TLS\"", host, name)
+	}
+	if read := counter.n; read > int64(doc.Len()/10) {
+		t.Errorf("read %d of %d bytes for the first host", read, doc.Len())
+	}
+}
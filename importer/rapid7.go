@@ -0,0 +1,259 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	register(Format{Name: "rapid7", open: newRapid7Reader})
+}
+
+// rapid7Layout is the header of an InsightVM/Nexpose XML Export 2.0
+// import: one row per vulnerable test result of a node.
+var rapid7Layout = newRowLayout(
+	ColumnHost, "hostname", "mac_address", "site", ColumnOS,
+	ColumnPort, ColumnProtocol, "service",
+	ColumnName, ColumnCVSS, ColumnCVE, ColumnSeverity,
+	"vulnerability_id", "rapid7_severity", "status", "cvss_vector", "risk_score",
+	"published", "vulnerable_since", "category", "description", "solution", "proof",
+)
+
+// rapid7Content is an element of structured text, such as a proof or a
+// solution made of paragraphs and lists.
+type rapid7Content struct {
+	Inner string `xml:",innerxml"`
+}
+
+var markup = regexp.MustCompile(`<[^>]*>`)
+
+// text returns the content without markup, white space collapsed.
+func (c rapid7Content) text() string {
+	return strings.Join(strings.Fields(html.UnescapeString(markup.ReplaceAllString(c.Inner, " "))), " ")
+}
+
+// rapid7Test is the result of a vulnerability check on a node or service.
+type rapid7Test struct {
+	ID              string `xml:"id,attr"`
+	Status          string `xml:"status,attr"`
+	VulnerableSince string `xml:"vulnerable-since,attr"`
+	rapid7Content
+}
+
+// rapid7Node is a scanned <node>.
+type rapid7Node struct {
+	Address         string   `xml:"address,attr"`
+	HardwareAddress string   `xml:"hardware-address,attr"`
+	SiteName        string   `xml:"site-name,attr"`
+	Names           []string `xml:"names>name"`
+	Fingerprints    []struct {
+		Certainty string `xml:"certainty,attr"`
+		Vendor    string `xml:"vendor,attr"`
+		Family    string `xml:"family,attr"`
+		Product   string `xml:"product,attr"`
+		Version   string `xml:"version,attr"`
+	} `xml:"fingerprints>os"`
+	Tests     []rapid7Test `xml:"tests>test"`
+	Endpoints []struct {
+		Protocol string `xml:"protocol,attr"`
+		Port     string `xml:"port,attr"`
+		Services []struct {
+			Name  string       `xml:"name,attr"`
+			Tests []rapid7Test `xml:"tests>test"`
+		} `xml:"services>service"`
+	} `xml:"endpoints>endpoint"`
+}
+
+// rapid7Definition is a <vulnerability> of the VulnerabilityDefinitions.
+type rapid7Definition struct {
+	ID          string        `xml:"id,attr"`
+	Title       string        `xml:"title,attr"`
+	Severity    string        `xml:"severity,attr"`
+	CVSSScore   string        `xml:"cvssScore,attr"`
+	CVSSVector  string        `xml:"cvssVector,attr"`
+	RiskScore   string        `xml:"riskScore,attr"`
+	Published   string        `xml:"published,attr"`
+	Description rapid7Content `xml:"description"`
+	Solution    rapid7Content `xml:"solution"`
+	References  []struct {
+		Source string `xml:"source,attr"`
+		Value  string `xml:",chardata"`
+	} `xml:"references>reference"`
+	Tags []string `xml:"tags>tag"`
+}
+
+// rapid7Finding is a vulnerable test result waiting for its definition.
+type rapid7Finding struct {
+	host                    record
+	port, protocol, service string
+	test                    rapid7Test
+}
+
+// rapid7Reader reads the nodes of the export, then joins their test
+// results with the vulnerability definitions that follow them.
+type rapid7Reader struct {
+	decoder     *xml.Decoder
+	headerSent  bool
+	pending     []rapid7Finding
+	definitions map[string]rapid7Definition // by lower case ID
+	done        bool
+}
+
+func newRapid7Reader(r io.Reader, _ Options) (rowSource, error) {
+	return &rapid7Reader{decoder: xml.NewDecoder(r), definitions: map[string]rapid7Definition{}}, nil
+}
+
+func (r *rapid7Reader) Read() ([]string, error) {
+	if !r.headerSent {
+		r.headerSent = true
+		return rapid7Layout.header, nil
+	}
+
+	for !r.done {
+		token, err := r.decoder.Token()
+		if err == io.EOF {
+			r.done = true
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid Rapid7 XML: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "NexposeReport":
+			if version := attr(start, "version"); version != "" && version != "2.0" {
+				return nil, fmt.Errorf("unsupported Rapid7 XML export version %s", version)
+			}
+		case "node":
+			var node rapid7Node
+			if err := r.decoder.DecodeElement(&node, &start); err != nil {
+				return nil, fmt.Errorf("invalid node: %w", err)
+			}
+			r.addNode(node)
+		case "vulnerability":
+			var definition rapid7Definition
+			if err := r.decoder.DecodeElement(&definition, &start); err != nil {
+				return nil, fmt.Errorf("invalid vulnerability definition: %w", err)
+			}
+			r.definitions[strings.ToLower(definition.ID)] = definition
+		}
+	}
+
+	if len(r.pending) == 0 {
+		return nil, io.EOF
+	}
+	f := r.pending[0]
+	r.pending = r.pending[1:]
+	return r.row(f), nil
+}
+
+// addNode holds the vulnerable test results of a node and of its services.
+func (r *rapid7Reader) addNode(node rapid7Node) {
+	host := rapid7Layout.row()
+	host.set(ColumnHost, node.Address)
+	if len(node.Names) > 0 {
+		host.set("hostname", node.Names[0])
+	}
+	host.set("mac_address", node.HardwareAddress)
+	host.set("site", node.SiteName)
+
+	// Take the most certain OS fingerprint
+	best := -1.0
+	for _, fp := range node.Fingerprints {
+		certainty, _ := strconv.ParseFloat(fp.Certainty, 64)
+		if certainty > best {
+			best = certainty
+			host.set(ColumnOS, strings.Join(strings.Fields(strings.Join([]string{fp.Vendor, firstNonEmpty(fp.Product, fp.Family), fp.Version}, " ")), " "))
+		}
+	}
+
+	for _, test := range node.Tests {
+		if rapid7Vulnerable(test.Status) {
+			r.pending = append(r.pending, rapid7Finding{host: host, test: test})
+		}
+	}
+	for _, endpoint := range node.Endpoints {
+		for _, service := range endpoint.Services {
+			for _, test := range service.Tests {
+				if rapid7Vulnerable(test.Status) {
+					r.pending = append(r.pending, rapid7Finding{
+						host: host, port: endpoint.Port, protocol: endpoint.Protocol, service: service.Name, test: test,
+					})
+				}
+			}
+		}
+	}
+}
+
+// row joins a test result with its definition.
+func (r *rapid7Reader) row(f rapid7Finding) []string {
+	row := rapid7Layout.row()
+	copy(row.values, f.host.values)
+	row.set(ColumnPort, f.port)
+	row.set(ColumnProtocol, f.protocol)
+	row.set("service", f.service)
+	row.set("vulnerability_id", f.test.ID)
+	row.set("status", f.test.Status)
+	row.set("vulnerable_since", f.test.VulnerableSince)
+	row.set("proof", f.test.text())
+
+	// Test IDs are lower case while definition IDs keep their case
+	d, ok := r.definitions[strings.ToLower(f.test.ID)]
+	if !ok {
+		row.set(ColumnName, f.test.ID)
+		return row.values
+	}
+
+	var cves []string
+	for _, ref := range d.References {
+		if strings.EqualFold(ref.Source, "CVE") {
+			cves = append(cves, strings.ToUpper(strings.TrimSpace(ref.Value)))
+		}
+	}
+	row.set(ColumnName, firstNonEmpty(d.Title, f.test.ID))
+	row.set(ColumnCVSS, score(d.CVSSScore))
+	row.set(ColumnCVE, joinList(cves))
+	row.set(ColumnSeverity, rapid7Severity(d.Severity))
+	row.set("rapid7_severity", d.Severity)
+	row.set("cvss_vector", strings.Trim(d.CVSSVector, "()"))
+	row.set("risk_score", d.RiskScore)
+	row.set("published", d.Published)
+	row.set("category", strings.Join(d.Tags, ", "))
+	row.set("description", d.Description.text())
+	row.set("solution", d.Solution.text())
+	return row.values
+}
+
+// rapid7Vulnerable reports whether a test status is a finding.
+func rapid7Vulnerable(status string) bool {
+	switch status {
+	case "vulnerable-exploited", "vulnerable-version", "vulnerable-potential":
+		return true
+	}
+	return false
+}
+
+// rapid7Severity maps the 1-10 severity onto the standard names through the
+// Critical (8-10), Severe (4-7) and Moderate (1-3) ratings of the console.
+func rapid7Severity(severity string) string {
+	n, err := strconv.Atoi(strings.TrimSpace(severity))
+	switch {
+	case err != nil:
+		return ""
+	case n >= 8:
+		return "Critical"
+	case n >= 4:
+		return "High"
+	case n >= 1:
+		return "Medium"
+	}
+	return "Info"
+}
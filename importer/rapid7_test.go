@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRapid7(t *testing.T) {
+	_, rows := readRows(t, "rapid7.xml", "", Options{})
+	checkRows(t, rows, []map[string]string{
+		// Definition IDs join test IDs whatever their case
+		{ColumnHost: "10.0.0.5", "hostname": "web01.example.com", "mac_address": "00:50:56:AB:CD:EF", "site": "HQ",
+			ColumnOS: "Ubuntu Ubuntu Linux 22.04", ColumnPort: "", ColumnName: "Ubuntu: glibc vulnerability (CVE-2023-4911)",
+			ColumnCVSS: "7.8", ColumnCVE: "CVE-2023-4911", ColumnSeverity: "Critical", "vulnerability_id": "ubuntu-cve-2023-4911",
+			"rapid7_severity": "8", "status": "vulnerable-version", "cvss_vector": "AV:L/AC:L/Au:N/C:C/I:C/A:C", "risk_score": "812.5",
+			"published": "20231003T000000000", "vulnerable_since": "20240101T000000000", "category": "Ubuntu, Local",
+			"description": "A buffer overflow in the dynamic loader's handling of GLIBC_TUNABLES.",
+			"solution":    "Upgrade libc6 to 2.35-0ubuntu3.4",
+			"proof":       "Vulnerable OS: Ubuntu Linux 22.04 libc6 2.35-0ubuntu3.3 & older"},
+		{ColumnHost: "10.0.0.5", ColumnPort: "443", ColumnProtocol: "tcp", "service": "HTTPS",
+			ColumnName: "TLS/SSL Server Supports 3DES Cipher Suite", ColumnCVSS: "5.0", ColumnCVE: "CVE-2016-2183, CVE-2016-6329",
+			ColumnSeverity: "High", "status": "vulnerable-exploited", "proof": "Negotiated TLS_RSA_WITH_3DES_EDE_CBC_SHA"},
+		// A test without a definition keeps its ID as the name
+		{ColumnName: "http-custom-check", ColumnCVSS: "", ColumnSeverity: "", "status": "vulnerable-potential"},
+		{ColumnHost: "10.0.0.6", "hostname": "", ColumnOS: "", ColumnPort: "161", ColumnProtocol: "udp", "service": "SNMP",
+			ColumnName: "SNMP default community", ColumnCVSS: "", ColumnCVE: "", ColumnSeverity: "Medium"},
+	})
+}
+
+func TestRapid7Version(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.xml")
+	if err := os.WriteFile(path, []byte(`<NexposeReport version="1.0"><nodes/></NexposeReport>`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Open(path, "rapid7", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(); err == nil || !strings.Contains(err.Error(), "version 1.0") {
+		t.Errorf("Read of a version 1.0 export = %v, want an unsupported version", err)
+	}
+}
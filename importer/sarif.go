@@ -0,0 +1,252 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	register(Format{Name: "sarif", Extensions: []string{".sarif", ".sarif.json"}, open: newSARIFReader})
+}
+
+// AssetRepository is the asset_type of static analysis findings.
+const AssetRepository = "repository"
+
+// Level is the severity and CVSS score a SARIF result level stands for.
+type Level struct {
+	Severity string  `yaml:"severity"`
+	CVSS     float64 `yaml:"cvss"`
+}
+
+// DefaultLevels maps the SARIF result levels onto the report severities.
+// A rule's security-severity property, as CodeQL sets it, takes precedence
+// over the CVSS score of its level.
+var DefaultLevels = map[string]Level{
+	"error":   {Severity: "High", CVSS: 7.0},
+	"warning": {Severity: "Medium", CVSS: 5.0},
+	"note":    {Severity: "Low", CVSS: 2.0},
+	"none":    {Severity: "Info", CVSS: 0},
+}
+
+// sarifLayout is the header of a SARIF import: one row per result, with the
+// repository as the Host.
+var sarifLayout = newRowLayout(
+	ColumnHost, ColumnName, ColumnCVSS, ColumnCVE, ColumnSeverity, ColumnAssetType,
+	"rule_id", "level", "cwe", "file", "start_line", "message", "tool",
+)
+
+// sarifMessage is a SARIF message or multiformat string.
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// sarifRule is a reportingDescriptor of a tool component.
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	Name                 string       `json:"name"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+	Properties struct {
+		Tags             []string    `json:"tags"`
+		SecuritySeverity json.Number `json:"security-severity"`
+	} `json:"properties"`
+}
+
+// sarifComponent is the driver or an extension of a tool.
+type sarifComponent struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+// sarifSuppression records why a result was suppressed.
+type sarifSuppression struct {
+	Status string `json:"status"`
+}
+
+// sarifLog is a SARIF 2.1.0 log.
+type sarifLog struct {
+	Version string `json:"version"`
+	Runs    []struct {
+		Tool struct {
+			Driver     sarifComponent   `json:"driver"`
+			Extensions []sarifComponent `json:"extensions"`
+		} `json:"tool"`
+		VersionControlProvenance []struct {
+			RepositoryURI string `json:"repositoryUri"`
+		} `json:"versionControlProvenance"`
+		Results []struct {
+			RuleID    string `json:"ruleId"`
+			RuleIndex *int   `json:"ruleIndex"`
+			Rule      struct {
+				ID    string `json:"id"`
+				Index *int   `json:"index"`
+			} `json:"rule"`
+			Kind      string       `json:"kind"`
+			Level     string       `json:"level"`
+			Message   sarifMessage `json:"message"`
+			Locations []struct {
+				PhysicalLocation struct {
+					ArtifactLocation struct {
+						URI string `json:"uri"`
+					} `json:"artifactLocation"`
+					Region struct {
+						StartLine int `json:"startLine"`
+					} `json:"region"`
+				} `json:"physicalLocation"`
+			} `json:"locations"`
+			Suppressions []sarifSuppression `json:"suppressions"`
+		} `json:"results"`
+	} `json:"runs"`
+}
+
+func newSARIFReader(r io.Reader, opts Options) (rowSource, error) {
+	var log sarifLog
+	if err := json.NewDecoder(r).Decode(&log); err != nil {
+		return nil, fmt.Errorf("invalid SARIF: %w", err)
+	}
+	if log.Version != "" && log.Version != "2.1.0" {
+		return nil, fmt.Errorf("unsupported SARIF version %s", log.Version)
+	}
+
+	levels := make(map[string]Level, len(DefaultLevels))
+	for name, level := range DefaultLevels {
+		levels[name] = level
+	}
+	for name, level := range opts.Levels {
+		levels[strings.ToLower(name)] = level
+	}
+
+	reader := &sliceReader{header: sarifLayout.header}
+	for _, run := range log.Runs {
+		// The repository comes from version control details, else from the
+		// configured asset
+		repository := opts.Asset
+		if len(run.VersionControlProvenance) > 0 && run.VersionControlProvenance[0].RepositoryURI != "" {
+			repository = run.VersionControlProvenance[0].RepositoryURI
+		}
+
+		rules := map[string]sarifRule{}
+		for _, component := range append([]sarifComponent{run.Tool.Driver}, run.Tool.Extensions...) {
+			for _, rule := range component.Rules {
+				rules[rule.ID] = rule
+			}
+		}
+
+		for _, result := range run.Results {
+			if !sarifFinding(result.Kind) || sarifSuppressed(result.Suppressions) {
+				continue
+			}
+			id := firstNonEmpty(result.RuleID, result.Rule.ID)
+			rule, ok := rules[id]
+			if !ok {
+				// Results may point at their rule by index only
+				index := result.RuleIndex
+				if index == nil {
+					index = result.Rule.Index
+				}
+				if index != nil && *index >= 0 && *index < len(run.Tool.Driver.Rules) {
+					rule = run.Tool.Driver.Rules[*index]
+					id = firstNonEmpty(id, rule.ID)
+				}
+			}
+
+			// SARIF defaults the level to warning
+			level := strings.ToLower(firstNonEmpty(result.Level, rule.DefaultConfiguration.Level, "warning"))
+			mapped := levels[level]
+			score, severity := formatScore(mapped.CVSS), mapped.Severity
+			if value, err := rule.Properties.SecuritySeverity.Float64(); err == nil {
+				score, severity = formatScore(value), cvssSeverity(value)
+			}
+
+			row := sarifLayout.row()
+			row.set(ColumnHost, repository)
+			row.set(ColumnName, firstNonEmpty(rule.ShortDescription.Text, rule.Name, id))
+			row.set(ColumnCVSS, score)
+			row.set(ColumnSeverity, severity)
+			row.set(ColumnAssetType, AssetRepository)
+			row.set("rule_id", id)
+			row.set("level", level)
+			row.set("cwe", strings.Join(cweTags(rule.Properties.Tags), ", "))
+			row.set("message", result.Message.Text)
+			row.set("tool", run.Tool.Driver.Name)
+			if len(result.Locations) > 0 {
+				location := result.Locations[0].PhysicalLocation
+				row.set("file", location.ArtifactLocation.URI)
+				if location.Region.StartLine > 0 {
+					row.set("start_line", strconv.Itoa(location.Region.StartLine))
+				}
+			}
+			reader.rows = append(reader.rows, row.values)
+		}
+
+		// A log covering several repositories has no single subject
+		if reader.about == "" {
+			reader.about = repository
+		} else if reader.about != repository {
+			reader.about = ""
+			break
+		}
+	}
+	return reader, nil
+}
+
+// sarifFinding reports whether a result kind is a finding rather than a
+// passed or skipped check.
+func sarifFinding(kind string) bool {
+	switch kind {
+	case "", "fail", "open", "review":
+		return true
+	}
+	return false
+}
+
+// sarifSuppressed reports whether a result was suppressed for good.
+func sarifSuppressed(suppressions []sarifSuppression) bool {
+	for _, s := range suppressions {
+		if s.Status == "" || s.Status == "accepted" {
+			return true
+		}
+	}
+	return false
+}
+
+// cweTag matches CWE references in rule tags such as
+// external/cwe/cwe-079 or CWE-79.
+var cweTag = regexp.MustCompile(`(?i)\bcwe[-/]0*(\d+)\b`)
+
+// cweTags returns the CWE identifiers among tags, as CWE-79.
+func cweTags(tags []string) []string {
+	var cwes []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		for _, match := range cweTag.FindAllStringSubmatch(tag, -1) {
+			cwe := "CWE-" + match[1]
+			if !seen[cwe] {
+				seen[cwe] = true
+				cwes = append(cwes, cwe)
+			}
+		}
+	}
+	return cwes
+}
+
+// cvssSeverity names the FIRST CVSS v3 rating of a score.
+func cvssSeverity(score float64) string {
+	switch {
+	case score >= 9:
+		return "Critical"
+	case score >= 7:
+		return "High"
+	case score >= 4:
+		return "Medium"
+	case score > 0:
+		return "Low"
+	}
+	return "Info"
+}
@@ -0,0 +1,41 @@
+package importer
+
+import "testing"
+
+func TestSARIF(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts Options
+		want []map[string]string
+	}{
+		{
+			name: "default levels",
+			want: []map[string]string{
+				// The security-severity of a rule beats its level
+				{ColumnHost: "https://github.com/example/app", ColumnName: "Database query built from user-controlled sources",
+					ColumnCVSS: "8.8", ColumnSeverity: "High", ColumnAssetType: AssetRepository, "rule_id": "go/sql-injection",
+					"level": "error", "cwe": "CWE-89", "file": "store/query.go", "start_line": "42", "tool": "CodeQL"},
+				{ColumnCVSS: "5.0", ColumnSeverity: "Medium", "level": "warning", "cwe": "CWE-252", "file": "export.go", "start_line": ""},
+				// A result may point at its rule by index only
+				{ColumnName: "LogInjection", ColumnCVSS: "2.0", ColumnSeverity: "Low", "rule_id": "go/log-injection", "level": "note", "cwe": "CWE-117"},
+				{ColumnName: "TODO comment", ColumnCVSS: "0.0", ColumnSeverity: "Info", "level": "none"},
+			},
+		},
+		{
+			name: "configured levels",
+			opts: Options{Levels: map[string]Level{"Warning": {Severity: "High", CVSS: 7.5}, "note": {Severity: "Medium", CVSS: 4.0}}},
+			want: []map[string]string{
+				{ColumnCVSS: "8.8", ColumnSeverity: "High"},
+				{ColumnCVSS: "7.5", ColumnSeverity: "High", "level": "warning"},
+				{ColumnCVSS: "4.0", ColumnSeverity: "Medium", "level": "note"},
+				{ColumnCVSS: "0.0", ColumnSeverity: "Info", "level": "none"},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			header, rows := readRows(t, "codeql.sarif", "", tt.opts)
+			checkHeader(t, header)
+			checkRows(t, rows, tt.want)
+		})
+	}
+}
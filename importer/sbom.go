@@ -0,0 +1,584 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	register(Format{
+		Name:       "cyclonedx",
+		Extensions: []string{".cdx.json", ".cdx.xml", ".bom.json", ".bom.xml"},
+		Kind:       Components,
+		open:       newCycloneDXReader,
+		findings:   newCycloneDXFindingsReader,
+	})
+	register(Format{Name: "spdx", Extensions: []string{".spdx.json"}, Kind: Components, open: newSPDXReader})
+	register(Format{Name: "vex", Extensions: []string{".vex.json", ".vex.xml"}, Kind: Statements, open: newVEXReader})
+}
+
+// AssetProduct is the asset_type of findings embedded in an SBOM.
+const AssetProduct = "product"
+
+// ColumnComponent names the component of a components table row.
+const ColumnComponent = "component"
+
+// componentLayout is the header of an SBOM import: one row per component,
+// with the product the SBOM describes as the Host.
+var componentLayout = newRowLayout(
+	ColumnHost, ColumnComponent, "version", "group", "type", "purl", "cpe", "licenses", "supplier", "bom_ref",
+)
+
+// bomFindingLayout is the header of the vulnerabilities embedded in a
+// CycloneDX SBOM: one row per affected component.
+var bomFindingLayout = newRowLayout(
+	ColumnHost, ColumnName, ColumnCVSS, ColumnCVE, ColumnSeverity, ColumnAssetType,
+	"vulnerability_id", "package", "installed_version", "purl", "bom_ref", "cwe", "cvss_vector", "source", "state",
+)
+
+// vexLayout is the header of a VEX import: one row per statement on an
+// affected component. Empty product or component columns make the
+// statement apply to any.
+var vexLayout = newRowLayout(
+	ColumnHost, "vulnerability_id", ColumnComponent, "version", "purl", "bom_ref",
+	"state", "justification", "response", "detail",
+)
+
+// bom is a software bill of materials, whatever its format.
+type bom struct {
+	product         string
+	components      []bomComponent
+	vulnerabilities []bomVulnerability
+}
+
+type bomComponent struct {
+	ref, kind, group, name, version string
+	purl, cpe, supplier             string
+	licenses                        []string
+}
+
+type bomVulnerability struct {
+	id, source, description      string
+	ratings                      []bomRating
+	cwes                         []int
+	state, justification, detail string
+	responses                    []string
+	affects                      []string
+}
+
+type bomRating struct {
+	score                    float64
+	severity, method, vector string
+}
+
+// component returns the component that ref points at.
+func (b *bom) component(ref string) (bomComponent, bool) {
+	for _, c := range b.components {
+		if c.ref != "" && c.ref == ref {
+			return c, true
+		}
+	}
+	return bomComponent{}, false
+}
+
+// productName names the product an SBOM describes.
+func productName(name, version string) string {
+	if version == "" {
+		return name
+	}
+	return name + "@" + version
+}
+
+func newCycloneDXReader(r io.Reader, _ Options) (rowSource, error) {
+	doc, err := decodeCycloneDX(r)
+	if err != nil {
+		return nil, err
+	}
+	return componentRows(doc), nil
+}
+
+// componentRows lists the components of an SBOM.
+func componentRows(doc *bom) *sliceReader {
+	reader := &sliceReader{header: componentLayout.header, about: doc.product}
+	for _, c := range doc.components {
+		row := componentLayout.row()
+		row.set(ColumnHost, doc.product)
+		row.set(ColumnComponent, c.name)
+		row.set("version", c.version)
+		row.set("group", c.group)
+		row.set("type", c.kind)
+		row.set("purl", c.purl)
+		row.set("cpe", c.cpe)
+		row.set("licenses", strings.Join(c.licenses, ", "))
+		row.set("supplier", c.supplier)
+		row.set("bom_ref", c.ref)
+		reader.rows = append(reader.rows, row.values)
+	}
+	return reader
+}
+
+func newCycloneDXFindingsReader(r io.Reader, _ Options) (rowSource, error) {
+	doc, err := decodeCycloneDX(r)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &sliceReader{header: bomFindingLayout.header, about: doc.product}
+	for _, v := range doc.vulnerabilities {
+		// The SBOM itself may rule the vulnerability out
+		if v.state == "not_affected" || v.state == "false_positive" {
+			continue
+		}
+		score, severity, vector := bestRating(v.ratings)
+		cwes := make([]string, len(v.cwes))
+		for i, cwe := range v.cwes {
+			cwes[i] = "CWE-" + strconv.Itoa(cwe)
+		}
+
+		affects := v.affects
+		if len(affects) == 0 {
+			affects = []string{""}
+		}
+		for _, ref := range affects {
+			c, ok := doc.component(ref)
+			if !ok {
+				c = bomComponent{ref: ref}
+			}
+			row := bomFindingLayout.row()
+			row.set(ColumnHost, doc.product)
+			name := v.id
+			if c.name != "" {
+				name += " (" + c.name + ")"
+			}
+			row.set(ColumnName, name)
+			row.set(ColumnCVSS, score)
+			row.set(ColumnCVE, cveOf(v.id))
+			row.set(ColumnSeverity, namedSeverity(severity))
+			row.set(ColumnAssetType, AssetProduct)
+			row.set("vulnerability_id", v.id)
+			row.set("package", c.name)
+			row.set("installed_version", c.version)
+			row.set("purl", c.purl)
+			row.set("bom_ref", c.ref)
+			row.set("cwe", strings.Join(cwes, ", "))
+			row.set("cvss_vector", vector)
+			row.set("source", v.source)
+			row.set("state", v.state)
+			reader.rows = append(reader.rows, row.values)
+		}
+	}
+	if len(reader.rows) == 0 {
+		return nil, ErrNoFindings
+	}
+	return reader, nil
+}
+
+// ratingMethods ranks the CycloneDX rating methods, best first.
+var ratingMethods = []string{"CVSSv4", "CVSSv31", "CVSSv3", "CVSSv2"}
+
+// bestRating returns the score, severity and vector of the most recent
+// CVSS rating, or of the first rating when none is CVSS.
+func bestRating(ratings []bomRating) (string, string, string) {
+	for _, method := range ratingMethods {
+		for _, r := range ratings {
+			if r.method == method && r.score > 0 {
+				return formatScore(r.score), firstNonEmpty(r.severity, cvssSeverity(r.score)), r.vector
+			}
+		}
+	}
+	for _, r := range ratings {
+		if r.score > 0 {
+			return formatScore(r.score), firstNonEmpty(r.severity, cvssSeverity(r.score)), r.vector
+		}
+		if r.severity != "" {
+			return "", r.severity, r.vector
+		}
+	}
+	return "", "", ""
+}
+
+func newVEXReader(r io.Reader, _ Options) (rowSource, error) {
+	doc, err := decodeCycloneDX(r)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &sliceReader{header: vexLayout.header, about: doc.product}
+	for _, v := range doc.vulnerabilities {
+		if v.state == "" {
+			continue
+		}
+		affects := v.affects
+		if len(affects) == 0 {
+			affects = []string{""}
+		}
+		for _, ref := range affects {
+			row := vexLayout.row()
+			row.set(ColumnHost, doc.product)
+			row.set("vulnerability_id", strings.ToUpper(v.id))
+			if c, ok := doc.component(ref); ok {
+				row.set(ColumnComponent, c.name)
+				row.set("version", c.version)
+				row.set("purl", c.purl)
+			} else if strings.HasPrefix(ref, "pkg:") {
+				name, version := purlNameVersion(ref)
+				row.set(ColumnComponent, name)
+				row.set("version", version)
+				row.set("purl", ref)
+			} else if i := strings.LastIndex(ref, "#"); i >= 0 {
+				// A BOM-Link into another SBOM: urn:cdx:serial/version#bom-ref
+				row.set("bom_ref", ref[i+1:])
+			} else {
+				row.set("bom_ref", ref)
+			}
+			row.set("state", v.state)
+			row.set("justification", v.justification)
+			row.set("response", strings.Join(v.responses, ", "))
+			row.set("detail", v.detail)
+			reader.rows = append(reader.rows, row.values)
+		}
+	}
+	return reader, nil
+}
+
+// purlNameVersion returns the name and version of a package URL such as
+// pkg:npm/%40angular/core@12.0.0?arch=x86.
+func purlNameVersion(purl string) (string, string) {
+	purl = strings.SplitN(purl, "#", 2)[0]
+	purl = strings.SplitN(purl, "?", 2)[0]
+	var version string
+	if i := strings.LastIndex(purl, "@"); i > strings.LastIndex(purl, "/") {
+		purl, version = purl[:i], purl[i+1:]
+	}
+	name := purl[strings.LastIndex(purl, "/")+1:]
+	return name, version
+}
+
+// namedSeverity maps the severity words of the container and SBOM formats
+// onto the standard names.
+func namedSeverity(severity string) string {
+	switch strings.ToLower(strings.TrimSpace(severity)) {
+	case "critical":
+		return "Critical"
+	case "high":
+		return "High"
+	case "medium":
+		return "Medium"
+	case "low":
+		return "Low"
+	case "negligible", "info", "none":
+		return "Info"
+	}
+	return ""
+}
+
+// decodeCycloneDX reads a CycloneDX document, telling JSON from XML by its
+// first character.
+func decodeCycloneDX(r io.Reader) (*bom, error) {
+	buffered := bufio.NewReader(r)
+	for {
+		b, err := buffered.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("invalid CycloneDX: %w", err)
+		}
+		// Skip white space and a UTF-8 byte order mark
+		switch b {
+		case ' ', '\t', '\r', '\n', 0xef, 0xbb, 0xbf:
+			continue
+		}
+		if err := buffered.UnreadByte(); err != nil {
+			return nil, err
+		}
+		if b == '<' {
+			return decodeCycloneDXXML(buffered)
+		}
+		return decodeCycloneDXJSON(buffered)
+	}
+}
+
+// cdxJSONComponent is a component of a CycloneDX JSON document.
+type cdxJSONComponent struct {
+	Ref      string `json:"bom-ref"`
+	Type     string `json:"type"`
+	Group    string `json:"group"`
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	PURL     string `json:"purl"`
+	CPE      string `json:"cpe"`
+	Supplier struct {
+		Name string `json:"name"`
+	} `json:"supplier"`
+	Licenses []struct {
+		License struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"license"`
+		Expression string `json:"expression"`
+	} `json:"licenses"`
+	Components []cdxJSONComponent `json:"components"`
+}
+
+func (c cdxJSONComponent) flatten(into []bomComponent) []bomComponent {
+	component := bomComponent{
+		ref: c.Ref, kind: c.Type, group: c.Group, name: c.Name, version: c.Version,
+		purl: c.PURL, cpe: c.CPE, supplier: c.Supplier.Name,
+	}
+	for _, l := range c.Licenses {
+		if license := firstNonEmpty(l.License.ID, l.License.Name, l.Expression); license != "" {
+			component.licenses = append(component.licenses, license)
+		}
+	}
+	into = append(into, component)
+	for _, child := range c.Components {
+		into = child.flatten(into)
+	}
+	return into
+}
+
+// cdxJSON is a CycloneDX JSON document.
+type cdxJSON struct {
+	BOMFormat string `json:"bomFormat"`
+	Metadata  struct {
+		Component *cdxJSONComponent `json:"component"`
+	} `json:"metadata"`
+	Components      []cdxJSONComponent `json:"components"`
+	Vulnerabilities []struct {
+		ID     string `json:"id"`
+		Source struct {
+			Name string `json:"name"`
+		} `json:"source"`
+		Ratings []struct {
+			Score    float64 `json:"score"`
+			Severity string  `json:"severity"`
+			Method   string  `json:"method"`
+			Vector   string  `json:"vector"`
+		} `json:"ratings"`
+		CWEs        []int  `json:"cwes"`
+		Description string `json:"description"`
+		Analysis    struct {
+			State         string   `json:"state"`
+			Justification string   `json:"justification"`
+			Response      []string `json:"response"`
+			Detail        string   `json:"detail"`
+		} `json:"analysis"`
+		Affects []struct {
+			Ref string `json:"ref"`
+		} `json:"affects"`
+	} `json:"vulnerabilities"`
+}
+
+func decodeCycloneDXJSON(r io.Reader) (*bom, error) {
+	var doc cdxJSON
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid CycloneDX JSON: %w", err)
+	}
+	if doc.BOMFormat != "" && doc.BOMFormat != "CycloneDX" {
+		return nil, fmt.Errorf("not a CycloneDX document: bomFormat %s", doc.BOMFormat)
+	}
+
+	result := &bom{}
+	if c := doc.Metadata.Component; c != nil {
+		result.product = productName(c.Name, c.Version)
+	}
+	for _, c := range doc.Components {
+		result.components = c.flatten(result.components)
+	}
+	for _, v := range doc.Vulnerabilities {
+		vuln := bomVulnerability{
+			id: v.ID, source: v.Source.Name, description: v.Description, cwes: v.CWEs,
+			state: v.Analysis.State, justification: v.Analysis.Justification,
+			detail: v.Analysis.Detail, responses: v.Analysis.Response,
+		}
+		for _, rating := range v.Ratings {
+			vuln.ratings = append(vuln.ratings, bomRating{score: rating.Score, severity: rating.Severity, method: rating.Method, vector: rating.Vector})
+		}
+		for _, affect := range v.Affects {
+			vuln.affects = append(vuln.affects, affect.Ref)
+		}
+		result.vulnerabilities = append(result.vulnerabilities, vuln)
+	}
+	return result, nil
+}
+
+// cdxXMLComponent is a component of a CycloneDX XML document.
+type cdxXMLComponent struct {
+	Ref      string `xml:"bom-ref,attr"`
+	Type     string `xml:"type,attr"`
+	Group    string `xml:"group"`
+	Name     string `xml:"name"`
+	Version  string `xml:"version"`
+	PURL     string `xml:"purl"`
+	CPE      string `xml:"cpe"`
+	Supplier string `xml:"supplier>name"`
+	Licenses []struct {
+		ID   string `xml:"id"`
+		Name string `xml:"name"`
+	} `xml:"licenses>license"`
+	Expressions []string          `xml:"licenses>expression"`
+	Components  []cdxXMLComponent `xml:"components>component"`
+}
+
+func (c cdxXMLComponent) flatten(into []bomComponent) []bomComponent {
+	component := bomComponent{
+		ref: c.Ref, kind: c.Type, group: c.Group, name: c.Name, version: c.Version,
+		purl: c.PURL, cpe: c.CPE, supplier: c.Supplier,
+	}
+	for _, l := range c.Licenses {
+		if license := firstNonEmpty(l.ID, l.Name); license != "" {
+			component.licenses = append(component.licenses, license)
+		}
+	}
+	component.licenses = append(component.licenses, c.Expressions...)
+	into = append(into, component)
+	for _, child := range c.Components {
+		into = child.flatten(into)
+	}
+	return into
+}
+
+// cdxXML is a CycloneDX XML document.
+type cdxXML struct {
+	XMLName  xml.Name `xml:"bom"`
+	Metadata struct {
+		Component *cdxXMLComponent `xml:"component"`
+	} `xml:"metadata"`
+	Components      []cdxXMLComponent `xml:"components>component"`
+	Vulnerabilities []struct {
+		ID      string `xml:"id"`
+		Source  string `xml:"source>name"`
+		Ratings []struct {
+			Score    string `xml:"score"`
+			Severity string `xml:"severity"`
+			Method   string `xml:"method"`
+			Vector   string `xml:"vector"`
+		} `xml:"ratings>rating"`
+		CWEs        []int  `xml:"cwes>cwe"`
+		Description string `xml:"description"`
+		Analysis    struct {
+			State         string   `xml:"state"`
+			Justification string   `xml:"justification"`
+			Responses     []string `xml:"responses>response"`
+			Detail        string   `xml:"detail"`
+		} `xml:"analysis"`
+		Affects []string `xml:"affects>target>ref"`
+	} `xml:"vulnerabilities>vulnerability"`
+}
+
+func decodeCycloneDXXML(r io.Reader) (*bom, error) {
+	var doc cdxXML
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid CycloneDX XML: %w", err)
+	}
+
+	result := &bom{}
+	if c := doc.Metadata.Component; c != nil {
+		result.product = productName(c.Name, c.Version)
+	}
+	for _, c := range doc.Components {
+		result.components = c.flatten(result.components)
+	}
+	for _, v := range doc.Vulnerabilities {
+		vuln := bomVulnerability{
+			id: v.ID, source: v.Source, description: v.Description, cwes: v.CWEs,
+			state: v.Analysis.State, justification: v.Analysis.Justification,
+			detail: v.Analysis.Detail, responses: v.Analysis.Responses, affects: v.Affects,
+		}
+		for _, rating := range v.Ratings {
+			score, _ := strconv.ParseFloat(strings.TrimSpace(rating.Score), 64)
+			vuln.ratings = append(vuln.ratings, bomRating{score: score, severity: rating.Severity, method: rating.Method, vector: rating.Vector})
+		}
+		result.vulnerabilities = append(result.vulnerabilities, vuln)
+	}
+	return result, nil
+}
+
+// spdxDocument is an SPDX 2.x JSON document.
+type spdxDocument struct {
+	SPDXVersion       string   `json:"spdxVersion"`
+	Name              string   `json:"name"`
+	DocumentDescribes []string `json:"documentDescribes"`
+	Packages          []struct {
+		SPDXID           string `json:"SPDXID"`
+		Name             string `json:"name"`
+		VersionInfo      string `json:"versionInfo"`
+		Supplier         string `json:"supplier"`
+		LicenseConcluded string `json:"licenseConcluded"`
+		LicenseDeclared  string `json:"licenseDeclared"`
+		Purpose          string `json:"primaryPackagePurpose"`
+		ExternalRefs     []struct {
+			Type    string `json:"referenceType"`
+			Locator string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+	Relationships []struct {
+		Element string `json:"spdxElementId"`
+		Type    string `json:"relationshipType"`
+		Related string `json:"relatedSpdxElement"`
+	} `json:"relationships"`
+}
+
+func newSPDXReader(r io.Reader, _ Options) (rowSource, error) {
+	var doc spdxDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid SPDX JSON: %w", err)
+	}
+	if !strings.HasPrefix(doc.SPDXVersion, "SPDX-2") {
+		return nil, fmt.Errorf("unsupported SPDX version %q", doc.SPDXVersion)
+	}
+
+	// The product is the package the document describes
+	described := map[string]bool{}
+	for _, id := range doc.DocumentDescribes {
+		described[id] = true
+	}
+	for _, rel := range doc.Relationships {
+		if rel.Element == "SPDXRef-DOCUMENT" && rel.Type == "DESCRIBES" {
+			described[rel.Related] = true
+		}
+	}
+
+	result := &bom{product: doc.Name}
+	var products []string
+	for _, p := range doc.Packages {
+		if described[p.SPDXID] {
+			products = append(products, productName(p.Name, p.VersionInfo))
+			continue
+		}
+		c := bomComponent{
+			ref: p.SPDXID, kind: strings.ToLower(p.Purpose), name: p.Name, version: spdxValue(p.VersionInfo),
+			supplier: strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(spdxValue(p.Supplier), "Organization:"), "Person:")),
+		}
+		if license := firstNonEmpty(spdxValue(p.LicenseConcluded), spdxValue(p.LicenseDeclared)); license != "" {
+			c.licenses = []string{license}
+		}
+		for _, ref := range p.ExternalRefs {
+			switch ref.Type {
+			case "purl":
+				c.purl = ref.Locator
+			case "cpe23Type", "cpe22Type":
+				c.cpe = firstNonEmpty(c.cpe, ref.Locator)
+			}
+		}
+		result.components = append(result.components, c)
+	}
+	if len(products) > 0 {
+		sort.Strings(products)
+		result.product = strings.Join(products, ", ")
+	}
+	return componentRows(result), nil
+}
+
+// spdxValue drops the NOASSERTION and NONE placeholders of SPDX fields.
+func spdxValue(value string) string {
+	switch value {
+	case "NOASSERTION", "NONE":
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCycloneDXComponents(t *testing.T) {
+	for _, tt := range []struct {
+		file string
+		want []map[string]string
+	}{
+		{
+			file: "app.cdx.json",
+			want: []map[string]string{
+				{ColumnHost: "shop-api@2.3.0", ColumnComponent: "net", "version": "v0.17.0", "group": "golang.org/x", "type": "library",
+					"purl": "pkg:golang/golang.org/x/net@v0.17.0", "licenses": "BSD-3-Clause", "bom_ref": "pkg:golang/golang.org/x/net@v0.17.0"},
+				{ColumnComponent: "lodash", "licenses": "MIT OR Apache-2.0", "supplier": "OpenJS Foundation"},
+				// Nested components follow their parent
+				{ColumnHost: "shop-api@2.3.0", ColumnComponent: "lodash.merge", "version": "4.6.2"},
+			},
+		},
+		{
+			file: "app.cdx.xml",
+			want: []map[string]string{
+				{ColumnHost: "billing@1.0.4", ColumnComponent: "log4j-core", "version": "2.14.1", "group": "org.apache.logging.log4j",
+					"type": "library", "licenses": "Apache-2.0", "supplier": "Apache Software Foundation",
+					"purl": "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1"},
+				{ColumnComponent: "log4j-api", "licenses": "Apache-2.0 OR MIT", "supplier": ""},
+			},
+		},
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			_, rows := readRows(t, tt.file, "", Options{})
+			checkRows(t, rows, tt.want)
+		})
+	}
+}
+
+func TestCycloneDXFindings(t *testing.T) {
+	for _, tt := range []struct {
+		file string
+		want []map[string]string
+	}{
+		{
+			file: "app.cdx.json",
+			want: []map[string]string{
+				// The CVSSv31 rating beats the CVSSv2 one
+				{ColumnHost: "shop-api@2.3.0", ColumnName: "CVE-2023-44487 (net)", ColumnCVSS: "7.5", ColumnCVE: "CVE-2023-44487",
+					ColumnSeverity: "High", ColumnAssetType: AssetProduct, "package": "net", "installed_version": "v0.17.0",
+					"cwe": "CWE-400", "cvss_vector": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H", "source": "NVD"},
+				// One row per affected component, the severity from the score
+				{ColumnName: "CVE-2021-23337 (lodash)", ColumnCVSS: "7.2", ColumnSeverity: "High", "package": "lodash", "cwe": "CWE-77, CWE-94"},
+				{ColumnName: "CVE-2021-23337 (lodash.merge)", "package": "lodash.merge", "installed_version": "4.6.2"},
+				// CVE-2020-28500 is not_affected; an unknown component keeps its ref
+				{ColumnName: "GHSA-29mw-wpgm-hmr9", ColumnCVSS: "", ColumnCVE: "", ColumnSeverity: "Low", "package": "",
+					"bom_ref": "pkg:npm/unlisted@1.0.0", "state": "in_triage"},
+			},
+		},
+		{
+			// CVE-2021-45046 is a false positive
+			file: "app.cdx.xml",
+			want: []map[string]string{
+				{ColumnHost: "billing@1.0.4", ColumnName: "CVE-2021-44228 (log4j-core)", ColumnCVSS: "10.0", ColumnSeverity: "Critical",
+					"cwe": "CWE-502, CWE-917", "source": "NVD", "cvss_vector": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H"},
+			},
+		},
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			header, rows := readFindings(t, tt.file, "cyclonedx")
+			checkHeader(t, header)
+			checkRows(t, rows, tt.want)
+		})
+	}
+}
+
+func TestCycloneDXWithoutFindings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lib.cdx.json")
+	if err := os.WriteFile(path, []byte(`{"bomFormat": "CycloneDX", "components": [{"name": "lodash"}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFindings(path, "cyclonedx", Options{}); !errors.Is(err, ErrNoFindings) {
+		t.Errorf("OpenFindings of an SBOM without vulnerabilities = %v, want ErrNoFindings", err)
+	}
+}
+
+func TestSPDX(t *testing.T) {
+	_, rows := readRows(t, "app.spdx.json", "", Options{})
+	checkRows(t, rows, []map[string]string{
+		// The described package is the product, not a component
+		{ColumnHost: "web-frontend@1.2.0", ColumnComponent: "react", "version": "18.2.0", "type": "library",
+			"purl": "pkg:npm/react@18.2.0", "licenses": "MIT", "supplier": "Meta Platforms, Inc.", "bom_ref": "SPDXRef-Package-react"},
+		// NOASSERTION reads as empty
+		{ColumnComponent: "openssl", "version": "", "supplier": "", "licenses": "Apache-2.0",
+			"cpe": "cpe:2.3:a:openssl:openssl:3.0.7:*:*:*:*:*:*:*", "purl": "pkg:generic/openssl@3.0.7"},
+	})
+}
+
+func TestVEX(t *testing.T) {
+	_, rows := readRows(t, "app.vex.json", "", Options{})
+	checkRows(t, rows, []map[string]string{
+		{ColumnHost: "shop-api@2.3.0", "vulnerability_id": "CVE-2020-28500", ColumnComponent: "lodash", "version": "4.17.20",
+			"purl": "pkg:npm/lodash@4.17.20", "bom_ref": "", "state": "not_affected", "justification": "code_not_reachable",
+			"detail": "toNumber is never called"},
+		// A package URL names the component itself
+		{"vulnerability_id": "CVE-2023-44487", ColumnComponent: "net", "version": "v0.17.0",
+			"purl": "pkg:golang/golang.org/x/net@v0.17.0?type=module", "state": "exploitable", "response": "update, workaround_available"},
+		// A BOM-Link points into another SBOM
+		{"vulnerability_id": "CVE-2022-24999", ColumnComponent: "", "bom_ref": "qs-6.5.2", "state": "not_affected"},
+		// A statement without targets covers the whole product; one without analysis is no statement
+		{"vulnerability_id": "CVE-2024-0001", ColumnComponent: "", "bom_ref": "", "state": "resolved"},
+	})
+}
+
+func TestPURLNameVersion(t *testing.T) {
+	for _, tt := range []struct{ purl, name, version string }{
+		{"pkg:npm/%40angular/core@12.0.0?arch=x86", "core", "12.0.0"},
+		{"pkg:golang/golang.org/x/net@v0.17.0#sub/path", "net", "v0.17.0"},
+		{"pkg:deb/debian/curl", "curl", ""},
+	} {
+		if name, version := purlNameVersion(tt.purl); name != tt.name || version != tt.version {
+			t.Errorf("purlNameVersion(%q) = %q, %q, want %q, %q", tt.purl, name, version, tt.name, tt.version)
+		}
+	}
+}
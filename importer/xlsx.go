@@ -0,0 +1,366 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func init() {
+	register(Format{Name: "xlsx", Extensions: []string{".xlsx", ".xlsm"}, profiled: true, open: newXLSXReader})
+}
+
+// AllSheets selects every visible sheet of a workbook.
+const AllSheets = "*"
+
+// headerSearchRows is the number of rows searched for the header row of a
+// sheet, past any title block.
+const headerSearchRows = 25
+
+// xlsxMerge is a merged range of cells and its value.
+type xlsxMerge struct {
+	startCol, startRow, endCol, endRow int
+	value                              string
+}
+
+// xlsxSheet is a sheet to read and where its data starts.
+type xlsxSheet struct {
+	name   string
+	merges []xlsxMerge
+	header []string
+	// start is the first data row, counting from 1.
+	start int
+	// columns places the sheet columns in the combined header.
+	columns []int
+}
+
+// fill sets the cells of row that merged ranges cover to the range value.
+func (s *xlsxSheet) fill(row int, cells []string) []string {
+	for _, m := range s.merges {
+		if row < m.startRow || row > m.endRow {
+			continue
+		}
+		for len(cells) < m.endCol {
+			cells = append(cells, "")
+		}
+		for col := m.startCol; col <= m.endCol; col++ {
+			cells[col-1] = m.value
+		}
+	}
+	return cells
+}
+
+// xlsxReader streams the rows of one sheet, or of every sheet under their
+// combined header with the sheet name in a sheet column.
+type xlsxReader struct {
+	file       *excelize.File
+	sheets     []*xlsxSheet
+	header     []string
+	all        bool
+	date1904   bool
+	headerSent bool
+
+	// The sheet being read, as formatted and as raw values
+	current        int
+	formatted, raw *excelize.Rows
+	row            int
+}
+
+func newXLSXReader(r io.Reader, opts Options) (rowSource, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workbook: %w", err)
+	}
+	reader := &xlsxReader{file: file, all: opts.Sheet == AllSheets}
+	if err := reader.init(opts); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+// init picks the sheets and finds their headers.
+func (r *xlsxReader) init(opts Options) error {
+	var names []string
+	switch opts.Sheet {
+	case "":
+		names = r.file.GetSheetList()[:1]
+	case AllSheets:
+		for _, name := range r.file.GetSheetList() {
+			if visible, err := r.file.GetSheetVisible(name); err == nil && visible {
+				names = append(names, name)
+			}
+		}
+	default:
+		if index, err := r.file.GetSheetIndex(opts.Sheet); err != nil || index < 0 {
+			return fmt.Errorf("workbook has no sheet %q; it has %s", opts.Sheet, strings.Join(r.file.GetSheetList(), ", "))
+		}
+		names = []string{opts.Sheet}
+	}
+	if wb := r.file.WorkBook; wb != nil && wb.WorkbookPr != nil {
+		r.date1904 = wb.WorkbookPr.Date1904
+	}
+
+	position := map[string]int{}
+	if r.all {
+		r.header = append(r.header, "sheet")
+		position["sheet"] = 0
+	}
+	for _, name := range names {
+		sheet := &xlsxSheet{name: name}
+		if err := r.findHeader(sheet, opts.HeaderRow); err != nil {
+			return fmt.Errorf("sheet %s: %w", name, err)
+		}
+		if sheet.header == nil {
+			// An empty sheet among many is skipped
+			if r.all {
+				continue
+			}
+			return fmt.Errorf("sheet %s has no header row", name)
+		}
+		for _, column := range sheet.header {
+			i, ok := position[column]
+			if !ok {
+				i = len(r.header)
+				position[column] = i
+				r.header = append(r.header, column)
+			}
+			sheet.columns = append(sheet.columns, i)
+		}
+		r.sheets = append(r.sheets, sheet)
+	}
+	return nil
+}
+
+// findHeader reads the merged ranges and the header of a sheet. Without a
+// header row number, the header is the first of the top rows with the most
+// distinct texts. A header whose merged cells span sub-headers on the next row
+// is read as two rows, e.g. "CVSS Base" and "CVSS Temporal".
+func (r *xlsxReader) findHeader(sheet *xlsxSheet, headerRow int) error {
+	merged, err := r.file.GetMergeCells(sheet.name)
+	if err != nil {
+		return err
+	}
+	for _, m := range merged {
+		startCol, startRow, err := excelize.CellNameToCoordinates(m.GetStartAxis())
+		if err != nil {
+			return err
+		}
+		endCol, endRow, err := excelize.CellNameToCoordinates(m.GetEndAxis())
+		if err != nil {
+			return err
+		}
+		sheet.merges = append(sheet.merges, xlsxMerge{startCol, startRow, endCol, endRow, strings.TrimSpace(m.GetCellValue())})
+	}
+
+	rows, err := r.file.Rows(sheet.name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var top [][]string
+	limit := headerSearchRows
+	if headerRow > 0 {
+		limit = headerRow + 1
+	}
+	for n := 1; n <= limit && rows.Next(); n++ {
+		cells, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+		top = append(top, trimCells(cells))
+	}
+
+	best, bestCount := 0, 1
+	if headerRow > 0 {
+		if headerRow > len(top) {
+			return nil
+		}
+		best = headerRow
+	} else {
+		for i, cells := range top {
+			if count := textCells(cells); count > bestCount {
+				best, bestCount = i+1, count
+			}
+		}
+	}
+	if best == 0 {
+		return nil
+	}
+
+	// Rows are scored on their own cells; merged ranges fill them after
+	header := sheet.fill(best, append([]string(nil), top[best-1]...))
+	sheet.start = best + 1
+	if best < len(top) && sheet.spansSubHeaders(best, top[best]) {
+		sub := sheet.fill(best+1, top[best])
+		for i := range header {
+			if i < len(sub) && sub[i] != "" && sub[i] != header[i] {
+				header[i] = strings.TrimSpace(header[i] + " " + sub[i])
+			}
+		}
+		for i := len(header); i < len(sub); i++ {
+			header = append(header, sub[i])
+		}
+		sheet.start = best + 2
+	}
+	// Name blank columns by letter and number repeated names
+	seen := map[string]int{}
+	for i, name := range header {
+		if name == "" {
+			column, _ := excelize.ColumnNumberToName(i + 1)
+			name = "Column " + column
+		}
+		if seen[name]++; seen[name] > 1 {
+			name += " " + strconv.Itoa(seen[name])
+		}
+		header[i] = name
+	}
+	sheet.header = header
+	return nil
+}
+
+// spansSubHeaders reports whether a header row has a merged cell across
+// columns with text on the next row below it.
+func (s *xlsxSheet) spansSubHeaders(row int, next []string) bool {
+	for _, m := range s.merges {
+		if m.startRow != row || m.endRow != row || m.endCol == m.startCol {
+			continue
+		}
+		for col := m.startCol; col <= m.endCol && col <= len(next); col++ {
+			if next[col-1] != "" && !isNumber(next[col-1]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *xlsxReader) Read() ([]string, error) {
+	if !r.headerSent {
+		r.headerSent = true
+		return r.header, nil
+	}
+
+	for r.current < len(r.sheets) {
+		sheet := r.sheets[r.current]
+		if r.formatted == nil {
+			var err error
+			if r.formatted, err = r.file.Rows(sheet.name); err != nil {
+				return nil, err
+			}
+			if r.raw, err = r.file.Rows(sheet.name); err != nil {
+				return nil, err
+			}
+			r.row = 0
+		}
+
+		for r.formatted.Next() && r.raw.Next() {
+			// Both iterators read every row, or their cells run together
+			r.row++
+			formatted, err := r.formatted.Columns()
+			if err != nil {
+				return nil, fmt.Errorf("sheet %s row %d: %w", sheet.name, r.row, err)
+			}
+			raw, err := r.raw.Columns(excelize.Options{RawCellValue: true})
+			if err != nil {
+				return nil, fmt.Errorf("sheet %s row %d: %w", sheet.name, r.row, err)
+			}
+			if r.row < sheet.start {
+				continue
+			}
+			cells := sheet.fill(r.row, trimCells(formatted))
+			if !anyValue(cells) {
+				continue
+			}
+
+			values := make([]string, len(r.header))
+			if r.all {
+				values[0] = sheet.name
+			}
+			for i, value := range cells {
+				if i >= len(sheet.columns) {
+					break
+				}
+				if i < len(raw) {
+					value = r.coerce(value, strings.TrimSpace(raw[i]))
+				}
+				values[sheet.columns[i]] = value
+			}
+			return values, nil
+		}
+
+		r.formatted.Close()
+		r.raw.Close()
+		r.formatted, r.raw = nil, nil
+		r.current++
+	}
+	r.file.Close()
+	return nil, io.EOF
+}
+
+// dateLike matches formatted values that show a date or a time.
+var dateLike = regexp.MustCompile(`(?i)\d[-/:]\d|\b\d{1,2}\.\d{1,2}\.\d{2,4}\b|\b(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)`)
+
+// coerce turns a formatted cell value into a plain one: numbers lose their
+// thousands separators, currency signs and percent formatting, and dates
+// become YYYY-MM-DD, with the time when there is one.
+func (r *xlsxReader) coerce(formatted, raw string) string {
+	if formatted == raw || !isNumber(raw) {
+		return formatted
+	}
+	if !dateLike.MatchString(formatted) {
+		return raw
+	}
+	serial, _ := strconv.ParseFloat(raw, 64)
+	t, err := excelize.ExcelDateToTime(serial, r.date1904)
+	if err != nil {
+		return formatted
+	}
+	if serial == float64(int64(serial)) {
+		return t.Format("2006-01-02")
+	}
+	if serial < 1 {
+		return t.Format("15:04:05")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// trimCells trims the white space around cell values.
+func trimCells(cells []string) []string {
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// textCells counts the distinct texts other than numbers among cells.
+func textCells(cells []string) int {
+	texts := map[string]bool{}
+	for _, cell := range cells {
+		if cell != "" && !isNumber(cell) && strings.IndexFunc(cell, unicode.IsLetter) >= 0 {
+			texts[cell] = true
+		}
+	}
+	return len(texts)
+}
+
+// anyValue reports whether any cell is not empty.
+func anyValue(cells []string) bool {
+	for _, cell := range cells {
+		if cell != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isNumber reports whether value is a plain number.
+func isNumber(value string) bool {
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// writeWorkbook saves a vulnerability report workbook: a Findings sheet
+// under a title block with a two row header, a Hosts sheet and a hidden
+// Notes sheet.
+func writeWorkbook(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	must(f.SetSheetName("Sheet1", "Findings"))
+	must(f.SetCellValue("Findings", "A1", "Weekly vulnerability report"))
+	must(f.MergeCell("Findings", "A1", "E1"))
+	must(f.SetSheetRow("Findings", "A3", &[]interface{}{"Host", "Name", "CVSS", nil, "Found", "Cost"}))
+	must(f.MergeCell("Findings", "C3", "D3"))
+	must(f.SetSheetRow("Findings", "C4", &[]interface{}{"Base", "Temporal"}))
+	must(f.SetSheetRow("Findings", "A5", &[]interface{}{"10.0.0.1", "Weak TLS", 5.3, 4.8, 45355, 1234.5}))
+	must(f.SetSheetRow("Findings", "A6", &[]interface{}{"10.0.0.2", "Open SNMP", 7.5, nil, 45355.5}))
+	must(f.SetSheetRow("Findings", "B7", &[]interface{}{"Telnet enabled", 9.8}))
+	must(f.MergeCell("Findings", "A6", "A7"))
+	date, err := f.NewStyle(&excelize.Style{NumFmt: 14})
+	must(err)
+	must(f.SetCellStyle("Findings", "E5", "E5", date))
+	dateTime, err := f.NewStyle(&excelize.Style{NumFmt: 22})
+	must(err)
+	must(f.SetCellStyle("Findings", "E6", "E6", dateTime))
+	thousands, err := f.NewStyle(&excelize.Style{NumFmt: 4})
+	must(err)
+	must(f.SetCellStyle("Findings", "F5", "F5", thousands))
+
+	_, err = f.NewSheet("Hosts")
+	must(err)
+	must(f.SetSheetRow("Hosts", "A1", &[]interface{}{"Host", "OS"}))
+	must(f.SetSheetRow("Hosts", "A2", &[]interface{}{"10.0.0.1", "Ubuntu 22.04"}))
+
+	_, err = f.NewSheet("Notes")
+	must(err)
+	must(f.SetSheetRow("Notes", "A1", &[]interface{}{"Owner", "Comment"}))
+	must(f.SetSheetRow("Notes", "A2", &[]interface{}{"ops", "ignore"}))
+	must(f.SetSheetVisible("Notes", false))
+
+	path := filepath.Join(t.TempDir(), "report.xlsx")
+	must(f.SaveAs(path))
+	return path
+}
+
+// readWorkbook returns the header and the rows of a workbook, their
+// values joined by bars.
+func readWorkbook(t *testing.T, path string, opts Options) (string, []string) {
+	t.Helper()
+	r, err := Open(path, "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	header, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []string
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return strings.Join(header, "|"), rows
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, strings.Join(record, "|"))
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	path := writeWorkbook(t)
+	for _, tt := range []struct {
+		name       string
+		opts       Options
+		wantHeader string
+		wantRows   []string
+	}{
+		{
+			// The header is found below the title, its merged CVSS cell
+			// naming the sub-headers below it
+			name:       "first sheet",
+			opts:       Options{Profile: NoProfile},
+			wantHeader: "Host|Name|CVSS Base|CVSS Temporal|Found|Cost",
+			wantRows: []string{
+				"10.0.0.1|Weak TLS|5.3|4.8|2024-03-04|1234.5",
+				"10.0.0.2|Open SNMP|7.5||2024-03-04 12:00:00|",
+				// A merged cell fills every row it spans
+				"10.0.0.2|Telnet enabled|9.8|||",
+			},
+		},
+		{
+			name:       "header row",
+			opts:       Options{Profile: NoProfile, Sheet: "Hosts", HeaderRow: 2},
+			wantHeader: "10.0.0.1|Ubuntu 22.04",
+		},
+		{
+			name:       "named sheet",
+			opts:       Options{Profile: NoProfile, Sheet: "Hosts"},
+			wantHeader: "Host|OS",
+			wantRows:   []string{"10.0.0.1|Ubuntu 22.04"},
+		},
+		{
+			// Hidden sheets are left out
+			name:       "all sheets",
+			opts:       Options{Profile: NoProfile, Sheet: AllSheets},
+			wantHeader: "sheet|Host|Name|CVSS Base|CVSS Temporal|Found|Cost|OS",
+			wantRows: []string{
+				"Findings|10.0.0.1|Weak TLS|5.3|4.8|2024-03-04|1234.5|",
+				"Findings|10.0.0.2|Open SNMP|7.5||2024-03-04 12:00:00||",
+				"Findings|10.0.0.2|Telnet enabled|9.8||||",
+				"Hosts|10.0.0.1||||||Ubuntu 22.04",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			header, rows := readWorkbook(t, path, tt.opts)
+			if header != tt.wantHeader {
+				t.Errorf("header = %s, want %s", header, tt.wantHeader)
+			}
+			if fmt.Sprint(rows) != fmt.Sprint(tt.wantRows) {
+				t.Errorf("rows:\n%s\nwant:\n%s", strings.Join(rows, "\n"), strings.Join(tt.wantRows, "\n"))
+			}
+		})
+	}
+}
+
+func TestXLSXMissingSheet(t *testing.T) {
+	_, err := Open(writeWorkbook(t), "", Options{Sheet: "Summary"})
+	if err == nil || !strings.Contains(err.Error(), "Findings, Hosts, Notes") {
+		t.Errorf("Open of a missing sheet = %v, want the sheets listed", err)
+	}
+}
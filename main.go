@@ -1,110 +1,137 @@
 package main
 
 import (
-	dbsql "database/sql"
-	"encoding/csv"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+)
 
-	_ "github.com/go-sql-driver/mysql"
-	"github.com/sentlab/update-db/excel"
-	"github.com/sentlab/update-db/sql"
+// Exit codes returned by the binary.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
 )
 
-func main() {
-	// os.Args[1] should contain your database connection string.
-	db, err := dbsql.Open("mysql", os.Args[1])
-	if err != nil {
-		fmt.Printf("Error opening DB. Error: %v\n", err)
-		os.Exit(1)
-	}
-	defer db.Close()
+const programName = "update-db"
 
-	// os.Args[2] should contain the table name to use in queries.
-	tableName := os.Args[2]
+// command is a single subcommand of the binary.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
 
-	// os.Args[3] should contain the path to the CSV file to upload.
-	csvFilePath := os.Args[3]
+// usageError marks an error caused by bad command-line input.
+type usageError struct {
+	msg string
+}
 
-	// Upload the CSV file to the database table.
-	err = uploadCSV(db, tableName, csvFilePath)
-	if err != nil {
-		fmt.Printf("Error uploading CSV file. Error: %v\n", err)
-		os.Exit(1)
-	}
+func (e *usageError) Error() string { return e.msg }
 
-	// Execute the original SQL queries and populate the data structures.
-	// Call your RunQueries function to execute the queries and populate the data structures.
-	vulnBySeverity, topTenVulnHosts, mostDangerousVulns, vulnByType, countCVSSYear := sql.RunQueries(db, tableName)
-	if err != nil {
-		fmt.Printf("Error executing queries. Error: %v\n", err)
-		os.Exit(1)
-	}
-	// os.Args[4] should contain the path to the Excel file you want to update.
-	fileLocation := os.Args[4]
-
-	// Call the WriteData function to write the data to the Excel file.
-	excel.WriteData(fileLocation, vulnBySeverity, topTenVulnHosts, mostDangerousVulns, vulnByType, countCVSSYear)
-	if err != nil {
-		fmt.Printf("Error writing data to Excel file. Error: %v\n", err)
-		os.Exit(1)
-	}
+func newUsageError(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+var commands = []command{
+	{name: "import", summary: "Upload a CSV file into a database table", run: runImport},
+	{name: "report", summary: "Run the report queries and populate an Excel workbook", run: runReport},
+	{name: "scans", summary: "List the scan snapshots of a table", run: runScans},
+	{name: "export", summary: "Write the rows of a table or scan as JSON Lines", run: runExport},
+	{name: "load-input", summary: "Create the input table from a CSV file and load it", run: runLoadInput},
+	{name: "fix-nulls", summary: "Replace NULL values in a table with empty strings", run: runFixNulls},
+	{name: "merge-state", summary: "Copy the input table state into a new source table column", run: runMergeState},
+	{name: "count-by-os", summary: "Count vulnerabilities by operating system, severity and state", run: runCountByOS},
+	{name: "match", summary: "Set a column value for a single row", run: runMatch},
+}
 
-	fmt.Println("Data written to Excel file successfully.")
+func main() {
+	// Cancel the running command on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stderr)
+	stop()
+	os.Exit(code)
 }
 
-func uploadCSV(db *dbsql.DB, tableName string, csvFilePath string) error {
-	file, err := os.Open(csvFilePath)
-	if err != nil {
-		return err
+// run dispatches args to the matching subcommand and returns the exit code.
+func run(ctx context.Context, args []string, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitUsage
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = -1 // Allow variable number of fields
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage(stderr)
+		return exitOK
+	}
 
-	records, err := reader.ReadAll()
-	if err != nil {
-		return err
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(ctx, args[1:])
+		if err == nil {
+			return exitOK
+		}
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		fmt.Fprintf(stderr, "%s %s: %v\n", programName, name, err)
+		var uerr *usageError
+		if errors.As(err, &uerr) {
+			return exitUsage
+		}
+		return exitFailure
 	}
 
-	// Truncate the table before uploading the CSV data
-	_, err = db.Exec(fmt.Sprintf("TRUNCATE TABLE %s", tableName))
-	if err != nil {
-		return err
+	fmt.Fprintf(stderr, "%s: unknown command %q\n\n", programName, name)
+	printUsage(stderr)
+	return exitUsage
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\nCommands:\n", programName)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintf(w, "\nRun '%s <command> --help' for the flags of a command.\n", programName)
+}
 
-	// Prepare the SQL statement for inserting data
-	stmt, err := db.Prepare(fmt.Sprintf("INSERT INTO %s VALUES(%s)", tableName, generatePlaceholders(len(records[0]))))
-	if err != nil {
-		return err
+// newFlagSet returns a flag set whose usage text describes the subcommand.
+func newFlagSet(name string, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags]\n\n%s\n\nFlags:\n", programName, name, usage)
+		fs.PrintDefaults()
 	}
-	defer stmt.Close()
-
-	// Insert each record into the table
-	for _, record := range records {
-		// Convert record to []interface{}
-		recordValues := make([]interface{}, len(record))
-		for i, v := range record {
-			recordValues[i] = v
-		}
+	return fs
+}
 
-		_, err := stmt.Exec(recordValues...)
-		if err != nil {
+// parseFlags parses args and turns flag errors into usage errors.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
 			return err
 		}
+		return &usageError{msg: err.Error()}
+	}
+	if fs.NArg() > 0 {
+		return newUsageError("unexpected arguments: %v", fs.Args())
 	}
-
 	return nil
 }
 
-func generatePlaceholders(count int) string {
-	placeholders := make([]byte, 2*count-1)
-	for i := 0; i < count; i++ {
-		placeholders[2*i] = '?'
-		if i < count-1 {
-			placeholders[2*i+1] = ','
+// requireFlags returns a usage error for the first named flag left empty.
+func requireFlags(fs *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		if f := fs.Lookup(name); f != nil && f.Value.String() == "" {
+			return newUsageError("missing required flag --%s", name)
 		}
 	}
-	return string(placeholders)
+	return nil
 }
@@ -0,0 +1,117 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// OpenService counts the hosts that expose a service on a port.
+type OpenService struct {
+	Port     int
+	Protocol string
+	Service  string
+	Product  string
+	Hosts    int
+}
+
+// OpenServices counts the hosts of the asset scans that sel picks by open
+// port, protocol and service, most widespread first. A table without scans
+// is counted whole.
+func OpenServices(ctx context.Context, s *Store, assetsTable string, sel Selector) ([]OpenService, error) {
+	if err := ValidateIdentifier(assetsTable); err != nil {
+		return nil, err
+	}
+	if err := s.RequireColumns(ctx, assetsTable, "Host", "Port", "Protocol", "service", "product"); err != nil {
+		return nil, err
+	}
+	sc, err := assetScope(ctx, s, assetsTable, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	SELECT {Port}, {Protocol}, {service}, MAX({product}), COUNT(DISTINCT {Host}) AS Hosts
+	FROM !!
+	WHERE {Port} IS NOT NULL
+	GROUP BY {Port}, {Protocol}, {service}
+	ORDER BY Hosts DESC, {Port}
+	`
+	query, args := sc.expand(s, query)
+	rows, err := s.Query(ctx, query, args...)
+	if err != nil {
+		return nil, &QueryError{Query: "open services", Err: err}
+	}
+	defer rows.Close()
+
+	results := []OpenService{}
+	for rows.Next() {
+		var res OpenService
+		var port, protocol, service, product sql.NullString
+		if err := rows.Scan(&port, &protocol, &service, &product, &res.Hosts); err != nil {
+			return nil, &QueryError{Query: "open services", Err: err}
+		}
+		if res.Port, err = strconv.Atoi(port.String); err != nil {
+			continue
+		}
+		res.Protocol = protocol.String
+		res.Service = service.String
+		res.Product = product.String
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
+
+// assetScope covers the asset scans that sel picks, or the whole table when
+// it has no scans.
+func assetScope(ctx context.Context, s *Store, assetsTable string, sel Selector) (scope, error) {
+	scans, err := Scans(ctx, s, assetsTable)
+	if err != nil {
+		return scope{}, err
+	}
+	if len(scans) == 0 {
+		return tableScope(assetsTable), nil
+	}
+	if scans, err = ResolveScans(ctx, s, assetsTable, sel); err != nil {
+		return scope{}, err
+	}
+	ids := make([]int64, len(scans))
+	for i, scan := range scans {
+		ids[i] = scan.ID
+	}
+	return scanScope(assetsTable, ids...), nil
+}
+
+// osSource returns the FROM clause of the rows in scope, aliased v, its
+// arguments and the operating system expression of a count by OS. With an
+// assets table, rows without an operating system take the one of their
+// host in the latest asset scans.
+func osSource(ctx context.Context, s *Store, sc scope, assetsTable string) (string, string, []interface{}, error) {
+	from, args := sc.expand(s, "(SELECT * FROM !!) v")
+	os := "v." + s.Quote("asset_operating_system")
+	if assetsTable == "" {
+		return from, os, args, nil
+	}
+
+	if err := ValidateIdentifier(assetsTable); err != nil {
+		return "", "", nil, err
+	}
+	exists, err := s.TableExists(ctx, assetsTable)
+	if err != nil || !exists {
+		return from, os, args, err
+	}
+	if err := s.RequireColumns(ctx, assetsTable, "Host", "asset_operating_system"); err != nil {
+		return "", "", nil, err
+	}
+	latest, err := assetScope(ctx, s, assetsTable, Selector{})
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	assets, assetArgs := latest.expand(s, `(SELECT {Host}, MAX(NULLIF({asset_operating_system}, '')) AS os FROM !! GROUP BY {Host})`)
+	from = fmt.Sprintf("%s LEFT JOIN %s a ON a.%s = v.%s", from, assets, s.Quote("Host"), s.Quote("Host"))
+	os = fmt.Sprintf("COALESCE(NULLIF(%s, ''), a.os)", os)
+	return from, os, append(args, assetArgs...), nil
+}
@@ -0,0 +1,168 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Defaults for the batched inserts.
+const (
+	DefaultBatchSize = 500
+	DefaultTxRows    = 50000
+)
+
+// batchWriter inserts rows with multi-row INSERT statements and commits
+// every txRows rows, so neither memory nor transactions grow with the input.
+type batchWriter struct {
+	s         *Store
+	tableName string
+	columns   []string
+	batchSize int
+	txRows    int
+
+	tx      *sql.Tx
+	stmt    *sql.Stmt
+	pending []interface{}
+	rows    int
+	txCount int
+	written int
+}
+
+// newBatchWriter prepares batched inserts into the named columns of a table.
+// batchSize and txRows fall back to the defaults when not positive.
+func newBatchWriter(s *Store, tableName string, columns []string, batchSize int, txRows int) *batchWriter {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if len(columns) > 0 && batchSize > s.Dialect.MaxParams()/len(columns) {
+		batchSize = s.Dialect.MaxParams() / len(columns)
+	}
+	if txRows <= 0 {
+		txRows = DefaultTxRows
+	}
+	if txRows < batchSize {
+		txRows = batchSize
+	}
+	return &batchWriter{
+		s:         s,
+		tableName: tableName,
+		columns:   columns,
+		batchSize: batchSize,
+		txRows:    txRows,
+		pending:   make([]interface{}, 0, batchSize*len(columns)),
+	}
+}
+
+// Add queues a row and writes the batch once it is full. It reports
+// whether the add ended a transaction.
+func (w *batchWriter) Add(ctx context.Context, values []interface{}) (bool, error) {
+	if len(values) != len(w.columns) {
+		return false, fmt.Errorf("row has %d values for %d columns", len(values), len(w.columns))
+	}
+	w.pending = append(w.pending, values...)
+	w.rows++
+	if w.rows < w.batchSize {
+		return false, nil
+	}
+	if err := w.flush(ctx); err != nil {
+		return false, err
+	}
+	if w.txCount < w.txRows {
+		return false, nil
+	}
+	return true, w.commit()
+}
+
+// Close writes the queued rows and commits the open transaction.
+func (w *batchWriter) Close(ctx context.Context) error {
+	if err := w.flush(ctx); err != nil {
+		return err
+	}
+	return w.commit()
+}
+
+// Abort rolls back the open transaction. Rows of earlier transactions stay.
+func (w *batchWriter) Abort() {
+	if w.stmt != nil {
+		w.stmt.Close()
+		w.stmt = nil
+	}
+	if w.tx != nil {
+		w.tx.Rollback()
+		w.tx = nil
+	}
+}
+
+// Written returns the number of rows committed so far.
+func (w *batchWriter) Written() int {
+	return w.written
+}
+
+func (w *batchWriter) flush(ctx context.Context) error {
+	if w.rows == 0 {
+		return nil
+	}
+	if w.tx == nil {
+		tx, err := w.s.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("unable to start transaction: %w", err)
+		}
+		w.tx = tx
+	}
+
+	var err error
+	if w.rows == w.batchSize {
+		// Full batches share one prepared statement per transaction
+		if w.stmt == nil {
+			w.stmt, err = w.tx.PrepareContext(ctx, w.query(w.batchSize))
+			if err != nil {
+				return fmt.Errorf("unable to prepare insert into %s: %w", w.tableName, err)
+			}
+		}
+		_, err = w.stmt.ExecContext(ctx, w.pending...)
+	} else {
+		_, err = w.tx.ExecContext(ctx, w.query(w.rows), w.pending...)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to insert into %s: %w", w.tableName, err)
+	}
+
+	w.txCount += w.rows
+	w.pending = w.pending[:0]
+	w.rows = 0
+	return nil
+}
+
+func (w *batchWriter) commit() error {
+	if w.tx == nil {
+		return nil
+	}
+	if w.stmt != nil {
+		w.stmt.Close()
+		w.stmt = nil
+	}
+	err := w.tx.Commit()
+	w.tx = nil
+	if err != nil {
+		return fmt.Errorf("unable to commit rows into %s: %w", w.tableName, err)
+	}
+	w.written += w.txCount
+	w.txCount = 0
+	return nil
+}
+
+// query builds a multi-row INSERT for rows rows.
+func (w *batchWriter) query(rows int) string {
+	row := "(" + generatePlaceholders(len(w.columns)) + ")"
+	values := strings.TrimSuffix(strings.Repeat(row+", ", rows), ", ")
+
+	quoted := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		quoted[i] = w.s.Quote(column)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", w.s.Quote(w.tableName), strings.Join(quoted, ", "), values)
+	return w.s.Rebind(query)
+}
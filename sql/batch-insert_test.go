@@ -0,0 +1,91 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestNewBatchWriterSizes(t *testing.T) {
+	for _, tt := range []struct {
+		columns   int
+		batchSize int
+		txRows    int
+		wantBatch int
+		wantTx    int
+	}{
+		{columns: 5, wantBatch: DefaultBatchSize, wantTx: DefaultTxRows},
+		{columns: 5, batchSize: 10, txRows: 100, wantBatch: 10, wantTx: 100},
+		// Transactions hold at least one batch
+		{columns: 5, batchSize: 10, txRows: 3, wantBatch: 10, wantTx: 10},
+		// SQLite takes 32766 bind parameters per statement
+		{columns: 100, wantBatch: 327, wantTx: DefaultTxRows},
+		{columns: 5, batchSize: 10000, txRows: 100, wantBatch: 6553, wantTx: 6553},
+		{columns: 32766, wantBatch: 1, wantTx: DefaultTxRows},
+	} {
+		t.Run(fmt.Sprintf("%d columns batch %d tx %d", tt.columns, tt.batchSize, tt.txRows), func(t *testing.T) {
+			s := &Store{Dialect: sqliteDialect{}}
+			w := newBatchWriter(s, "vulns", make([]string, tt.columns), tt.batchSize, tt.txRows)
+			if w.batchSize != tt.wantBatch || w.txRows != tt.wantTx {
+				t.Errorf("batch %d tx %d, want batch %d tx %d", w.batchSize, w.txRows, tt.wantBatch, tt.wantTx)
+			}
+			if params := w.batchSize * tt.columns; params > s.Dialect.MaxParams() {
+				t.Errorf("a batch binds %d parameters, more than %d", params, s.Dialect.MaxParams())
+			}
+		})
+	}
+}
+
+func TestBatchWriterTransactions(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		rows      int
+		abort     bool
+		commits   string
+		wantTable int
+	}{
+		// Every second batch of two rows ends a transaction
+		{name: "close", rows: 9, commits: "[4 8]", wantTable: 9},
+		{name: "exact", rows: 8, commits: "[4 8]", wantTable: 8},
+		{name: "abort", rows: 9, abort: true, commits: "[4 8]", wantTable: 8},
+		{name: "abort first transaction", rows: 3, abort: true, commits: "[]", wantTable: 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStore(t)
+			mustExec(t, s, `CREATE TABLE "vulns" ("Host" TEXT, "CVSS" REAL)`)
+			ctx := context.Background()
+
+			w := newBatchWriter(s, "vulns", []string{"Host", "CVSS"}, 2, 4)
+			commits := []int{}
+			for i := 1; i <= tt.rows; i++ {
+				committed, err := w.Add(ctx, []interface{}{fmt.Sprintf("host%d", i), 5.0})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if committed {
+					commits = append(commits, w.Written())
+				}
+			}
+			if tt.abort {
+				w.Abort()
+			} else if err := w.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := fmt.Sprint(commits); got != tt.commits {
+				t.Errorf("committed after %s rows, want %s", got, tt.commits)
+			}
+			if got := len(columnValues(t, s, "vulns", "Host")); got != tt.wantTable || got != w.Written() {
+				t.Errorf("table has %d rows and %d are written, want %d", got, w.Written(), tt.wantTable)
+			}
+		})
+	}
+}
+
+func TestBatchWriterRowWidth(t *testing.T) {
+	s := newTestStore(t)
+	w := newBatchWriter(s, "vulns", []string{"Host", "CVSS"}, 2, 4)
+	if _, err := w.Add(context.Background(), []interface{}{"host1"}); err == nil {
+		t.Error("a row of one value for two columns was queued")
+	}
+}
@@ -0,0 +1,179 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ColumnPlan lines the columns of a source file up with the columns of a table.
+type ColumnPlan struct {
+	// Columns holds the table column each used source column is written to.
+	Columns []string
+	// Indexes holds the source position of each entry of Columns.
+	Indexes []int
+	// Unmapped lists the source headers that match no table column.
+	Unmapped []string
+
+	convert   []converter
+	constants map[int]interface{}
+}
+
+// MappingError reports a source file whose columns cannot be loaded safely.
+type MappingError struct {
+	// Missing lists required columns that no source header provides.
+	Missing []string
+	// Unknown lists mapping targets that are not columns of the table.
+	Unknown []string
+	// Duplicate lists table columns that more than one source header maps to.
+	Duplicate []string
+}
+
+func (e *MappingError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing required columns "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unknown) > 0 {
+		parts = append(parts, "mapping targets not in table "+strings.Join(e.Unknown, ", "))
+	}
+	if len(e.Duplicate) > 0 {
+		parts = append(parts, "columns mapped more than once "+strings.Join(e.Duplicate, ", "))
+	}
+	return "invalid column mapping: " + strings.Join(parts, "; ")
+}
+
+// Is makes a *MappingError with missing columns match ErrMissingColumn.
+func (e *MappingError) Is(target error) bool {
+	return target == ErrMissingColumn && len(e.Missing) > 0
+}
+
+// PlanColumns matches a source header to the columns of a table. A header
+// listed in mapping goes to the named table column; any other header goes to
+// the table column with the same name, ignoring case and punctuation. It
+// returns a *MappingError when a required column is not provided, when a
+// mapping names a column the table lacks, or when two headers land on the
+// same column.
+func PlanColumns(header []string, tableColumns []Column, mapping map[string]string, required []string) (*ColumnPlan, error) {
+	byKey := make(map[string]string, len(tableColumns))
+	kinds := make(map[string]ColumnKind, len(tableColumns))
+	for _, c := range tableColumns {
+		byKey[columnKey(c.Name)] = c.Name
+		kinds[c.Name] = kindOfType(c.Type)
+	}
+	mappingByKey := make(map[string]string, len(mapping))
+	for source, target := range mapping {
+		mappingByKey[columnKey(source)] = target
+	}
+
+	plan := &ColumnPlan{}
+	mappingErr := &MappingError{}
+	used := make(map[string]bool)
+	for i, name := range header {
+		var target string
+		if mapped, ok := mappingByKey[columnKey(name)]; ok {
+			if mapped == "" {
+				// An empty target drops the source column on purpose
+				continue
+			}
+			target, ok = byKey[columnKey(mapped)]
+			if !ok {
+				mappingErr.Unknown = append(mappingErr.Unknown, mapped)
+				continue
+			}
+		} else if match, ok := byKey[columnKey(name)]; ok {
+			target = match
+		} else {
+			plan.Unmapped = append(plan.Unmapped, name)
+			continue
+		}
+
+		if used[target] {
+			mappingErr.Duplicate = append(mappingErr.Duplicate, target)
+			continue
+		}
+		used[target] = true
+		plan.Columns = append(plan.Columns, target)
+		plan.Indexes = append(plan.Indexes, i)
+		plan.convert = append(plan.convert, converterFor(kinds[target]))
+	}
+
+	for _, name := range required {
+		if target, ok := byKey[columnKey(name)]; !ok || !used[target] {
+			mappingErr.Missing = append(mappingErr.Missing, name)
+		}
+	}
+
+	if len(mappingErr.Missing) > 0 || len(mappingErr.Unknown) > 0 || len(mappingErr.Duplicate) > 0 {
+		return plan, mappingErr
+	}
+	if len(plan.Columns) == 0 {
+		return plan, fmt.Errorf("no source column matches a table column")
+	}
+	return plan, nil
+}
+
+// Values picks the planned columns out of a source record and converts them
+// to the type of their table column. Empty values become NULL in typed columns.
+func (p *ColumnPlan) Values(record []string) ([]interface{}, error) {
+	values := make([]interface{}, len(p.Indexes))
+	for i, index := range p.Indexes {
+		if constant, ok := p.constants[i]; ok {
+			values[i] = constant
+			continue
+		}
+		value := ""
+		if index < len(record) {
+			value = record[index]
+		}
+		converted, err := p.convert[i](value)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", p.Columns[i], err)
+		}
+		values[i] = converted
+	}
+	return values, nil
+}
+
+// setConstant writes value to column on every row, in place of any source
+// column planned for it.
+func (p *ColumnPlan) setConstant(column string, value interface{}) {
+	for i, planned := range p.Columns {
+		if strings.EqualFold(planned, column) {
+			p.Columns = append(p.Columns[:i], p.Columns[i+1:]...)
+			p.Indexes = append(p.Indexes[:i], p.Indexes[i+1:]...)
+			p.convert = append(p.convert[:i], p.convert[i+1:]...)
+			constants := make(map[int]interface{}, len(p.constants))
+			for at, constant := range p.constants {
+				switch {
+				case at < i:
+					constants[at] = constant
+				case at > i:
+					constants[at-1] = constant
+				}
+			}
+			p.constants = constants
+			break
+		}
+	}
+	if p.constants == nil {
+		p.constants = make(map[int]interface{})
+	}
+	p.constants[len(p.Columns)] = value
+	p.Columns = append(p.Columns, column)
+	p.Indexes = append(p.Indexes, -1)
+	p.convert = append(p.convert, nil)
+}
+
+// columnKey folds a column name so that "Plugin Output", "plugin_output" and
+// "Plugin.Output" compare equal. A leading byte order mark is dropped too.
+func columnKey(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
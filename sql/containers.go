@@ -0,0 +1,115 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AssetContainerImage is the asset_type of findings in container images.
+const AssetContainerImage = "container_image"
+
+// containerColumns are the columns the container image report reads on top
+// of the required ones.
+var containerColumns = []string{"asset_type", "fixed_version"}
+
+// ContainerImage sums up the findings of a container image.
+type ContainerImage struct {
+	Image     string
+	CVSSTotal int
+	Findings  int
+	// Bands counts the findings of the image by severity band, like
+	// VulnBySeverity.
+	Bands []BandCount
+	// Fixable counts the findings that a newer package version fixes.
+	Fixable int
+}
+
+// hasContainerColumns reports whether table holds container image findings,
+// as loaded by the Trivy and Grype importers.
+func hasContainerColumns(ctx context.Context, s *Store, table string) (bool, error) {
+	columns, err := s.Columns(ctx, table)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range containerColumns {
+		if !hasColumn(columns, name) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// containerImages returns the ten container images with the most findings
+// in the highest severity bands, then the highest CVSS total.
+func containerImages(ctx context.Context, s *Store, sc scope, bands SeverityBands) ([]ContainerImage, error) {
+	query := `
+	SELECT {Host}, {CVSS}, COUNT(*),
+	SUM(CASE WHEN COALESCE({fixed_version}, '') <> '' THEN 1 ELSE 0 END)
+	FROM !!
+	WHERE {asset_type} = ?
+	GROUP BY {Host}, {CVSS}
+	`
+	query, args := sc.expand(s, query)
+	rows, err := s.Query(ctx, query, append(args, AssetContainerImage)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Sum up the findings of each image
+	type imageTotals struct {
+		image   ContainerImage
+		total   float64
+		counter *bandCounter
+	}
+	var images []*imageTotals
+	byImage := map[string]*imageTotals{}
+	for rows.Next() {
+		var image, cvss sql.NullString
+		var count, fixable int
+		if err := rows.Scan(&image, &cvss, &count, &fixable); err != nil {
+			return nil, err
+		}
+		totals, ok := byImage[image.String]
+		if !ok {
+			totals = &imageTotals{image: ContainerImage{Image: image.String}, counter: newBandCounter(bands)}
+			byImage[image.String] = totals
+			images = append(images, totals)
+		}
+		totals.counter.add(cvss.String, count)
+		totals.image.Findings += count
+		totals.image.Fixable += fixable
+		if score, err := strconv.ParseFloat(strings.TrimSpace(cvss.String), 64); err == nil {
+			totals.total += score * float64(count)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]ContainerImage, 0, len(images))
+	for _, totals := range images {
+		totals.image.CVSSTotal = int(math.Round(totals.total))
+		totals.image.Bands = totals.counter.counts()
+		results = append(results, totals.image)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		// The bands are counted highest first, and Unscored last
+		a, b := results[i].Bands, results[j].Bands
+		for k := 0; k < len(a)-1; k++ {
+			if a[k].Total != b[k].Total {
+				return a[k].Total > b[k].Total
+			}
+		}
+		return results[i].CVSSTotal > results[j].CVSSTotal
+	})
+	if len(results) > 10 {
+		results = results[:10]
+	}
+	return results, nil
+}
@@ -0,0 +1,63 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Columns the importer fills in from the CVSS vectors of findings, that
+// the reports group by.
+const (
+	ColumnAttackVector = "cvss_attack_vector"
+	ColumnPrivileges   = "cvss_privileges_required"
+)
+
+// VulnByComponent counts the findings with a value of a CVSS vector
+// component, such as the Network attack vector.
+type VulnByComponent struct {
+	Value    string
+	Findings int
+	Hosts    int
+	// MaxCVSS is the highest CVSS score of the findings.
+	MaxCVSS float64
+}
+
+// vulnByComponent groups the findings in scope by the value of column, most
+// findings first. Findings without a vector are left out, and a table
+// without the column gives no groups.
+func vulnByComponent(ctx context.Context, s *Store, sc scope, column string) ([]VulnByComponent, error) {
+	columns, err := s.Columns(ctx, sc.table)
+	if err != nil {
+		return nil, err
+	}
+	if !hasColumn(columns, column) {
+		return nil, nil
+	}
+
+	query := `
+	SELECT {` + column + `}, COUNT(*) AS Findings, COUNT(DISTINCT {Host}) AS Hosts, MAX({CVSS}) AS Max_CVSS
+	FROM !!
+	WHERE COALESCE({` + column + `}, '') <> ''
+	GROUP BY {` + column + `}
+	ORDER BY Findings DESC, {` + column + `}
+	`
+	query, args := sc.expand(s, query)
+	rows, err := s.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []VulnByComponent{}
+	for rows.Next() {
+		var res VulnByComponent
+		var max sql.NullFloat64
+		if err := rows.Scan(&res.Value, &res.Findings, &res.Hosts, &max); err != nil {
+			return nil, err
+		}
+		res.MaxCVSS = max.Float64
+		results = append(results, res)
+	}
+	return results, rows.Err()
+}
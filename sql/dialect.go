@@ -0,0 +1,380 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Column describes a single column of a table.
+type Column struct {
+	Name string
+	Type string
+}
+
+// Dialect hides the SQL differences between the supported databases.
+type Dialect interface {
+	// Name returns the name of the dialect, e.g. "mysql".
+	Name() string
+	// Quote quotes an identifier such as a table or column name.
+	Quote(identifier string) string
+	// Placeholder returns the bind parameter for the n-th argument, starting at 1.
+	Placeholder(n int) string
+	// MaxParams returns the most bind parameters a single statement may use.
+	MaxParams() int
+	// Truncate returns the statement that removes every row of a table.
+	Truncate(table string) string
+	// Concat returns the expression that joins the strings of expressions.
+	Concat(expressions ...string) string
+	// Columns returns the columns of a table in their declared order.
+	Columns(ctx context.Context, db *sql.DB, table string) ([]Column, error)
+	// TableExists reports whether a table exists.
+	TableExists(ctx context.Context, db *sql.DB, table string) (bool, error)
+	// ColumnType returns the SQL type used to store a column kind.
+	ColumnType(kind ColumnKind) string
+	// IndexColumn returns the index expression for a column of the given kind.
+	IndexColumn(column string, kind ColumnKind) string
+	// CreateLike creates an empty table with the columns of an existing one.
+	CreateLike(ctx context.Context, db *sql.DB, table string, like string) error
+	// Swap atomically replaces table with staging, dropping the old rows.
+	Swap(ctx context.Context, db *sql.DB, table string, staging string) error
+}
+
+// DialectFor returns the dialect registered under name.
+func DialectFor(name string) (Dialect, error) {
+	switch strings.ToLower(name) {
+	case "mysql":
+		return mysqlDialect{}, nil
+	case "sqlite", "sqlite3":
+		return sqliteDialect{}, nil
+	case "postgres", "postgresql", "pgsql":
+		return postgresDialect{}, nil
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", name)
+}
+
+// swapName returns a name for the old rows of table while Swap replaces
+// them, unlikely to be taken by a table of the user.
+func swapName(table string) (string, error) {
+	var random [4]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		return "", fmt.Errorf("unable to name the old rows of %s: %w", table, err)
+	}
+	return shortenIdentifier(table, fmt.Sprintf("_old_%x", random)), nil
+}
+
+// quoteWith wraps identifier in quote, doubling any embedded quote character.
+func quoteWith(identifier string, quote string) string {
+	return quote + strings.ReplaceAll(identifier, quote, quote+quote) + quote
+}
+
+// scanColumns reads (name, type) rows into a column list.
+func scanColumns(rows *sql.Rows) ([]Column, error) {
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var c Column
+		if err := rows.Scan(&c.Name, &c.Type); err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) Quote(identifier string) string { return quoteWith(identifier, "`") }
+
+func (mysqlDialect) Placeholder(n int) string { return "?" }
+
+func (mysqlDialect) MaxParams() int { return 65535 }
+
+func (d mysqlDialect) Truncate(table string) string {
+	return "TRUNCATE TABLE " + d.Quote(table)
+}
+
+// MySQL reads || as OR unless PIPES_AS_CONCAT is set.
+func (mysqlDialect) Concat(expressions ...string) string {
+	return "CONCAT(" + strings.Join(expressions, ", ") + ")"
+}
+
+func (mysqlDialect) Columns(ctx context.Context, db *sql.DB, table string) ([]Column, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT column_name, column_type
+		FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ?
+		ORDER BY ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	return scanColumns(rows)
+}
+
+func (mysqlDialect) TableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_name = ?`, table).Scan(&count)
+	return count > 0, err
+}
+
+// IP addresses are kept as text in every dialect so that they compare
+// directly with host columns of other tables.
+func (mysqlDialect) ColumnType(kind ColumnKind) string {
+	switch kind {
+	case KindInteger:
+		return "BIGINT"
+	case KindReal:
+		return "DOUBLE"
+	case KindBoolean:
+		return "BOOLEAN"
+	case KindDate:
+		return "DATE"
+	case KindTimestamp:
+		return "DATETIME"
+	}
+	return "TEXT"
+}
+
+// MySQL can only index a prefix of a TEXT column.
+func (d mysqlDialect) IndexColumn(column string, kind ColumnKind) string {
+	if kind == KindText || kind == KindIP {
+		return d.Quote(column) + "(255)"
+	}
+	return d.Quote(column)
+}
+
+func (d mysqlDialect) CreateLike(ctx context.Context, db *sql.DB, table string, like string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s LIKE %s", d.Quote(table), d.Quote(like)))
+	return err
+}
+
+// RENAME TABLE swaps both names in one atomic step; DDL cannot run in a
+// MySQL transaction. Once it succeeds the new rows are in place, so failing
+// to drop the old ones is only worth a warning.
+func (d mysqlDialect) Swap(ctx context.Context, db *sql.DB, table string, staging string) error {
+	old, err := swapName(table)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("RENAME TABLE %s TO %s, %s TO %s",
+		d.Quote(table), d.Quote(old), d.Quote(staging), d.Quote(table)))
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, "DROP TABLE "+d.Quote(old)); err != nil {
+		log.Printf("warning: %s was replaced but its old rows remain in %s: %v", table, old, err)
+	}
+	return nil
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) Quote(identifier string) string { return quoteWith(identifier, `"`) }
+
+func (sqliteDialect) Placeholder(n int) string { return "?" }
+
+// SQLite has allowed 32766 variables since 3.32; older builds stop at 999.
+func (sqliteDialect) MaxParams() int { return 32766 }
+
+// SQLite has no TRUNCATE; an unqualified DELETE uses the truncate optimization.
+func (d sqliteDialect) Truncate(table string) string {
+	return "DELETE FROM " + d.Quote(table)
+}
+
+func (sqliteDialect) Concat(expressions ...string) string {
+	return "(" + strings.Join(expressions, " || ") + ")"
+}
+
+func (sqliteDialect) Columns(ctx context.Context, db *sql.DB, table string) ([]Column, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name, type FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	return scanColumns(rows)
+}
+
+func (sqliteDialect) TableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count)
+	return count > 0, err
+}
+
+func (sqliteDialect) ColumnType(kind ColumnKind) string {
+	switch kind {
+	case KindInteger:
+		return "INTEGER"
+	case KindReal:
+		return "REAL"
+	case KindBoolean:
+		return "BOOLEAN"
+	case KindDate:
+		return "DATE"
+	case KindTimestamp:
+		return "TIMESTAMP"
+	}
+	return "TEXT"
+}
+
+func (d sqliteDialect) IndexColumn(column string, kind ColumnKind) string {
+	return d.Quote(column)
+}
+
+func (d sqliteDialect) CreateLike(ctx context.Context, db *sql.DB, table string, like string) error {
+	columns, err := d.Columns(ctx, db, like)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return &TableError{Table: like}
+	}
+	definitions := make([]string, len(columns))
+	for i, c := range columns {
+		definitions[i] = strings.TrimSpace(d.Quote(c.Name) + " " + c.Type)
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (%s)", d.Quote(table), strings.Join(definitions, ", ")))
+	return err
+}
+
+// Swap renames the tables inside a transaction. The indexes of the old table
+// are dropped with it and recreated on the new one from their saved SQL.
+func (d sqliteDialect) Swap(ctx context.Context, db *sql.DB, table string, staging string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", table)
+	if err != nil {
+		return err
+	}
+	var indexes []string
+	for rows.Next() {
+		var index string
+		if err := rows.Scan(&index); err != nil {
+			rows.Close()
+			return err
+		}
+		indexes = append(indexes, index)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	old, err := swapName(table)
+	if err != nil {
+		return err
+	}
+	statements := []string{
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", d.Quote(table), d.Quote(old)),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", d.Quote(staging), d.Quote(table)),
+		"DROP TABLE " + d.Quote(old),
+	}
+	for _, statement := range append(statements, indexes...) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) Quote(identifier string) string { return quoteWith(identifier, `"`) }
+
+func (postgresDialect) Placeholder(n int) string { return fmt.Sprintf("$%d", n) }
+
+func (postgresDialect) MaxParams() int { return 65535 }
+
+func (d postgresDialect) Truncate(table string) string {
+	return "TRUNCATE TABLE " + d.Quote(table)
+}
+
+func (postgresDialect) Concat(expressions ...string) string {
+	return "(" + strings.Join(expressions, " || ") + ")"
+}
+
+func (postgresDialect) Columns(ctx context.Context, db *sql.DB, table string) ([]Column, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT column_name, data_type
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1
+		ORDER BY ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	return scanColumns(rows)
+}
+
+func (postgresDialect) TableExists(ctx context.Context, db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_name = $1`, table).Scan(&count)
+	return count > 0, err
+}
+
+func (postgresDialect) ColumnType(kind ColumnKind) string {
+	switch kind {
+	case KindInteger:
+		return "BIGINT"
+	case KindReal:
+		return "DOUBLE PRECISION"
+	case KindBoolean:
+		return "BOOLEAN"
+	case KindDate:
+		return "DATE"
+	case KindTimestamp:
+		return "TIMESTAMP"
+	}
+	return "TEXT"
+}
+
+func (d postgresDialect) IndexColumn(column string, kind ColumnKind) string {
+	return d.Quote(column)
+}
+
+func (d postgresDialect) CreateLike(ctx context.Context, db *sql.DB, table string, like string) error {
+	_, err := db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING ALL)", d.Quote(table), d.Quote(like)))
+	return err
+}
+
+// Swap renames the tables inside a transaction; readers keep seeing the old
+// table until it commits.
+func (d postgresDialect) Swap(ctx context.Context, db *sql.DB, table string, staging string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	old, err := swapName(table)
+	if err != nil {
+		return err
+	}
+	statements := []string{
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", d.Quote(table), d.Quote(old)),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", d.Quote(staging), d.Quote(table)),
+		"DROP TABLE " + d.Quote(old),
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,196 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// recorder is a database/sql driver that records the statements it runs
+// instead of running them, failing those that start with fail.
+type recorder struct {
+	statements []string
+	fail       string
+}
+
+var swapNamePattern = regexp.MustCompile(`_old_[0-9a-f]{8}`)
+
+func (r *recorder) record(statement string) error {
+	r.statements = append(r.statements, swapNamePattern.ReplaceAllString(statement, "_old_*"))
+	if r.fail != "" && strings.HasPrefix(statement, r.fail) {
+		return errors.New("recorder: " + r.fail + " failed")
+	}
+	return nil
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return r, nil }
+func (r *recorder) Driver() driver.Driver                        { return nil }
+func (r *recorder) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("recorder: prepared statements are not supported")
+}
+func (r *recorder) Close() error                 { return nil }
+func (r *recorder) Begin() (driver.Tx, error)    { return r, r.record("BEGIN") }
+func (r *recorder) Commit() error                { return r.record("COMMIT") }
+func (r *recorder) Rollback() error              { return r.record("ROLLBACK") }
+func (r *recorder) RowsAffected() (int64, error) { return 0, nil }
+func (r *recorder) LastInsertId() (int64, error) { return 0, nil }
+
+func (r *recorder) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return r, r.record(query)
+}
+
+func TestSwapStatements(t *testing.T) {
+	for _, tt := range []struct {
+		dialect Dialect
+		fail    string
+		want    []string
+		wantErr bool
+		warning bool
+	}{
+		{
+			dialect: mysqlDialect{},
+			want: []string{
+				"RENAME TABLE `vulns` TO `vulns_old_*`, `vulns_staging` TO `vulns`",
+				"DROP TABLE `vulns_old_*`",
+			},
+		},
+		{
+			dialect: mysqlDialect{},
+			fail:    "RENAME",
+			want:    []string{"RENAME TABLE `vulns` TO `vulns_old_*`, `vulns_staging` TO `vulns`"},
+			wantErr: true,
+		},
+		{
+			// The new rows are in place once RENAME succeeds
+			dialect: mysqlDialect{},
+			fail:    "DROP",
+			want: []string{
+				"RENAME TABLE `vulns` TO `vulns_old_*`, `vulns_staging` TO `vulns`",
+				"DROP TABLE `vulns_old_*`",
+			},
+			warning: true,
+		},
+		{
+			dialect: postgresDialect{},
+			want: []string{
+				"BEGIN",
+				`ALTER TABLE "vulns" RENAME TO "vulns_old_*"`,
+				`ALTER TABLE "vulns_staging" RENAME TO "vulns"`,
+				`DROP TABLE "vulns_old_*"`,
+				"COMMIT",
+			},
+		},
+		{
+			dialect: postgresDialect{},
+			fail:    "DROP",
+			want: []string{
+				"BEGIN",
+				`ALTER TABLE "vulns" RENAME TO "vulns_old_*"`,
+				`ALTER TABLE "vulns_staging" RENAME TO "vulns"`,
+				`DROP TABLE "vulns_old_*"`,
+				"ROLLBACK",
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(fmt.Sprintf("%s fail %q", tt.dialect.Name(), tt.fail), func(t *testing.T) {
+			r := &recorder{fail: tt.fail}
+			db := sql.OpenDB(r)
+			defer db.Close()
+			var logged bytes.Buffer
+			defer log.SetOutput(log.Writer())
+			log.SetOutput(&logged)
+
+			err := tt.dialect.Swap(context.Background(), db, "vulns", "vulns_staging")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Swap = %v, want error %v", err, tt.wantErr)
+			}
+			if got, want := strings.Join(r.statements, "\n"), strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("statements:\n%s\nwant:\n%s", got, want)
+			}
+			if warned := strings.Contains(logged.String(), "warning:"); warned != tt.warning {
+				t.Errorf("logged %q, want a warning %v", logged.String(), tt.warning)
+			}
+		})
+	}
+}
+
+func TestSwapSQLite(t *testing.T) {
+	s := newTestStore(t)
+	mustExec(t, s, `CREATE TABLE "vulns" ("Host" TEXT, "CVSS" REAL)`)
+	mustExec(t, s, `CREATE INDEX "vulns_host" ON "vulns" ("Host")`)
+	mustExec(t, s, `INSERT INTO "vulns" VALUES ('old1', 5.0)`)
+	ctx := context.Background()
+	if err := s.Dialect.CreateLike(ctx, s.DB, "vulns_staging", "vulns"); err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, s, `INSERT INTO "vulns_staging" VALUES ('new1', 9.8), ('new2', 4.3)`)
+
+	if err := s.Dialect.Swap(ctx, s.DB, "vulns", "vulns_staging"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(columnValues(t, s, "vulns", "Host")); got != "[new1 new2]" {
+		t.Errorf("rows after the swap = %s, want [new1 new2]", got)
+	}
+	var tables, index string
+	err := s.QueryRow(ctx, "SELECT group_concat(name, ' ') FROM sqlite_master WHERE type = 'table'").Scan(&tables)
+	if err != nil || tables != "vulns" {
+		t.Errorf("tables after the swap = %q, %v, want vulns", tables, err)
+	}
+	err = s.QueryRow(ctx, "SELECT tbl_name FROM sqlite_master WHERE type = 'index' AND name = 'vulns_host'").Scan(&index)
+	if err != nil || index != "vulns" {
+		t.Errorf("index vulns_host is on %q, %v, want vulns", index, err)
+	}
+}
+
+func TestCreateLike(t *testing.T) {
+	s := newTestStore(t)
+	mustExec(t, s, `CREATE TABLE "vulns" ("Host" TEXT, "CVSS" REAL, "Seen" TIMESTAMP, "Count" INTEGER)`)
+	mustExec(t, s, `INSERT INTO "vulns" VALUES ('old1', 5.0, '2024-01-02 03:04:05', 1)`)
+	ctx := context.Background()
+
+	if err := s.Dialect.CreateLike(ctx, s.DB, "vulns_staging", "vulns"); err != nil {
+		t.Fatal(err)
+	}
+	want, err := s.Columns(ctx, "vulns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Columns(ctx, "vulns_staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("columns = %v, want %v", got, want)
+	}
+	if rows := columnValues(t, s, "vulns_staging", "Host"); len(rows) != 0 {
+		t.Errorf("the copy has rows %v", rows)
+	}
+
+	var tableErr *TableError
+	if err := s.Dialect.CreateLike(ctx, s.DB, "copy", "missing"); !errors.As(err, &tableErr) {
+		t.Errorf("CreateLike of a missing table = %v, want a *TableError", err)
+	}
+}
+
+func TestConcat(t *testing.T) {
+	for _, tt := range []struct {
+		dialect Dialect
+		want    string
+	}{
+		{mysqlDialect{}, "CONCAT(',', `CVE`, ',')"},
+		{sqliteDialect{}, `(',' || "CVE" || ',')`},
+		{postgresDialect{}, `(',' || "CVE" || ',')`},
+	} {
+		if got := tt.dialect.Concat("','", tt.dialect.Quote("CVE"), "','"); got != tt.want {
+			t.Errorf("%s Concat = %s, want %s", tt.dialect.Name(), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// epssColumns are the columns of an EPSS table, as the epss import creates it.
+var epssColumns = []string{"CVE", "epss", "percentile"}
+
+// epssBatch is the number of CVEs looked up per EPSS query.
+const epssBatch = 500
+
+// LikelyExploitedVuln is a CVE of the findings with its EPSS score.
+type LikelyExploitedVuln struct {
+	CVE      string
+	VulnName string
+	// EPSS is the probability that the CVE is exploited in the next 30
+	// days, and Percentile the share of CVEs scored lower.
+	EPSS       float64
+	Percentile float64
+	// CVSS is the highest CVSS score of the findings.
+	CVSS     float64
+	Hosts    int
+	Findings int
+}
+
+// LikelyExploitedHost is a host with the CVE most likely to be exploited
+// of its findings.
+type LikelyExploitedHost struct {
+	Host    string
+	MaxEPSS float64
+	CVE     string
+	// CVEs counts the CVEs of the host that EPSS scores.
+	CVEs int
+}
+
+// likelyExploited ranks the CVEs and the hosts of the findings in scope by
+// the EPSS scores of the latest scans of epssTable, ten of each, and returns
+// the day of the scores when the table records it. CVE lists are split.
+// Nothing is ranked when the EPSS table does not exist.
+func likelyExploited(ctx context.Context, s *Store, sc scope, epssTable string) ([]LikelyExploitedVuln, []LikelyExploitedHost, string, error) {
+	if err := ValidateIdentifier(epssTable); err != nil {
+		return nil, nil, "", err
+	}
+	exists, err := s.TableExists(ctx, epssTable)
+	if err != nil || !exists {
+		return nil, nil, "", err
+	}
+	if err := s.RequireColumns(ctx, epssTable, epssColumns...); err != nil {
+		return nil, nil, "", err
+	}
+	scores, err := assetScope(ctx, s, epssTable, Selector{})
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	// Gather the CVEs of each host
+	query, args := sc.expand(s, `
+	SELECT {Host}, {CVE}, MIN({Name}), MAX({CVSS}), COUNT(*)
+	FROM !!
+	WHERE COALESCE({CVE}, '') <> ''
+	GROUP BY {Host}, {CVE}
+	`)
+	rows, err := s.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	defer rows.Close()
+
+	vulns := map[string]*LikelyExploitedVuln{}
+	hostCVEs := map[string]map[string]bool{}
+	for rows.Next() {
+		var host, cves, name, cvss sql.NullString
+		var findings int
+		if err := rows.Scan(&host, &cves, &name, &cvss, &findings); err != nil {
+			return nil, nil, "", err
+		}
+		score, _ := strconv.ParseFloat(strings.TrimSpace(cvss.String), 64)
+		if hostCVEs[host.String] == nil {
+			hostCVEs[host.String] = map[string]bool{}
+		}
+		for _, cve := range splitCVEs(cves.String) {
+			vuln, ok := vulns[cve]
+			if !ok {
+				vuln = &LikelyExploitedVuln{CVE: cve, VulnName: name.String}
+				vulns[cve] = vuln
+			}
+			if !hostCVEs[host.String][cve] {
+				hostCVEs[host.String][cve] = true
+				vuln.Hosts++
+			}
+			vuln.Findings += findings
+			if score > vuln.CVSS {
+				vuln.CVSS = score
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, "", err
+	}
+
+	// Look up their scores
+	cves := make([]string, 0, len(vulns))
+	for cve := range vulns {
+		cves = append(cves, cve)
+	}
+	sort.Strings(cves)
+	scored := map[string]bool{}
+	for start := 0; start < len(cves); start += epssBatch {
+		batch := cves[start:minInt(start+epssBatch, len(cves))]
+		query, args := scores.expand(s, "SELECT {CVE}, {epss}, {percentile} FROM !! WHERE {CVE} IN ("+generatePlaceholders(len(batch))+")")
+		for _, cve := range batch {
+			args = append(args, cve)
+		}
+		if err := scanEPSS(ctx, s, query, args, vulns, scored); err != nil {
+			return nil, nil, "", err
+		}
+	}
+
+	var ranked []LikelyExploitedVuln
+	for _, cve := range cves {
+		if scored[cve] {
+			ranked = append(ranked, *vulns[cve])
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].EPSS != ranked[j].EPSS {
+			return ranked[i].EPSS > ranked[j].EPSS
+		}
+		return ranked[i].CVSS > ranked[j].CVSS
+	})
+
+	var hosts []LikelyExploitedHost
+	for host, cves := range hostCVEs {
+		ranking := LikelyExploitedHost{Host: host}
+		for cve := range cves {
+			if !scored[cve] {
+				continue
+			}
+			ranking.CVEs++
+			epss := vulns[cve].EPSS
+			if ranking.CVE == "" || epss > ranking.MaxEPSS || epss == ranking.MaxEPSS && cve < ranking.CVE {
+				ranking.MaxEPSS, ranking.CVE = epss, cve
+			}
+		}
+		if ranking.CVEs > 0 {
+			hosts = append(hosts, ranking)
+		}
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].MaxEPSS != hosts[j].MaxEPSS {
+			return hosts[i].MaxEPSS > hosts[j].MaxEPSS
+		}
+		if hosts[i].CVEs != hosts[j].CVEs {
+			return hosts[i].CVEs > hosts[j].CVEs
+		}
+		return hosts[i].Host < hosts[j].Host
+	})
+
+	date, err := epssDate(ctx, s, scores)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return ranked[:minInt(10, len(ranked))], hosts[:minInt(10, len(hosts))], date, nil
+}
+
+// scanEPSS sets the scores of vulns that query returns, and marks them
+// scored.
+func scanEPSS(ctx context.Context, s *Store, query string, args []interface{}, vulns map[string]*LikelyExploitedVuln, scored map[string]bool) error {
+	rows, err := s.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cve, epss, percentile sql.NullString
+		if err := rows.Scan(&cve, &epss, &percentile); err != nil {
+			return err
+		}
+		vuln, ok := vulns[cve.String]
+		score, err := strconv.ParseFloat(strings.TrimSpace(epss.String), 64)
+		if !ok || err != nil {
+			continue
+		}
+		vuln.EPSS = score
+		vuln.Percentile, _ = strconv.ParseFloat(strings.TrimSpace(percentile.String), 64)
+		scored[cve.String] = true
+	}
+	return rows.Err()
+}
+
+// epssDate returns the latest score date of the EPSS scores in scope, or
+// an empty string when the table does not record it.
+func epssDate(ctx context.Context, s *Store, scores scope) (string, error) {
+	columns, err := s.Columns(ctx, scores.table)
+	if err != nil || !hasColumn(columns, "score_date") {
+		return "", err
+	}
+	var date sql.NullString
+	query, args := scores.expand(s, `SELECT MAX({score_date}) FROM !!`)
+	if err := s.QueryRow(ctx, query, args...).Scan(&date); err != nil {
+		return "", err
+	}
+	return date.String, nil
+}
+
+// cveSeparators separate the identifiers of a CVE column value listing
+// several.
+const cveSeparators = ",; \t\n"
+
+// splitCVEs returns the CVE identifiers of a CVE column value, which may
+// list several, in upper case.
+func splitCVEs(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return strings.ContainsRune(cveSeparators, r)
+	})
+	cves := make([]string, 0, len(fields))
+	for _, field := range fields {
+		cves = append(cves, strings.ToUpper(field))
+	}
+	return cves
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,81 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors, also matched by the typed errors below through errors.Is.
+var (
+	ErrMissingTable      = errors.New("missing table")
+	ErrMissingColumn     = errors.New("missing column")
+	ErrInvalidCVSS       = errors.New("invalid CVSS value")
+	ErrNoMatch           = errors.New("no matching row")
+	ErrEmptyImport       = errors.New("source has no data rows")
+	ErrInvalidIdentifier = errors.New("invalid identifier")
+	ErrNoScan            = errors.New("no matching scan")
+)
+
+// TableError reports a table that does not exist.
+type TableError struct {
+	Table string
+}
+
+func (e *TableError) Error() string {
+	return fmt.Sprintf("table %s does not exist", e.Table)
+}
+
+// Is makes a *TableError match ErrMissingTable.
+func (e *TableError) Is(target error) bool { return target == ErrMissingTable }
+
+// ColumnError reports a column that a table lacks.
+type ColumnError struct {
+	Table  string
+	Column string
+}
+
+func (e *ColumnError) Error() string {
+	return fmt.Sprintf("table %s has no column %s", e.Table, e.Column)
+}
+
+// Is makes a *ColumnError match ErrMissingColumn.
+func (e *ColumnError) Is(target error) bool { return target == ErrMissingColumn }
+
+// CVSSError reports a CVSS score that is not a number between 0 and 10.
+type CVSSError struct {
+	Table string
+	Value string
+}
+
+func (e *CVSSError) Error() string {
+	return fmt.Sprintf("table %s has invalid CVSS value %q", e.Table, e.Value)
+}
+
+// Is makes a *CVSSError match ErrInvalidCVSS.
+func (e *CVSSError) Is(target error) bool { return target == ErrInvalidCVSS }
+
+// QueryError reports a failed report query.
+type QueryError struct {
+	Query string
+	Err   error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s query failed: %v", e.Query, e.Err)
+}
+
+func (e *QueryError) Unwrap() error { return e.Err }
+
+// IdentifierError reports a table or column name that cannot be quoted safely.
+type IdentifierError struct {
+	Identifier string
+	Reason     string
+}
+
+func (e *IdentifierError) Error() string {
+	return fmt.Sprintf("identifier %q %s", e.Identifier, e.Reason)
+}
+
+// Is makes an *IdentifierError match ErrInvalidIdentifier.
+func (e *IdentifierError) Is(target error) bool { return target == ErrInvalidIdentifier }
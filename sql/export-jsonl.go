@@ -0,0 +1,142 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportJSONL writes the rows of the scans of tableName that sel picks to w
+// as JSON Lines: one object per row, keyed by column name in column order.
+// Integer, real and boolean columns give numbers and booleans, date and
+// timestamp columns ISO 8601 strings, and NULL gives null. A table without
+// scans is written whole when sel picks the latest scan. It returns the
+// number of rows written.
+func ExportJSONL(ctx context.Context, s *Store, tableName string, sel Selector, w io.Writer) (int, error) {
+	if err := ValidateIdentifier(tableName); err != nil {
+		return 0, err
+	}
+	columns, err := s.Columns(ctx, tableName)
+	if err != nil {
+		return 0, fmt.Errorf("unable to list columns of %s: %w", tableName, err)
+	}
+	if len(columns) == 0 {
+		return 0, &TableError{Table: tableName}
+	}
+	sc, _, err := selectScans(ctx, s, tableName, sel)
+	if err != nil {
+		return 0, err
+	}
+
+	names := make([]string, len(columns))
+	keys := make([][]byte, len(columns))
+	kinds := make([]ColumnKind, len(columns))
+	for i, c := range columns {
+		names[i] = s.Quote(c.Name)
+		keys[i], _ = json.Marshal(c.Name)
+		kinds[i] = kindOfType(c.Type)
+	}
+	query, args := sc.expand(s, "SELECT "+strings.Join(names, ", ")+" FROM !!")
+	if hasColumn(columns, ScanColumn) {
+		query += " ORDER BY " + s.Quote(ScanColumn)
+	}
+	rows, err := s.Query(ctx, query, args...)
+	if err != nil {
+		return 0, &QueryError{Query: "JSON Lines export", Err: err}
+	}
+	defer rows.Close()
+
+	out := bufio.NewWriter(w)
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	written := 0
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return written, fmt.Errorf("unable to read row %d of %s: %w", written+1, tableName, err)
+		}
+		out.WriteByte('{')
+		for i, value := range values {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			out.Write(keys[i])
+			out.WriteByte(':')
+			data, err := json.Marshal(typedValue(value, kinds[i]))
+			if err != nil {
+				return written, fmt.Errorf("column %s: %w", columns[i].Name, err)
+			}
+			out.Write(data)
+		}
+		out.WriteString("}\n")
+		written++
+	}
+	if err := rows.Err(); err != nil {
+		return written, &QueryError{Query: "JSON Lines export", Err: err}
+	}
+	return written, out.Flush()
+}
+
+// typedValue turns a value read from a column into the JSON value of the
+// column kind. Values that do not fit the kind stay strings.
+func typedValue(value interface{}, kind ColumnKind) interface{} {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	case time.Time:
+		if kind == KindDate {
+			return v.Format("2006-01-02")
+		}
+		return v.UTC().Format(time.RFC3339)
+	case int64:
+		if kind == KindBoolean {
+			return v != 0
+		}
+		return v
+	default:
+		return v
+	}
+
+	if text == "" && kind != KindText && kind != KindIP {
+		return nil
+	}
+	switch kind {
+	case KindInteger:
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return n
+		}
+	case KindReal:
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return json.Number(strconv.FormatFloat(f, 'f', -1, 64))
+		}
+	case KindBoolean:
+		if b, err := strconv.ParseBool(text); err == nil {
+			return b
+		}
+	case KindDate:
+		if t, ok := parseLayouts(text, dateLayouts); ok {
+			return t.Format("2006-01-02")
+		}
+		if t, ok := parseLayouts(text, timestampLayouts); ok {
+			return t.Format("2006-01-02")
+		}
+	case KindTimestamp:
+		if t, ok := parseLayouts(text, timestampLayouts); ok {
+			return t.UTC().Format(time.RFC3339)
+		}
+	}
+	return text
+}
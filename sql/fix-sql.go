@@ -2,61 +2,57 @@
 package sql
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-
-	//"log"
 	"strings"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
-// Fix Sql null values in the database
-func FixSql(dbPath string) error {
-	// Open the database connection
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return fmt.Errorf("unable to connect to database: %w", err)
+// Fix Sql null values in the rows of the scans of the table that sel picks
+func FixSql(ctx context.Context, s *Store, tableName string, sel Selector) error {
+	if err := ValidateIdentifier(tableName); err != nil {
+		return err
 	}
-	defer db.Close()
-
-	// Get the list of columns in the PopularBank table
-	columnsQuery := "PRAGMA table_info(`PopularBank`)"
-	rows, err := db.Query(columnsQuery)
+	// Get the list of columns in the table
+	columns, err := s.Columns(ctx, tableName)
 	if err != nil {
-		return fmt.Errorf("unable to execute query: %w", err)
+		return fmt.Errorf("unable to list columns: %w", err)
+	}
+	if len(columns) == 0 {
+		return &TableError{Table: tableName}
 	}
-	defer rows.Close()
-
-	var columnNames []string
-	for rows.Next() {
-		var cid int
-		var name string
-		var dataType string
-		var notNull int
-		var defaultValue interface{}
-		var primaryKey int
 
-		err := rows.Scan(&cid, &name, &dataType, &notNull, &defaultValue, &primaryKey)
-		if err != nil {
-			return fmt.Errorf("unable to scan row: %w", err)
+	// Update each row and replace null values in text columns with empty strings
+	var updateColumns []string
+	for _, column := range columns {
+		if !isTextType(column.Type) {
+			continue
 		}
-
-		columnNames = append(columnNames, name)
+		colName := s.Quote(column.Name)
+		updateColumns = append(updateColumns, fmt.Sprintf("%s = COALESCE(%s, '')", colName, colName))
 	}
-
-	// Update each row and replace null values with empty strings
-	updateColumns := make([]string, len(columnNames))
-	for i, colName := range columnNames {
-		updateColumns[i] = fmt.Sprintf("`%s` = COALESCE(`%s`, '')", colName, colName)
+	if len(updateColumns) == 0 {
+		return fmt.Errorf("table %s has no text columns", tableName)
+	}
+	sc, _, err := selectScans(ctx, s, tableName, sel)
+	if err != nil {
+		return err
+	}
+	updateQuery := fmt.Sprintf("UPDATE %s SET %s", s.Quote(tableName), strings.Join(updateColumns, ", "))
+	if sc.where != "" {
+		updateQuery += " WHERE " + sc.condition(s)
 	}
-	updateQuery := fmt.Sprintf("UPDATE `PopularBank` SET %s", strings.Join(updateColumns, ", "))
 
-	_, err = db.Exec(updateQuery)
+	_, err = s.Exec(ctx, updateQuery, sc.args...)
 	if err != nil {
 		return fmt.Errorf("unable to execute update query: %w", err)
 	}
 
-	fmt.Println("Update operation completed successfully.")
 	return nil
 }
+
+// isTextType reports whether a declared column type holds strings. Columns
+// without a declared type are treated as text, as SQLite does.
+func isTextType(columnType string) bool {
+	t := strings.ToUpper(columnType)
+	return t == "" || strings.Contains(t, "CHAR") || strings.Contains(t, "TEXT") || strings.Contains(t, "CLOB")
+}
@@ -0,0 +1,54 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxIdentifierLength is the longest table or column name accepted, in bytes.
+// PostgreSQL silently truncates longer names and MySQL rejects them.
+const MaxIdentifierLength = 63
+
+// ValidateIdentifier checks that name can be used as a table or column name.
+// Quote characters are allowed since the dialects escape them; empty names,
+// invalid UTF-8, control characters and overlong names are not.
+func ValidateIdentifier(name string) error {
+	switch {
+	case name == "":
+		return &IdentifierError{Identifier: name, Reason: "is empty"}
+	case len(name) > MaxIdentifierLength:
+		return &IdentifierError{Identifier: name, Reason: "is too long"}
+	case !utf8.ValidString(name):
+		return &IdentifierError{Identifier: name, Reason: "is not valid UTF-8"}
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return &IdentifierError{Identifier: name, Reason: "contains a control character"}
+		}
+	}
+	return nil
+}
+
+// validateIdentifiers returns the error of the first invalid name.
+func validateIdentifiers(names ...string) error {
+	for _, name := range names {
+		if err := ValidateIdentifier(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shortenIdentifier appends suffix to name, first cutting name short at a
+// character boundary when the result would be too long for an identifier.
+func shortenIdentifier(name string, suffix string) string {
+	cut := len(name)
+	if cut > MaxIdentifierLength-len(suffix) {
+		cut = MaxIdentifierLength - len(suffix)
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+	}
+	return name[:cut] + suffix
+}
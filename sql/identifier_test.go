@@ -0,0 +1,94 @@
+package sql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// identifierSeeds are names that have broken quoting in SQL tools before.
+var identifierSeeds = []string{
+	"vulns",
+	"Vulnerabilities 2023",
+	`a"b`,
+	"a`b",
+	`"; DROP TABLE vulns; --`,
+	"`; DROP TABLE vulns; --",
+	"Robert'); DROP TABLE students;--",
+	`""`,
+	"``",
+	"!!",
+	"{Host}",
+	"%s%v",
+	"$1",
+	"?",
+	"名前",
+	"",
+	"a\x00b",
+	"a\nb",
+	"\xff",
+	strings.Repeat("x", MaxIdentifierLength),
+	strings.Repeat("x", MaxIdentifierLength+1),
+}
+
+func FuzzValidateIdentifier(f *testing.F) {
+	for _, seed := range identifierSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		err := ValidateIdentifier(name)
+		valid := name != "" && len(name) <= MaxIdentifierLength && utf8.ValidString(name) &&
+			strings.IndexFunc(name, unicode.IsControl) < 0
+		if valid != (err == nil) {
+			t.Fatalf("ValidateIdentifier(%q) = %v, want valid %v", name, err, valid)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidIdentifier) {
+			t.Fatalf("ValidateIdentifier(%q) = %v, not an ErrInvalidIdentifier", name, err)
+		}
+	})
+}
+
+func FuzzQuote(f *testing.F) {
+	for _, seed := range identifierSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		for _, driver := range []string{"mysql", "sqlite", "postgres"} {
+			dialect, err := DialectFor(driver)
+			if err != nil {
+				t.Fatal(err)
+			}
+			quoted := dialect.Quote(name)
+			unquoted, ok := unquote(quoted)
+			if !ok || unquoted != name {
+				t.Fatalf("%s: Quote(%q) = %s, which does not read back as the name", driver, name, quoted)
+			}
+		}
+	})
+}
+
+// unquote reads a quoted identifier back the way the databases do: a quote
+// character opens and closes it, and a doubled one inside stands for itself.
+// It fails when the identifier ends before the end of quoted.
+func unquote(quoted string) (string, bool) {
+	if quoted == "" || (quoted[0] != '"' && quoted[0] != '`') {
+		return "", false
+	}
+	quote := quoted[0]
+	var name strings.Builder
+	for i := 1; i < len(quoted); i++ {
+		if quoted[i] != quote {
+			name.WriteByte(quoted[i])
+			continue
+		}
+		if i+1 < len(quoted) && quoted[i+1] == quote {
+			name.WriteByte(quote)
+			i++
+			continue
+		}
+		return name.String(), i == len(quoted)-1
+	}
+	return "", false
+}
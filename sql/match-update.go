@@ -2,46 +2,55 @@
 package sql
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-	"log"
-	"os"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
-func updatesql() {
-	// Check if column name argument is provided
-	if len(os.Args) < 2 {
-		log.Fatal("Please provide a column name as an argument.")
+// MergeState adds columnName to the target table and fills it with the state
+// of the input table row that has the same id, in the rows of the scans sel
+// picks.
+func MergeState(ctx context.Context, s *Store, targetTable string, inputTable string, columnName string, sel Selector) error {
+	if err := validateIdentifiers(targetTable, inputTable, columnName); err != nil {
+		return err
+	}
+	if err := s.RequireColumns(ctx, targetTable, "id"); err != nil {
+		return err
+	}
+	if err := s.RequireColumns(ctx, inputTable, "id", "state"); err != nil {
+		return err
 	}
-	columnName := os.Args[1]
 
-	// Connect to the SQLite database
-	db, err := sql.Open("sqlite3", "./vulns.db")
+	sc, _, err := selectScans(ctx, s, targetTable, sel)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	defer db.Close()
 
-	// Add new column to the "PopularBank" table.
-	alterTableSql := fmt.Sprintf(`ALTER TABLE PopularBank ADD COLUMN %s TEXT;`, columnName)
-	_, err = db.Exec(alterTableSql)
+	target := s.Quote(targetTable)
+	input := s.Quote(inputTable)
+	column := s.Quote(columnName)
+	id := s.Quote("id")
+
+	// Add new column to the target table.
+	alterTableSql := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s TEXT`, target, column)
+	_, err = s.Exec(ctx, alterTableSql)
 	if err != nil {
-		log.Fatalf("Failed to alter table: %v", err)
+		return fmt.Errorf("failed to alter table: %w", err)
 	}
 
-	// Update the new column in PopularBank with the corresponding value from Data_Input
-	updateStateSql := fmt.Sprintf(`UPDATE PopularBank
+	// Update the new column with the corresponding value from the input table
+	updateStateSql := fmt.Sprintf(`UPDATE %s
 			SET %s = (
-				SELECT state FROM Data_Input
-				WHERE PopularBank.id = Data_Input.id
-			);`, columnName)
+				SELECT %s FROM %s
+				WHERE %s.%s = %s.%s
+			)`, target, column, s.Quote("state"), input, target, id, input, id)
+	if sc.where != "" {
+		updateStateSql += " WHERE " + sc.condition(s)
+	}
 
-	_, err = db.Exec(updateStateSql)
+	_, err = s.Exec(ctx, updateStateSql, sc.args...)
 	if err != nil {
-		log.Fatalf("Failed to update state: %v", err)
+		return fmt.Errorf("failed to update state: %w", err)
 	}
 
-	fmt.Println("Update operation completed successfully!")
+	return nil
 }
@@ -0,0 +1,403 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScansTable records one row per import; ScanColumn tags every imported row
+// with the scan it belongs to, and SourceFileColumn with the file it came
+// from when a scan loads many.
+const (
+	ScansTable       = "scans"
+	ScanColumn       = "scan_id"
+	SourceFileColumn = "source_file"
+)
+
+// Scan statuses. Reports only read complete scans.
+const (
+	scanLoading  = "loading"
+	scanComplete = "complete"
+)
+
+// scanTimeLayout is how import timestamps are written, in UTC.
+const scanTimeLayout = "2006-01-02 15:04:05"
+
+// Scan is a snapshot of a table: the rows of one import.
+type Scan struct {
+	ID         int64
+	Table      string
+	SourceFile string
+	Scanner    string
+	ImportedAt time.Time
+	// replaces is set on a scan that replaces every row and scan of the
+	// table, and staging names the table its rows load into until then.
+	replaces bool
+	staging  string
+}
+
+// Selector picks the scans a report runs against. The zero Selector picks
+// the latest scan of each scanner, so findings from different scanners are
+// reported together.
+type Selector struct {
+	// ID picks the scan with this ID alone.
+	ID int64
+	// Date picks the latest scan of each scanner imported on or before this
+	// day, in UTC.
+	Date time.Time
+}
+
+// ParseSelector parses "latest" (or ""), a scan ID, or a YYYY-MM-DD date.
+func ParseSelector(text string) (Selector, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.EqualFold(text, "latest") {
+		return Selector{}, nil
+	}
+	if id, err := strconv.ParseInt(text, 10, 64); err == nil && id > 0 {
+		return Selector{ID: id}, nil
+	}
+	if date, err := time.Parse("2006-01-02", text); err == nil {
+		return Selector{Date: date}, nil
+	}
+	return Selector{}, fmt.Errorf("invalid scan selector %q: want latest, a scan ID or a YYYY-MM-DD date", text)
+}
+
+func (sel Selector) String() string {
+	switch {
+	case sel.ID != 0:
+		return "scan " + strconv.FormatInt(sel.ID, 10)
+	case !sel.Date.IsZero():
+		return "scan on or before " + sel.Date.Format("2006-01-02")
+	}
+	return "latest scan"
+}
+
+// BeginScan records a new scan of tableName and returns it. Rows imported
+// with ImportOptions.Scan set stay invisible to reports until Commit.
+func BeginScan(ctx context.Context, s *Store, tableName string, sourceFile string, scanner string) (*Scan, error) {
+	if err := ValidateIdentifier(tableName); err != nil {
+		return nil, err
+	}
+	if err := createScansTable(ctx, s); err != nil {
+		return nil, err
+	}
+
+	scan := &Scan{
+		Table:      tableName,
+		SourceFile: sourceFile,
+		Scanner:    scanner,
+		ImportedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	// Imports that begin at the same time may pick the same number; the
+	// primary key turns all but one of them away, and those pick again.
+	var err error
+	for attempt := 1; attempt <= scanNumberAttempts; attempt++ {
+		if err = insertScan(ctx, s, scan); err == nil || ctx.Err() != nil {
+			break
+		}
+		time.Sleep(time.Duration(attempt) * 10 * time.Millisecond)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to record scan: %w", err)
+	}
+	return scan, nil
+}
+
+// scanNumberAttempts bounds the tries of BeginScan to number a new scan.
+const scanNumberAttempts = 10
+
+// insertScan numbers scan one past the highest scan ID and records it, in a
+// serializable transaction.
+func insertScan(ctx context.Context, s *Store, scan *Scan) error {
+	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var last sql.NullInt64
+	query := fmt.Sprintf("SELECT MAX(%s) FROM %s", s.Quote(ScanColumn), s.Quote(ScansTable))
+	if err := tx.QueryRowContext(ctx, query).Scan(&last); err != nil {
+		return fmt.Errorf("unable to number the new scan: %w", err)
+	}
+	scan.ID = last.Int64 + 1
+
+	query = fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s) VALUES (?, ?, ?, ?, ?, ?)", s.Quote(ScansTable),
+		s.Quote(ScanColumn), s.Quote("table_name"), s.Quote("source_file"), s.Quote("scanner"), s.Quote("imported_at"), s.Quote("status"))
+	_, err = tx.ExecContext(ctx, s.Rebind(query), scan.ID, scan.Table, scan.SourceFile, scan.Scanner, scan.ImportedAt.Format(scanTimeLayout), scanLoading)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// BeginReplace records a new scan of tableName whose rows replace every row
+// and earlier scan of the table when it commits. Until then they load into
+// a staging table, so a failed import leaves the table as it was.
+func BeginReplace(ctx context.Context, s *Store, tableName string, sourceFile string, scanner string) (*Scan, error) {
+	scan, err := BeginScan(ctx, s, tableName, sourceFile, scanner)
+	if err != nil {
+		return nil, err
+	}
+	scan.replaces = true
+	if err := scan.stage(ctx, s); err != nil {
+		scan.Abort(context.Background(), s)
+		return nil, err
+	}
+	return scan, nil
+}
+
+// stage creates the staging table of a replacing scan, unless the table
+// does not exist yet and can be loaded directly.
+func (sc *Scan) stage(ctx context.Context, s *Store) error {
+	exists, err := s.TableExists(ctx, sc.Table)
+	if err != nil || !exists {
+		return err
+	}
+	staging := sc.Table + "_staging"
+	if err := ValidateIdentifier(staging); err != nil {
+		return err
+	}
+	if err := s.DropTable(ctx, staging); err != nil {
+		return fmt.Errorf("unable to drop stale staging table: %w", err)
+	}
+	if err := s.Dialect.CreateLike(ctx, s.DB, staging, sc.Table); err != nil {
+		return fmt.Errorf("unable to create staging table: %w", err)
+	}
+	sc.staging = staging
+	return nil
+}
+
+// target returns the table the rows of the scan load into.
+func (sc *Scan) target() string {
+	if sc.staging != "" {
+		return sc.staging
+	}
+	return sc.Table
+}
+
+// Commit checks the rows of the scan and makes it visible to reports. It
+// fails with ErrEmptyImport when the scan has no rows and with a *CVSSError
+// when a CVSS value is not a score. The checks of a replacing scan run on
+// its staging table, before it replaces the table.
+func (sc *Scan) Commit(ctx context.Context, s *Store) error {
+	target := sc.target()
+	var rows int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ?", s.Quote(target), s.Quote(ScanColumn))
+	if err := s.QueryRow(ctx, query, sc.ID).Scan(&rows); err != nil {
+		return fmt.Errorf("unable to count the rows of scan %d: %w", sc.ID, err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("scan %d of %s: %w", sc.ID, sc.Table, ErrEmptyImport)
+	}
+	columns, err := s.Columns(ctx, target)
+	if err != nil {
+		return fmt.Errorf("unable to list columns of %s: %w", target, err)
+	}
+	if hasColumn(columns, "CVSS") {
+		if err := validateCVSS(ctx, s, scanScope(target, sc.ID)); err != nil {
+			var cvssErr *CVSSError
+			if errors.As(err, &cvssErr) {
+				cvssErr.Table = sc.Table
+			}
+			return err
+		}
+	}
+
+	if sc.staging != "" {
+		if err := s.Dialect.Swap(ctx, s.DB, sc.Table, sc.staging); err != nil {
+			return fmt.Errorf("unable to swap in the new rows of %s: %w", sc.Table, err)
+		}
+		sc.staging = ""
+	}
+	if sc.replaces {
+		if err := removeEarlierScans(ctx, s, sc); err != nil {
+			return err
+		}
+	}
+
+	query = fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", s.Quote(ScansTable), s.Quote("status"), s.Quote(ScanColumn))
+	if _, err := s.Exec(ctx, query, scanComplete, sc.ID); err != nil {
+		return fmt.Errorf("unable to complete scan %d: %w", sc.ID, err)
+	}
+	return nil
+}
+
+// Abort removes the rows and the record of an unfinished scan. The table a
+// replacing scan has not replaced yet is left as it was.
+func (sc *Scan) Abort(ctx context.Context, s *Store) error {
+	if sc.staging != "" {
+		if err := s.DropTable(ctx, sc.staging); err != nil {
+			return fmt.Errorf("unable to drop the staging table of scan %d: %w", sc.ID, err)
+		}
+		sc.staging = ""
+		return sc.forget(ctx, s)
+	}
+	exists, err := s.TableExists(ctx, sc.Table)
+	if err != nil {
+		return err
+	}
+	if exists {
+		columns, err := s.Columns(ctx, sc.Table)
+		if err != nil {
+			return err
+		}
+		if hasColumn(columns, ScanColumn) {
+			query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", s.Quote(sc.Table), s.Quote(ScanColumn))
+			if _, err := s.Exec(ctx, query, sc.ID); err != nil {
+				return fmt.Errorf("unable to remove the rows of scan %d: %w", sc.ID, err)
+			}
+		}
+	}
+	return sc.forget(ctx, s)
+}
+
+// forget removes the record of the scan.
+func (sc *Scan) forget(ctx context.Context, s *Store) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", s.Quote(ScansTable), s.Quote(ScanColumn))
+	if _, err := s.Exec(ctx, query, sc.ID); err != nil {
+		return fmt.Errorf("unable to remove scan %d: %w", sc.ID, err)
+	}
+	return nil
+}
+
+// removeEarlierScans removes the records of the scans of the table of sc
+// before it, once its rows replaced theirs.
+func removeEarlierScans(ctx context.Context, s *Store, sc *Scan) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ? AND %s < ?", s.Quote(ScansTable), s.Quote("table_name"), s.Quote(ScanColumn))
+	if _, err := s.Exec(ctx, query, sc.Table, sc.ID); err != nil {
+		return fmt.Errorf("unable to remove the scans replaced by scan %d: %w", sc.ID, err)
+	}
+	return nil
+}
+
+// Scans lists the complete scans of tableName, oldest first.
+func Scans(ctx context.Context, s *Store, tableName string) ([]Scan, error) {
+	exists, err := s.TableExists(ctx, ScansTable)
+	if err != nil || !exists {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT %s, %s, %s, %s, %s FROM %s WHERE %s = ? AND %s = ? ORDER BY %s",
+		s.Quote(ScanColumn), s.Quote("table_name"), s.Quote("source_file"), s.Quote("scanner"), s.Quote("imported_at"),
+		s.Quote(ScansTable), s.Quote("table_name"), s.Quote("status"), s.Quote(ScanColumn))
+	rows, err := s.Query(ctx, query, tableName, scanComplete)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list scans: %w", err)
+	}
+	defer rows.Close()
+
+	var scans []Scan
+	for rows.Next() {
+		var scan Scan
+		var sourceFile, scanner sql.NullString
+		var importedAt timestamp
+		if err := rows.Scan(&scan.ID, &scan.Table, &sourceFile, &scanner, &importedAt); err != nil {
+			return nil, fmt.Errorf("unable to read scan: %w", err)
+		}
+		scan.SourceFile = sourceFile.String
+		scan.Scanner = scanner.String
+		scan.ImportedAt = importedAt.Time
+		scans = append(scans, scan)
+	}
+	return scans, rows.Err()
+}
+
+// ResolveScans returns the complete scans of tableName that sel picks,
+// oldest first. It fails with ErrNoScan when there is none.
+func ResolveScans(ctx context.Context, s *Store, tableName string, sel Selector) ([]Scan, error) {
+	scans, err := Scans(ctx, s, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	var picked []Scan
+	seen := make(map[string]bool)
+	for i := len(scans) - 1; i >= 0; i-- {
+		scan := scans[i]
+		switch {
+		case sel.ID != 0:
+			if scan.ID == sel.ID {
+				return []Scan{scan}, nil
+			}
+			continue
+		case !sel.Date.IsZero():
+			if !scan.ImportedAt.Before(sel.Date.AddDate(0, 0, 1)) {
+				continue
+			}
+		}
+		scanner := strings.ToLower(scan.Scanner)
+		if !seen[scanner] {
+			seen[scanner] = true
+			picked = append([]Scan{scan}, picked...)
+		}
+	}
+	if len(picked) == 0 {
+		return nil, fmt.Errorf("no %s of %s: %w", sel, tableName, ErrNoScan)
+	}
+	return picked, nil
+}
+
+// createScansTable creates the scans table when it does not exist.
+func createScansTable(ctx context.Context, s *Store) error {
+	d := s.Dialect
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s %s PRIMARY KEY, %s %s, %s %s, %s %s, %s %s, %s %s)", s.Quote(ScansTable),
+		s.Quote(ScanColumn), d.ColumnType(KindInteger),
+		s.Quote("table_name"), d.ColumnType(KindText),
+		s.Quote("source_file"), d.ColumnType(KindText),
+		s.Quote("scanner"), d.ColumnType(KindText),
+		s.Quote("imported_at"), d.ColumnType(KindTimestamp),
+		s.Quote("status"), d.ColumnType(KindText))
+	if _, err := s.Exec(ctx, query); err != nil {
+		return fmt.Errorf("unable to create scans table: %w", err)
+	}
+	return nil
+}
+
+// addScanColumn adds and indexes the scan_id column of a table that lacks it.
+func addScanColumn(ctx context.Context, s *Store, tableName string, columns []Column) error {
+	if hasColumn(columns, ScanColumn) {
+		return nil
+	}
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", s.Quote(tableName), s.Quote(ScanColumn), s.Dialect.ColumnType(KindInteger))
+	if _, err := s.Exec(ctx, query); err != nil {
+		return fmt.Errorf("unable to add %s to %s: %w", ScanColumn, tableName, err)
+	}
+	return createIndex(ctx, s, tableName, ScanColumn, KindInteger)
+}
+
+// timestamp scans the import time whichever form the driver returns it in.
+type timestamp struct {
+	time.Time
+}
+
+func (t *timestamp) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	case time.Time:
+		t.Time = v.UTC()
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	}
+	return fmt.Errorf("unsupported timestamp %T", value)
+}
+
+func (t *timestamp) parse(text string) error {
+	for _, layout := range []string{scanTimeLayout, time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00"} {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			t.Time = parsed.UTC()
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognised timestamp %q", text)
+}
@@ -0,0 +1,244 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// importScan loads rows into table as a scan begun with begin and commits
+// it, aborting it on failure.
+func importScan(t *testing.T, s *Store, begin func(context.Context, *Store, string, string, string) (*Scan, error), table string, rows string) (*Scan, error) {
+	t.Helper()
+	ctx := context.Background()
+	scan, err := begin(ctx, s, table, "test.csv", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ImportRows(ctx, s, table, csvRows(rows), ImportOptions{Scan: scan})
+	if err == nil {
+		err = scan.Commit(ctx, s)
+	}
+	if err != nil {
+		if abortErr := scan.Abort(ctx, s); abortErr != nil {
+			t.Fatal(abortErr)
+		}
+	}
+	return scan, err
+}
+
+func scanIDs(t *testing.T, s *Store, table string) string {
+	t.Helper()
+	scans, err := Scans(context.Background(), s, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int64, len(scans))
+	for i, scan := range scans {
+		ids[i] = scan.ID
+	}
+	return fmt.Sprint(ids)
+}
+
+func TestReplaceKeepsTableOnFailure(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		rows string
+		want error
+	}{
+		{"invalid CVSS", "Host,CVSS\nnew1,9.8\nnew2,high\n", ErrInvalidCVSS},
+		{"no rows", "Host,CVSS\n", ErrEmptyImport},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStore(t)
+			mustExec(t, s, `CREATE TABLE "vulns" ("Host" TEXT, "CVSS" TEXT)`)
+			if _, err := importScan(t, s, BeginScan, "vulns", "Host,CVSS\nold1,5.0\n"); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := importScan(t, s, BeginReplace, "vulns", tt.rows); !errors.Is(err, tt.want) {
+				t.Fatalf("replacing scan = %v, want %v", err, tt.want)
+			}
+			if got := fmt.Sprint(columnValues(t, s, "vulns", "Host")); got != "[old1]" {
+				t.Errorf("rows after the failed replace = %s, want [old1]", got)
+			}
+			if got := scanIDs(t, s, "vulns"); got != "[1]" {
+				t.Errorf("scans after the failed replace = %s, want [1]", got)
+			}
+			if exists, _ := s.TableExists(context.Background(), "vulns_staging"); exists {
+				t.Error("the staging table is left behind")
+			}
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	s := newTestStore(t)
+	mustExec(t, s, `CREATE TABLE "vulns" ("Host" TEXT, "CVSS" TEXT)`)
+	for _, rows := range []string{"Host,CVSS\nold1,5.0\n", "Host,CVSS\nold2,6.0\n"} {
+		if _, err := importScan(t, s, BeginScan, "vulns", rows); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scan, err := importScan(t, s, BeginReplace, "vulns", "Host,CVSS\nnew1,9.8\nnew2,\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(columnValues(t, s, "vulns", "Host")); got != "[new1 new2]" {
+		t.Errorf("rows after the replace = %s, want [new1 new2]", got)
+	}
+	if got := fmt.Sprint(columnValues(t, s, "vulns", ScanColumn)); got != fmt.Sprintf("[%d %d]", scan.ID, scan.ID) {
+		t.Errorf("scan IDs of the rows = %s, want scan %d", got, scan.ID)
+	}
+	if got := scanIDs(t, s, "vulns"); got != fmt.Sprintf("[%d]", scan.ID) {
+		t.Errorf("scans after the replace = %s, want [%d]", got, scan.ID)
+	}
+	latest, err := ResolveScans(context.Background(), s, "vulns", Selector{})
+	if err != nil || len(latest) != 1 || latest[0].ID != scan.ID {
+		t.Errorf("latest scans = %v, %v, want scan %d", latest, err, scan.ID)
+	}
+}
+
+func TestBeginScanConcurrent(t *testing.T) {
+	ctx := context.Background()
+	// Each import waits its turn for the database file, as with several
+	// processes importing at once
+	s, err := Open(ctx, "sqlite", filepath.Join(t.TempDir(), "scans.db")+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := createScansTable(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+
+	const imports = 8
+	ids := make(chan int64, imports)
+	var wg sync.WaitGroup
+	for i := 0; i < imports; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scan, err := BeginScan(ctx, s, "vulns", "test.csv", "")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ids <- scan.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int64]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("scan %d is handed out twice", id)
+		}
+		seen[id] = true
+	}
+	if got := fmt.Sprint(columnValues(t, s, ScansTable, ScanColumn)); len(seen) != imports || got != "[1 2 3 4 5 6 7 8]" {
+		t.Errorf("scans = %s, want [1 2 3 4 5 6 7 8]", got)
+	}
+}
+
+func TestScanLifecycle(t *testing.T) {
+	s := newTestStore(t)
+	mustExec(t, s, `CREATE TABLE "vulns" ("Host" TEXT, "CVSS" TEXT)`)
+	ctx := context.Background()
+
+	first, err := importScan(t, s, BeginScan, "vulns", "Host,CVSS\nhost1,5.0\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The rows of an unfinished scan are stored but not picked
+	second, err := BeginScan(ctx, s, "vulns", "test.csv", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportRows(ctx, s, "vulns", csvRows("Host,CVSS\nhost2,6.0\n"), ImportOptions{Scan: second}); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := ResolveScans(ctx, s, "vulns", Selector{})
+	if err != nil || len(latest) != 1 || latest[0].ID != first.ID {
+		t.Errorf("latest scans while loading = %v, %v, want scan %d", latest, err, first.ID)
+	}
+	if _, err := ResolveScans(ctx, s, "vulns", Selector{ID: second.ID}); !errors.Is(err, ErrNoScan) {
+		t.Errorf("ResolveScans of the loading scan = %v, want %v", err, ErrNoScan)
+	}
+
+	// Aborting it removes its rows and its record
+	if err := second.Abort(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(columnValues(t, s, "vulns", "Host")); got != "[host1]" {
+		t.Errorf("rows after the abort = %s, want [host1]", got)
+	}
+	if got := fmt.Sprint(columnValues(t, s, ScansTable, ScanColumn)); got != fmt.Sprintf("[%d]", first.ID) {
+		t.Errorf("scan records after the abort = %s, want [%d]", got, first.ID)
+	}
+
+	third, err := importScan(t, s, BeginScan, "vulns", "Host,CVSS\nhost3,7.0\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		sel  Selector
+		want int64
+	}{
+		{Selector{}, third.ID},
+		{Selector{ID: first.ID}, first.ID},
+		{Selector{Date: third.ImportedAt}, third.ID},
+	} {
+		picked, err := ResolveScans(ctx, s, "vulns", tt.sel)
+		if err != nil || len(picked) != 1 || picked[0].ID != tt.want {
+			t.Errorf("ResolveScans(%v) = %v, %v, want scan %d", tt.sel, picked, err, tt.want)
+		}
+	}
+	if _, err := ResolveScans(ctx, s, "vulns", Selector{Date: third.ImportedAt.AddDate(0, 0, -1)}); !errors.Is(err, ErrNoScan) {
+		t.Errorf("ResolveScans of the day before = %v, want %v", err, ErrNoScan)
+	}
+	if got := scanIDs(t, s, "vulns"); got != fmt.Sprintf("[%d %d]", first.ID, third.ID) {
+		t.Errorf("scans = %s, want [%d %d]", got, first.ID, third.ID)
+	}
+}
+
+func TestLatestScanOfEachScanner(t *testing.T) {
+	s := newTestStore(t)
+	mustExec(t, s, `CREATE TABLE "vulns" ("Host" TEXT)`)
+	ctx := context.Background()
+
+	var want []int64
+	for _, scanner := range []string{"nessus", "qualys", "Nessus"} {
+		scan, err := BeginScan(ctx, s, "vulns", "test.csv", scanner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ImportRows(ctx, s, "vulns", csvRows("Host\nhost1\n"), ImportOptions{Scan: scan}); err != nil {
+			t.Fatal(err)
+		}
+		if err := scan.Commit(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+		if scanner != "nessus" {
+			want = append(want, scan.ID)
+		}
+	}
+
+	picked, err := ResolveScans(ctx, s, "vulns", Selector{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int64, len(picked))
+	for i, scan := range picked {
+		ids[i] = scan.ID
+	}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("latest scans = %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,383 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ColumnKind is the logical type inferred for a column.
+type ColumnKind int
+
+// The column kinds, from the most to the least specific.
+const (
+	KindText ColumnKind = iota
+	KindInteger
+	KindReal
+	KindBoolean
+	KindDate
+	KindTimestamp
+	KindIP
+)
+
+func (k ColumnKind) String() string {
+	switch k {
+	case KindInteger:
+		return "integer"
+	case KindReal:
+		return "real"
+	case KindBoolean:
+		return "boolean"
+	case KindDate:
+		return "date"
+	case KindTimestamp:
+		return "timestamp"
+	case KindIP:
+		return "ip"
+	}
+	return "text"
+}
+
+// DefaultSampleRows is the number of rows inspected to infer column types.
+const DefaultSampleRows = 1000
+
+// IndexedColumns are indexed when a table is created, if present.
+var IndexedColumns = []string{"Host", "Name", "CVE", "CVSS"}
+
+// fixedKinds pins the kind of well-known columns whatever the sample holds;
+// a CVSS sample of whole numbers must still accept 9.8 later on, and a CVSS
+// version of 4.0 must not read 4.
+var fixedKinds = map[string]ColumnKind{
+	"cvss":                   KindReal,
+	"cvssversion":            KindText,
+	"cvssscorebase":          KindReal,
+	"cvssscoretemporal":      KindReal,
+	"cvssscoreenvironmental": KindReal,
+	"epss":                   KindReal,
+	"percentile":             KindReal,
+}
+
+// Layouts recognised as dates and timestamps, as scanners print them.
+var (
+	dateLayouts = []string{"2006-01-02", "01/02/2006", "2006/01/02"}
+
+	timestampLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04",
+		"01/02/2006 15:04:05",
+		"01/02/2006 15:04",
+		"Jan 2, 2006 15:04:05 MST",
+		time.ANSIC,
+		time.RFC1123,
+	}
+)
+
+// InferKinds returns the kind of each of count columns that fits every
+// non-empty sample value. Columns without sample values are text.
+func InferKinds(names []string, sample [][]string) []ColumnKind {
+	kinds := make([]ColumnKind, len(names))
+	for i, name := range names {
+		if kind, ok := fixedKinds[columnKey(name)]; ok {
+			kinds[i] = kind
+			continue
+		}
+
+		seen := false
+		kind := KindText
+		for _, row := range sample {
+			if i >= len(row) || strings.TrimSpace(row[i]) == "" {
+				continue
+			}
+			valueKind := detectKind(strings.TrimSpace(row[i]))
+			if !seen {
+				kind, seen = valueKind, true
+				continue
+			}
+			if kind = widenKind(kind, valueKind); kind == KindText {
+				break
+			}
+		}
+		kinds[i] = kind
+	}
+	return kinds
+}
+
+// detectKind returns the most specific kind a single value fits.
+func detectKind(value string) ColumnKind {
+	switch {
+	case isInteger(value):
+		return KindInteger
+	case isReal(value):
+		return KindReal
+	case isBoolean(value):
+		return KindBoolean
+	case net.ParseIP(value) != nil:
+		return KindIP
+	}
+	if _, ok := parseLayouts(value, dateLayouts); ok {
+		return KindDate
+	}
+	if _, ok := parseLayouts(value, timestampLayouts); ok {
+		return KindTimestamp
+	}
+	return KindText
+}
+
+// widenKind returns the kind that holds values of both kinds.
+func widenKind(a ColumnKind, b ColumnKind) ColumnKind {
+	switch {
+	case a == b:
+		return a
+	case a == KindInteger && b == KindReal, a == KindReal && b == KindInteger:
+		return KindReal
+	case a == KindDate && b == KindTimestamp, a == KindTimestamp && b == KindDate:
+		return KindTimestamp
+	}
+	return KindText
+}
+
+// isInteger accepts decimal integers without leading zeros, which would be
+// lost; identifiers such as "0042" stay text.
+func isInteger(value string) bool {
+	digits := strings.TrimPrefix(value, "-")
+	if digits == "" || (len(digits) > 1 && digits[0] == '0') {
+		return false
+	}
+	_, err := strconv.ParseInt(value, 10, 64)
+	return err == nil
+}
+
+func isReal(value string) bool {
+	for _, r := range value {
+		if !unicode.IsDigit(r) && r != '.' && r != '-' && r != '+' && r != 'e' && r != 'E' {
+			return false
+		}
+	}
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
+
+func isBoolean(value string) bool {
+	_, ok := parseBoolean(value)
+	return ok
+}
+
+func parseBoolean(value string) (bool, bool) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "y":
+		return true, true
+	case "false", "no", "n":
+		return false, true
+	}
+	return false, false
+}
+
+func parseLayouts(value string, layouts []string) (time.Time, bool) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// kindOfType maps a declared column type back to a column kind.
+func kindOfType(columnType string) ColumnKind {
+	t := strings.ToUpper(columnType)
+	switch {
+	case strings.Contains(t, "INT") && !strings.Contains(t, "INTERVAL") && !strings.Contains(t, "POINT"):
+		if t == "TINYINT(1)" {
+			return KindBoolean
+		}
+		return KindInteger
+	case strings.Contains(t, "REAL"), strings.Contains(t, "DOUBLE"), strings.Contains(t, "FLOAT"),
+		strings.Contains(t, "NUMERIC"), strings.Contains(t, "DECIMAL"):
+		return KindReal
+	case strings.Contains(t, "BOOL"):
+		return KindBoolean
+	case strings.Contains(t, "TIMESTAMP"), strings.Contains(t, "DATETIME"):
+		return KindTimestamp
+	case t == "DATE":
+		return KindDate
+	case t == "INET":
+		return KindIP
+	}
+	return KindText
+}
+
+// converter turns a source string into the value bound for a column kind.
+type converter func(value string) (interface{}, error)
+
+// converterFor returns the converter for a column kind. Empty values become
+// NULL in typed columns and stay empty strings in text columns.
+func converterFor(kind ColumnKind) converter {
+	return func(value string) (interface{}, error) {
+		if kind == KindText {
+			return value, nil
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil, nil
+		}
+		switch kind {
+		case KindInteger:
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return n, nil
+			}
+		case KindReal:
+			if f, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+				return f, nil
+			}
+		case KindBoolean:
+			if b, ok := parseBoolean(value); ok {
+				return b, nil
+			}
+			if value == "1" || value == "0" {
+				return value == "1", nil
+			}
+		case KindDate:
+			if t, ok := parseLayouts(value, dateLayouts); ok {
+				return t.Format("2006-01-02"), nil
+			}
+			if t, ok := parseLayouts(value, timestampLayouts); ok {
+				return t.Format("2006-01-02"), nil
+			}
+		case KindTimestamp:
+			if t, ok := parseLayouts(value, timestampLayouts); ok {
+				return t.UTC().Format("2006-01-02 15:04:05"), nil
+			}
+			if t, ok := parseLayouts(value, dateLayouts); ok {
+				return t.Format("2006-01-02 15:04:05"), nil
+			}
+		case KindIP:
+			if ip := net.ParseIP(value); ip != nil {
+				return ip.String(), nil
+			}
+		}
+		return nil, fmt.Errorf("value %q is not a valid %s", value, kind)
+	}
+}
+
+// NormalizeIdentifier turns a source header into a column name made of
+// letters, digits and underscores that does not start with a digit.
+func NormalizeIdentifier(name string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.TrimSpace(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	normalized := strings.TrimSuffix(b.String(), "_")
+	if normalized == "" {
+		normalized = "column"
+	}
+	if unicode.IsDigit([]rune(normalized)[0]) {
+		normalized = "c_" + normalized
+	}
+	return normalized
+}
+
+// NormalizeHeaders normalizes every header and makes the names unique,
+// ignoring case, by numbering repeats.
+func NormalizeHeaders(header []string) []string {
+	names := make([]string, len(header))
+	used := make(map[string]bool, len(header))
+	for i, h := range header {
+		base := NormalizeIdentifier(h)
+		name := base
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
+
+// CreateInferredTable creates tableName with one column per name, typed from
+// the sample rows, and indexes the IndexedColumns it contains.
+func CreateInferredTable(ctx context.Context, s *Store, tableName string, names []string, sample [][]string) error {
+	if err := ValidateIdentifier(tableName); err != nil {
+		return err
+	}
+	if err := validateIdentifiers(names...); err != nil {
+		return err
+	}
+	kinds := InferKinds(names, sample)
+
+	definitions := make([]string, len(names))
+	for i, name := range names {
+		definitions[i] = s.Quote(name) + " " + s.Dialect.ColumnType(kinds[i])
+	}
+	query := fmt.Sprintf("CREATE TABLE %s (%s)", s.Quote(tableName), strings.Join(definitions, ", "))
+	if _, err := s.Exec(ctx, query); err != nil {
+		return fmt.Errorf("unable to create table %s: %w", tableName, err)
+	}
+
+	for _, indexed := range IndexedColumns {
+		for i, name := range names {
+			if !strings.EqualFold(name, indexed) {
+				continue
+			}
+			if err := createIndex(ctx, s, tableName, name, kinds[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// createIndex indexes one column of a table as idx_<table>_<column>.
+func createIndex(ctx context.Context, s *Store, tableName string, column string, kind ColumnKind) error {
+	index := indexName(tableName, column)
+	if err := ValidateIdentifier(index); err != nil {
+		return err
+	}
+	query := fmt.Sprintf("CREATE INDEX %s ON %s (%s)", s.Quote(index), s.Quote(tableName), s.Dialect.IndexColumn(column, kind))
+	if _, err := s.Exec(ctx, query); err != nil {
+		return fmt.Errorf("unable to index %s.%s: %w", tableName, column, err)
+	}
+	return nil
+}
+
+// indexName names the index of a column of a table. A name too long for an
+// identifier is cut short and ends in a hash of the whole name instead, so
+// that the indexes of long names sharing a prefix stay apart.
+func indexName(tableName string, column string) string {
+	name := NormalizeIdentifier("idx_" + tableName + "_" + column)
+	if len(name) <= MaxIdentifierLength {
+		return name
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	return shortenIdentifier(name, fmt.Sprintf("_%08x", hash.Sum32()))
+}
+
+// AddTextColumns adds a text column to tableName for each of names, after
+// normalizing them.
+func AddTextColumns(ctx context.Context, s *Store, tableName string, names []string) error {
+	if err := ValidateIdentifier(tableName); err != nil {
+		return err
+	}
+	for _, name := range NormalizeHeaders(names) {
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", s.Quote(tableName), s.Quote(name), s.Dialect.ColumnType(KindText))
+		if _, err := s.Exec(ctx, query); err != nil {
+			return fmt.Errorf("unable to add column %s to %s: %w", name, tableName, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Unscored names the bucket of findings without a CVSS score.
+const Unscored = "Unscored"
+
+// SeverityBand names the CVSS scores from Min up to, but not including, the
+// Min of the next band. The highest band takes every score from its Min.
+type SeverityBand struct {
+	Name string
+	Min  float64
+}
+
+// SeverityBands are bands that cover every score from 0 without gaps,
+// lowest first.
+type SeverityBands []SeverityBand
+
+// FIRSTBands is the qualitative severity rating scale of CVSS v3, as FIRST
+// publishes it.
+var FIRSTBands = SeverityBands{
+	{Name: "None", Min: 0},
+	{Name: "Low", Min: 0.1},
+	{Name: "Medium", Min: 4},
+	{Name: "High", Min: 7},
+	{Name: "Critical", Min: 9},
+}
+
+// FiveBands is the scale of the original reports, which set apart the 10s
+// as Critical and the 9s as Severe.
+var FiveBands = SeverityBands{
+	{Name: "Low", Min: 0},
+	{Name: "Medium", Min: 4},
+	{Name: "High", Min: 7},
+	{Name: "Severe", Min: 9},
+	{Name: "Critical", Min: 10},
+}
+
+// DefaultBandPreset names the bands reports use unless configured otherwise.
+const DefaultBandPreset = "first"
+
+// bandPresets are the bands that config and flags can name.
+var bandPresets = map[string]SeverityBands{
+	"first":     FIRSTBands,
+	"five-band": FiveBands,
+}
+
+// BandPreset returns the named preset bands.
+func BandPreset(name string) (SeverityBands, error) {
+	bands, ok := bandPresets[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("unknown severity band preset %q; choose one of %s", name, strings.Join(BandPresets(), ", "))
+	}
+	return bands, nil
+}
+
+// BandPresets returns the names of the preset bands, sorted.
+func BandPresets() []string {
+	names := make([]string, 0, len(bandPresets))
+	for name := range bandPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that the bands start at 0, rise and have distinct names
+// other than Unscored.
+func (b SeverityBands) Validate() error {
+	if len(b) == 0 {
+		return fmt.Errorf("no severity bands")
+	}
+	if b[0].Min != 0 {
+		return fmt.Errorf("the lowest severity band %s starts at %g, not 0", b[0].Name, b[0].Min)
+	}
+	seen := map[string]bool{}
+	for i, band := range b {
+		name := strings.ToLower(strings.TrimSpace(band.Name))
+		switch {
+		case name == "":
+			return fmt.Errorf("severity band %d has no name", i+1)
+		case name == strings.ToLower(Unscored):
+			return fmt.Errorf("severity band %d may not be named %s", i+1, Unscored)
+		case seen[name]:
+			return fmt.Errorf("severity band %s is named twice", band.Name)
+		case band.Min > 10:
+			return fmt.Errorf("severity band %s starts above 10", band.Name)
+		case i > 0 && band.Min <= b[i-1].Min:
+			return fmt.Errorf("severity band %s does not start above %s", band.Name, b[i-1].Name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// Band returns the index of the band of score.
+func (b SeverityBands) Band(score float64) int {
+	for i := len(b) - 1; i > 0; i-- {
+		if score >= b[i].Min {
+			return i
+		}
+	}
+	return 0
+}
+
+// dangerous returns the index of the lowest band the most dangerous
+// vulnerabilities are taken from: the band named High, or else the second
+// highest band.
+func (b SeverityBands) dangerous() int {
+	for i, band := range b {
+		if strings.EqualFold(strings.TrimSpace(band.Name), "High") {
+			return i
+		}
+	}
+	if len(b) < 2 {
+		return 0
+	}
+	return len(b) - 2
+}
+
+// BandCount counts the findings of a severity band, or the unscored ones.
+type BandCount struct {
+	Band  string
+	Total int
+}
+
+// bandCounter counts findings by the band of their score.
+type bandCounter struct {
+	bands    SeverityBands
+	totals   []int
+	unscored int
+}
+
+func newBandCounter(bands SeverityBands) *bandCounter {
+	return &bandCounter{bands: bands, totals: make([]int, len(bands))}
+}
+
+// add counts n findings of a CVSS value; an empty one is unscored.
+func (c *bandCounter) add(value string, n int) {
+	score, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		c.unscored += n
+		return
+	}
+	c.totals[c.bands.Band(score)] += n
+}
+
+// counts returns the counts of the bands, highest first as the report
+// sheets list them, and then the unscored count.
+func (c *bandCounter) counts() []BandCount {
+	counts := make([]BandCount, 0, len(c.bands)+1)
+	for i := len(c.bands) - 1; i >= 0; i-- {
+		counts = append(counts, BandCount{Band: c.bands[i].Name, Total: c.totals[i]})
+	}
+	return append(counts, BandCount{Band: Unscored, Total: c.unscored})
+}
@@ -0,0 +1,108 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestBand(t *testing.T) {
+	for _, tt := range []struct {
+		bands SeverityBands
+		score float64
+		want  string
+	}{
+		{FIRSTBands, 0, "None"},
+		{FIRSTBands, 0.1, "Low"},
+		{FIRSTBands, 6.9999, "Medium"},
+		{FIRSTBands, 7.0, "High"},
+		{FIRSTBands, 8.9999, "High"},
+		{FIRSTBands, 9.0, "Critical"},
+		{FIRSTBands, 10.0, "Critical"},
+		{FiveBands, 6.9999, "Medium"},
+		{FiveBands, 7.0, "High"},
+		{FiveBands, 9.9999, "Severe"},
+		{FiveBands, 10.0, "Critical"},
+	} {
+		if got := tt.bands[tt.bands.Band(tt.score)].Name; got != tt.want {
+			t.Errorf("Band(%g) = %s, want %s", tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestBandCounter(t *testing.T) {
+	c := newBandCounter(FIRSTBands)
+	for value, n := range map[string]int{"6.9999": 1, "7.0": 2, " 10 ": 3, "": 4, "n/a": 5} {
+		c.add(value, n)
+	}
+	want := "[{Critical 3} {High 2} {Medium 1} {Low 0} {None 0} {Unscored 9}]"
+	if got := fmt.Sprint(c.counts()); got != want {
+		t.Errorf("counts = %s, want %s", got, want)
+	}
+}
+
+func TestValidateBands(t *testing.T) {
+	for _, tt := range []struct {
+		bands SeverityBands
+		valid bool
+	}{
+		{FIRSTBands, true},
+		{FiveBands, true},
+		{SeverityBands{{Name: "All", Min: 0}}, true},
+		{nil, false},
+		{SeverityBands{{Name: "Low", Min: 0.1}}, false},
+		{SeverityBands{{Name: "Low", Min: 0}, {Name: "low", Min: 5}}, false},
+		{SeverityBands{{Name: "Low", Min: 0}, {Name: "High", Min: 7}, {Name: "Medium", Min: 4}}, false},
+		{SeverityBands{{Name: "Low", Min: 0}, {Name: "unscored", Min: 5}}, false},
+		{SeverityBands{{Name: "Low", Min: 0}, {Name: "", Min: 5}}, false},
+		{SeverityBands{{Name: "Low", Min: 0}, {Name: "Beyond", Min: 10.5}}, false},
+	} {
+		if err := tt.bands.Validate(); (err == nil) != tt.valid {
+			t.Errorf("Validate(%v) = %v, want valid %v", tt.bands, err, tt.valid)
+		}
+	}
+}
+
+func TestMostDangerousVulns(t *testing.T) {
+	s := newTestStore(t)
+	mustExec(t, s, `CREATE TABLE "vulns" ("Host" TEXT, "Name" TEXT, "CVSS" TEXT)`)
+	mustExec(t, s, `INSERT INTO "vulns" VALUES
+		('h1', 'Weak TLS', '6.9999'),
+		('h2', 'Weak TLS', '6.9999'),
+		('h3', 'Weak TLS', '6.9999'),
+		('h1', 'Open SNMP', '7.0'),
+		('h2', 'Open SNMP', '7.5'),
+		('h1', 'Telnet', '10.0'),
+		('h1', 'Log4Shell', '9.5'),
+		('h2', 'Log4Shell', '10'),
+		('h3', 'Log4Shell', '9.5'),
+		('h4', 'Unscored', ''),
+		('h5', 'Unscored', NULL)`)
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name  string
+		bands SeverityBands
+		want  string
+	}{
+		// Scores from 7 up, 10 included, and none from 6.9999
+		{"first", FIRSTBands, "[{Log4Shell 10 3} {Open SNMP 7.5 2} {Telnet 10 1}]"},
+		{"five-band", FiveBands, "[{Log4Shell 10 3} {Open SNMP 7.5 2} {Telnet 10 1}]"},
+		// Without a High band, the second highest band is the lowest taken
+		{"custom", SeverityBands{{Name: "Routine", Min: 0}, {Name: "Urgent", Min: 9}, {Name: "Emergency", Min: 10}}, "[{Log4Shell 10 3} {Telnet 10 1}]"},
+		{"high", SeverityBands{{Name: "low", Min: 0}, {Name: "HIGH", Min: 6}, {Name: "severe", Min: 8}, {Name: "critical", Min: 9.5}},
+			"[{Log4Shell 10 3} {Weak TLS 6.9999 3} {Open SNMP 7.5 2} {Telnet 10 1}]"},
+		{"one band", SeverityBands{{Name: "All", Min: 0}},
+			"[{Log4Shell 10 3} {Weak TLS 6.9999 3} {Open SNMP 7.5 2} {Telnet 10 1}]"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mostDangerousVulns(ctx, s, scope{table: "vulns"}, tt.bands)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("mostDangerousVulns = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
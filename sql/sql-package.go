@@ -2,212 +2,355 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"os"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
-
-	// Import the sqlite SQL driver
-	_ "modernc.org/sqlite"
 )
 
-// CreateTable creates the table from the supplied values
-func CreateTable(dbLocation string, tableName string, values []string) {
-	// Open the SQLite DB at the provided location
-	database, err := sql.Open("sqlite", dbLocation)
-	// Handle any errors opening the DB
-	if err != nil {
-		fmt.Printf("Error opening SQLite DB File. Error: %v\n", err)
-		os.Exit(2)
-	}
-
-	// Structure the headers
-	headers := structureHeaders(values)
+// RequiredColumns are the columns the report queries read.
+var RequiredColumns = []string{"Host", "Name", "CVSS", "CVE"}
 
-	// Create the table
-	createTableQuery := `CREATE TABLE IF NOT EXISTS '` + tableName + `'(` + headers + `)`
-	tableQuery, err := database.Prepare(createTableQuery)
+// CreateTable creates the table from the supplied values when it does not
+// exist. Columns are text except CVSS, which is real.
+func CreateTable(ctx context.Context, s *Store, tableName string, values []string) error {
+	exists, err := s.TableExists(ctx, tableName)
 	if err != nil {
-		fmt.Printf("Improper SQL Query. Error: %v\n", err)
-		os.Exit(3)
+		return fmt.Errorf("unable to check table %s: %w", tableName, err)
 	}
-	defer tableQuery.Close()
-	tableQuery.Exec()
+	if exists {
+		return nil
+	}
+	return CreateInferredTable(ctx, s, tableName, values, nil)
 }
 
 // InsertDB inserts data into the database
-func InsertDB(dbLocation string, tableName string, headers []string, values [][]string) {
-	// Open the SQLite DB at the provided location
-	database, err := sql.Open("sqlite", dbLocation)
-	// Handle any errors opening the DB
-	if err != nil {
-		fmt.Printf("Error opening SQLite DB File. Error: %v\n", err)
-		os.Exit(2)
-	}
-
-	// Build insert query
-	insertValueQuery := "INSERT INTO %v (%v) VALUES (%v)"
-	headersString := strings.Join(headers[:], "', '")
-	headersString = "'" + headersString + "'"
-	valueCount := len(headers)
-	count := 1
-	valueString := "?"
-	for count < valueCount {
-		valueString = valueString + ", ?"
-		count++
-	}
-	tableName = "'" + tableName + "'"
-	insertValueQuery = fmt.Sprintf(insertValueQuery, tableName, headersString, valueString)
-
-	// Insert the data
-	tx, err := database.Begin()
-	if err != nil {
-		fmt.Println(err)
+func InsertDB(ctx context.Context, s *Store, tableName string, headers []string, values [][]string) error {
+	if err := validateIdentifiers(append([]string{tableName}, headers...)...); err != nil {
+		return err
 	}
-	fmt.Printf("Inserting data into database: %v table: %v\n", dbLocation, tableName)
-	rows := 0
-	for _, value := range values {
+
+	// Insert the data in batches
+	writer := newBatchWriter(s, tableName, headers, 0, 0)
+	defer writer.Abort()
+
+	for id, value := range values {
 		// Convert slice to interface
 		row := make([]interface{}, len(value))
 		for id := range value {
 			row[id] = value[id]
 		}
-		insertQuery, err := tx.Prepare(insertValueQuery)
-		if err != nil {
-			fmt.Printf("Error in inserting data into DB. Error: %v\n", err)
-			os.Exit(4)
+		if _, err := writer.Add(ctx, row); err != nil {
+			return fmt.Errorf("unable to insert row %d into %s: %w", id, tableName, err)
 		}
-		defer insertQuery.Close()
-		insertQuery.Exec(row...)
-		rows++
 	}
-	tx.Commit()
-	fmt.Printf("%v rows have been inserted into the table: %v\n", rows, tableName)
+
+	return writer.Close(ctx)
 }
 
-// RunQueries runs all queries on the sql database and returns a map of results
-func RunQueries(db *sql.DB, tableName string) (VulnBySeverity, []TopTenVulnHosts, []MostDangerousVulns, VulnByType, []CountCVSSYear) {
-	// tableName = "'" + tableName + "'"
-	vulnBySeverity := vulnBySeverity(db, tableName)
-	topTenVulnHosts := topTenVulnHosts(db, tableName)
-	mostDangerousVulns := mostDangerousVulns(db, tableName)
-	vulnByType := vulnByType(db, tableName)
-	countCVSSYear := countCVSSYear(db, tableName)
+// Report holds the results of every report query.
+type Report struct {
+	// Scans are the scans reported on, or nil for a table without scans.
+	Scans []Scan
+	// Suppressed counts the findings left out of the report because a VEX
+	// statement marks them not_affected.
+	Suppressed int
 
-	// you might have something like this for your return statement
-	return vulnBySeverity, topTenVulnHosts, mostDangerousVulns, vulnByType, countCVSSYear
+	VulnBySeverity     VulnBySeverity
+	TopTenVulnHosts    []TopTenVulnHosts
+	MostDangerousVulns []MostDangerousVulns
+	VulnByType         VulnByType
+	CountCVSSYear      []CountCVSSYear
+	// ContainerImages lists the most vulnerable container images, when the
+	// table holds container findings.
+	ContainerImages []ContainerImage
+	// LikelyExploitedVulns and LikelyExploitedHosts rank the CVEs and the
+	// hosts of the findings by EPSS score, when EPSS scores were imported.
+	// EPSSDate is the day of the scores, when known.
+	LikelyExploitedVulns []LikelyExploitedVuln
+	LikelyExploitedHosts []LikelyExploitedHost
+	EPSSDate             string
+	// ByAttackVector and ByPrivileges group the findings by the components
+	// of their CVSS vectors, when the table holds them.
+	ByAttackVector []VulnByComponent
+	ByPrivileges   []VulnByComponent
+	// OpenServices is filled in separately from an asset inventory; see
+	// OpenServices.
+	OpenServices []OpenService
+}
+
+// RunQueries runs all queries on the scans of the table that sel picks and
+// returns their results. A table without scans is reported whole when sel
+// picks the latest scan. Findings that a not_affected statement of vexTable
+// covers are left out; an empty vexTable keeps them all. CVEs and hosts are
+// ranked by the EPSS scores of epssTable when it is set and exists. Findings
+// are counted by the severity bands, FIRSTBands when none are given. It fails
+// with a *ColumnError when the table lacks a
+// required column, with ErrNoScan when no scan matches sel and with a
+// *CVSSError when a CVSS value is not a score between 0 and 10.
+func RunQueries(ctx context.Context, s *Store, tableName string, sel Selector, vexTable string, epssTable string, bands SeverityBands) (Report, error) {
+	var report Report
+
+	if err := ValidateIdentifier(tableName); err != nil {
+		return report, err
+	}
+	if len(bands) == 0 {
+		bands = FIRSTBands
+	}
+	if err := bands.Validate(); err != nil {
+		return report, err
+	}
+	if err := s.RequireColumns(ctx, tableName, RequiredColumns...); err != nil {
+		return report, err
+	}
+
+	// Pick the scan to report on
+	sc, scans, err := selectScans(ctx, s, tableName, sel)
+	if err != nil {
+		return report, err
+	}
+	report.Scans = scans
+
+	// Leave out the findings that VEX statements rule out
+	if vexTable != "" {
+		if sc, report.Suppressed, err = suppressVEX(ctx, s, sc, vexTable); err != nil {
+			return report, err
+		}
+	}
+
+	if err := validateCVSS(ctx, s, sc); err != nil {
+		return report, err
+	}
+
+	if report.VulnBySeverity, err = vulnBySeverity(ctx, s, sc, bands); err != nil {
+		return report, &QueryError{Query: "vulnerabilities by severity", Err: err}
+	}
+	if report.TopTenVulnHosts, err = topTenVulnHosts(ctx, s, sc, bands); err != nil {
+		return report, &QueryError{Query: "top vulnerable hosts", Err: err}
+	}
+	if report.MostDangerousVulns, err = mostDangerousVulns(ctx, s, sc, bands); err != nil {
+		return report, &QueryError{Query: "most dangerous vulnerabilities", Err: err}
+	}
+	if report.VulnByType, err = vulnByType(ctx, s, sc); err != nil {
+		return report, &QueryError{Query: "vulnerabilities by type", Err: err}
+	}
+	if report.CountCVSSYear, err = countCVSSYear(ctx, s, sc); err != nil {
+		return report, &QueryError{Query: "vulnerabilities by year", Err: err}
+	}
+	if containers, err := hasContainerColumns(ctx, s, tableName); err != nil {
+		return report, err
+	} else if containers {
+		if report.ContainerImages, err = containerImages(ctx, s, sc, bands); err != nil {
+			return report, &QueryError{Query: "container images", Err: err}
+		}
+	}
+	if epssTable != "" {
+		if report.LikelyExploitedVulns, report.LikelyExploitedHosts, report.EPSSDate, err = likelyExploited(ctx, s, sc, epssTable); err != nil {
+			return report, &QueryError{Query: "most likely exploited", Err: err}
+		}
+	}
+	for _, grouping := range []struct {
+		column string
+		query  string
+		result *[]VulnByComponent
+	}{
+		{ColumnAttackVector, "vulnerabilities by attack vector", &report.ByAttackVector},
+		{ColumnPrivileges, "vulnerabilities by privileges required", &report.ByPrivileges},
+	} {
+		if *grouping.result, err = vulnByComponent(ctx, s, sc, grouping.column); err != nil {
+			return report, &QueryError{Query: grouping.query, Err: err}
+		}
+	}
 
+	return report, nil
 }
 
-func structureHeaders(headers []string) string {
-	headersString := strings.Join(headers[:], "' TEXT, '")
-	headersString = "'" + headersString + "' TEXT"
-	headersString = strings.Replace(headersString, "'CVSS' TEXT", "'CVSS' NUMERIC", 1)
-	return headersString
+// validateCVSS checks that every non-empty CVSS value in scope is a score
+// from 0 to 10.
+func validateCVSS(ctx context.Context, s *Store, sc scope) error {
+	query, args := sc.expand(s, `SELECT DISTINCT {CVSS} FROM !! WHERE {CVSS} IS NOT NULL`)
+	rows, err := s.Query(ctx, query, args...)
+	if err != nil {
+		return &QueryError{Query: "CVSS validation", Err: err}
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return &QueryError{Query: "CVSS validation", Err: err}
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		score, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(score) || math.IsInf(score, 0) || score < 0 || score > 10 {
+			return &CVSSError{Table: sc.table, Value: value}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return &QueryError{Query: "CVSS validation", Err: err}
+	}
+	return nil
 }
 
 // Define vulnerability by severity structure
 type VulnBySeverity struct {
-	CritTotal int
-	SevTotal  int
-	HighTotal int
-	MedTotal  int
-	LowTotal  int
+	// Bands counts the findings of each severity band, highest first, and
+	// then the unscored ones.
+	Bands []BandCount
 }
 
-// Run vulnerability by severity query
-func vulnBySeverity(conn *sql.DB, tableName string) VulnBySeverity {
-	// Run the first query
+// Run vulnerability by severity query. Scores are banded here rather than
+// in SQL so that every dialect treats empty values alike.
+func vulnBySeverity(ctx context.Context, s *Store, sc scope, bands SeverityBands) (VulnBySeverity, error) {
 	var res VulnBySeverity
-	query := `
-	SELECT
-	(SELECT COUNT(*) FROM !! WHERE CVSS = 10) AS Critical,
-	(SELECT COUNT(*) FROM !! WHERE CVSS BETWEEN 9 AND 9.9) AS Severe,
-	(SELECT COUNT(*) FROM !! WHERE CVSS BETWEEN 7 and 8.9) AS High,
-	(SELECT COUNT(*) FROM !! WHERE CVSS BETWEEN 4 and 6.9) AS Medium,
-	(SELECT COUNT(*) FROM !! WHERE CVSS BETWEEN 0 and 3.9) AS Low
-	`
-	query = strings.Replace(query, "!!", tableName, -1)
-	rows, err := conn.Query(query)
+	query, args := sc.expand(s, `SELECT {CVSS}, COUNT(*) FROM !! GROUP BY {CVSS}`)
+	rows, err := s.Query(ctx, query, args...)
 	if err != nil {
-		fmt.Printf("Error running SQL Query. Error: %v\n", err)
-		os.Exit(4)
+		return res, err
 	}
+	defer rows.Close()
+
+	counter := newBandCounter(bands)
 	for rows.Next() {
-		rows.Scan(&res.CritTotal, &res.SevTotal, &res.HighTotal, &res.MedTotal, &res.LowTotal)
+		var cvss sql.NullString
+		var total int
+		if err := rows.Scan(&cvss, &total); err != nil {
+			return res, err
+		}
+		counter.add(cvss.String, total)
 	}
-	return res
+	res.Bands = counter.counts()
+	return res, rows.Err()
 }
 
 // Define top ten vulnerabilities structure
 type TopTenVulnHosts struct {
-	MostVulnHost  string
-	CVSSTotal     int
-	CriticalTotal int
-	SevereTotal   int
-	HighTotal     int
-	MediumTotal   int
-	LowTotal      int
+	MostVulnHost string
+	CVSSTotal    int
+	// Bands counts the findings of the host by severity band, like
+	// VulnBySeverity.
+	Bands []BandCount
 }
 
 // Run top ten vulnerabilities query
-func topTenVulnHosts(conn *sql.DB, tableName string) []TopTenVulnHosts {
-	// Run the second query
-	var res TopTenVulnHosts
-	query := `
-	SELECT Host, ROUND(SUM(CVSS)) AS CVSS_Total,
-	SUM(CASE WHEN CVSS = 10 THEN 1 ELSE 0 END) AS Critical,
-	SUM(CASE WHEN CVSS BETWEEN 9 AND 9.9 THEN 1 ELSE 0 END) AS Severe,
-	SUM(CASE WHEN CVSS BETWEEN 7 AND 8.9 THEN 1 ELSE 0 END) AS High,
-	SUM(CASE WHEN CVSS BETWEEN 4 AND 6.9 THEN 1 ELSE 0 END) AS Medium,
-	SUM(CASE WHEN CVSS BETWEEN 0 AND 3.9 THEN 1 ELSE 0 END) AS Low
-	FROM !! GROUP BY Host ORDER BY CVSS_Total DESC LIMIT 10
-	`
-	query = strings.Replace(query, "!!", tableName, -1)
-	rows, err := conn.Query(query)
+func topTenVulnHosts(ctx context.Context, s *Store, sc scope, bands SeverityBands) ([]TopTenVulnHosts, error) {
+	query := `SELECT {Host}, {CVSS}, COUNT(*) FROM !! GROUP BY {Host}, {CVSS}`
+	query, args := sc.expand(s, query)
+	rows, err := s.Query(ctx, query, args...)
 	if err != nil {
-		fmt.Printf("Error running SQL Query. Error: %v\n", err)
-		os.Exit(4)
+		return nil, err
 	}
-	results := []TopTenVulnHosts{}
+	defer rows.Close()
+
+	// Sum up the scores of each host
+	type hostTotals struct {
+		host    string
+		total   float64
+		counter *bandCounter
+	}
+	var hosts []*hostTotals
+	byHost := map[string]*hostTotals{}
 	for rows.Next() {
-		rows.Scan(&res.MostVulnHost, &res.CVSSTotal, &res.CriticalTotal, &res.SevereTotal, &res.HighTotal, &res.MediumTotal, &res.LowTotal)
-		results = append(results, res)
+		var host, cvss sql.NullString
+		var count int
+		if err := rows.Scan(&host, &cvss, &count); err != nil {
+			return nil, err
+		}
+		totals, ok := byHost[host.String]
+		if !ok {
+			totals = &hostTotals{host: host.String, counter: newBandCounter(bands)}
+			byHost[host.String] = totals
+			hosts = append(hosts, totals)
+		}
+		totals.counter.add(cvss.String, count)
+		if score, err := strconv.ParseFloat(strings.TrimSpace(cvss.String), 64); err == nil {
+			totals.total += score * float64(count)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	return results
+
+	sort.SliceStable(hosts, func(i, j int) bool { return hosts[i].total > hosts[j].total })
+	if len(hosts) > 10 {
+		hosts = hosts[:10]
+	}
+	results := make([]TopTenVulnHosts, 0, len(hosts))
+	for _, totals := range hosts {
+		results = append(results, TopTenVulnHosts{
+			MostVulnHost: totals.host,
+			CVSSTotal:    int(math.Round(totals.total)),
+			Bands:        totals.counter.counts(),
+		})
+	}
+	return results, nil
 }
 
 // Define most dangerous vulnerabilities structure
 type MostDangerousVulns struct {
 	VulnName  string
-	CVSS      int
+	CVSS      float64
 	CVSSTotal int
 }
 
-// Run most dangerous vulnerabilities query
-func mostDangerousVulns(conn *sql.DB, tableName string) []MostDangerousVulns {
-	// Run the second query
-	var res MostDangerousVulns
-	query := `
-	SELECT Name, CVSS, COUNT(*) AS Total
-	FROM !!
-	WHERE CVSS BETWEEN 7 AND 10
-	GROUP BY Name
-	ORDER BY Total DESC
-	LIMIT 10
-	`
-	query = strings.Replace(query, "!!", tableName, -1)
-	rows, err := conn.Query(query)
+// Run most dangerous vulnerabilities query: the ten names found most often
+// with a score in the dangerous bands.
+func mostDangerousVulns(ctx context.Context, s *Store, sc scope, bands SeverityBands) ([]MostDangerousVulns, error) {
+	query := `SELECT {Name}, {CVSS}, COUNT(*) FROM !! GROUP BY {Name}, {CVSS}`
+	query, args := sc.expand(s, query)
+	rows, err := s.Query(ctx, query, args...)
 	if err != nil {
-		fmt.Printf("Error running SQL Query. Error: %v\n", err)
-		os.Exit(4)
+		return nil, err
 	}
-	results := []MostDangerousVulns{}
+	defer rows.Close()
+
+	lowest := bands.dangerous()
+	var results []*MostDangerousVulns
+	byName := map[string]*MostDangerousVulns{}
 	for rows.Next() {
-		rows.Scan(&res.VulnName, &res.CVSS, &res.CVSSTotal)
-		results = append(results, res)
+		var name, cvss sql.NullString
+		var count int
+		if err := rows.Scan(&name, &cvss, &count); err != nil {
+			return nil, err
+		}
+		score, err := strconv.ParseFloat(strings.TrimSpace(cvss.String), 64)
+		if err != nil || bands.Band(score) < lowest {
+			continue
+		}
+		res, ok := byName[name.String]
+		if !ok {
+			res = &MostDangerousVulns{VulnName: name.String}
+			byName[name.String] = res
+			results = append(results, res)
+		}
+		res.CVSS = math.Max(res.CVSS, score)
+		res.CVSSTotal += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	return results
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].CVSSTotal != results[j].CVSSTotal {
+			return results[i].CVSSTotal > results[j].CVSSTotal
+		}
+		return results[i].VulnName < results[j].VulnName
+	})
+	if len(results) > 10 {
+		results = results[:10]
+	}
+	dangerous := make([]MostDangerousVulns, 0, len(results))
+	for _, res := range results {
+		dangerous = append(dangerous, *res)
+	}
+	return dangerous, nil
 }
 
 // Define vulnerabilty by type structure
@@ -223,30 +366,23 @@ type VulnByType struct {
 }
 
 // Run vulnerability by type query
-func vulnByType(conn *sql.DB, tableName string) VulnByType {
+func vulnByType(ctx context.Context, s *Store, sc scope) (VulnByType, error) {
 	// Run the second query
 	var res VulnByType
 	query := `
 	SELECT
-	(SELECT COUNT(*) FROM !! WHERE Name LIKE '%Oracle%') AS Oracle,
-	(SELECT COUNT(*) FROM !! WHERE Name LIKE '%Microsoft%') AS Microsoft,
-	(SELECT COUNT(*) FROM !! WHERE Name LIKE '%SSL%' OR '%TLS%') AS SSL,
-	(SELECT COUNT(*) FROM !! WHERE Name LIKE '%Firefox%') AS Firefox,
-	(SELECT COUNT(*) FROM !! WHERE Name LIKE '%SMB%') AS SMB,
-	(SELECT COUNT(*) FROM !! WHERE Name LIKE '%Apache%') AS Apache,
-	(SELECT COUNT(*) FROM !! WHERE Name LIKE '%PHP%') AS PHP,
-	(SELECT COUNT(*) FROM !! WHERE Name LIKE '%Adobe%') AS Adobe
+	(SELECT COUNT(*) FROM !! WHERE {Name} LIKE '%Oracle%') AS Oracle,
+	(SELECT COUNT(*) FROM !! WHERE {Name} LIKE '%Microsoft%') AS Microsoft,
+	(SELECT COUNT(*) FROM !! WHERE {Name} LIKE '%SSL%' OR {Name} LIKE '%TLS%') AS SSL,
+	(SELECT COUNT(*) FROM !! WHERE {Name} LIKE '%Firefox%') AS Firefox,
+	(SELECT COUNT(*) FROM !! WHERE {Name} LIKE '%SMB%') AS SMB,
+	(SELECT COUNT(*) FROM !! WHERE {Name} LIKE '%Apache%') AS Apache,
+	(SELECT COUNT(*) FROM !! WHERE {Name} LIKE '%PHP%') AS PHP,
+	(SELECT COUNT(*) FROM !! WHERE {Name} LIKE '%Adobe%') AS Adobe
 	`
-	query = strings.Replace(query, "!!", tableName, -1)
-	rows, err := conn.Query(query)
-	if err != nil {
-		fmt.Printf("Error running SQL Query. Error: %v\n", err)
-		os.Exit(4)
-	}
-	for rows.Next() {
-		rows.Scan(&res.OracleCount, &res.MicrosoftCount, &res.SSLCount, &res.FirefoxCount, &res.SMBCount, &res.ApacheCount, &res.PHPCount, &res.AdobeCount)
-	}
-	return res
+	query, args := sc.expand(s, query)
+	err := s.QueryRow(ctx, query, args...).Scan(&res.OracleCount, &res.MicrosoftCount, &res.SSLCount, &res.FirefoxCount, &res.SMBCount, &res.ApacheCount, &res.PHPCount, &res.AdobeCount)
+	return res, err
 }
 
 // Define count by year structure
@@ -256,26 +392,112 @@ type CountCVSSYear struct {
 }
 
 // Run count by year query
-func countCVSSYear(conn *sql.DB, tableName string) []CountCVSSYear {
+func countCVSSYear(ctx context.Context, s *Store, sc scope) ([]CountCVSSYear, error) {
 	// Run the second query
-	var res CountCVSSYear
 	query := `
-	SELECT SUBSTR(CVE,5,4) AS Year, COUNT(*) AS Total
+	SELECT SUBSTR({CVE},5,4) AS Year, COUNT(*) AS Total
 	FROM !!
-	WHERE CVE IS NOT ''
-	GROUP BY Year
+	WHERE {CVE} LIKE 'CVE-____-%'
+	GROUP BY SUBSTR({CVE},5,4)
 	ORDER BY Year DESC
 	`
-	query = strings.Replace(query, "!!", tableName, -1)
-	rows, err := conn.Query(query)
+	query, args := sc.expand(s, query)
+	rows, err := s.Query(ctx, query, args...)
 	if err != nil {
-		fmt.Printf("Error running SQL Query. Error: %v\n", err)
-		os.Exit(4)
+		return nil, err
 	}
+	defer rows.Close()
+
 	results := []CountCVSSYear{}
 	for rows.Next() {
-		rows.Scan(&res.Year, &res.Total)
+		var res CountCVSSYear
+		var year string
+		if err := rows.Scan(&year, &res.Total); err != nil {
+			return nil, err
+		}
+		// Skip malformed identifiers such as CVE-XXXX-0001
+		if res.Year, err = strconv.Atoi(year); err != nil {
+			continue
+		}
 		results = append(results, res)
 	}
-	return results
+	return results, rows.Err()
+}
+
+// scope is the set of rows the report queries read: a table, optionally
+// narrowed by a condition with ? parameters.
+type scope struct {
+	table string
+	where string
+	args  []interface{}
+}
+
+// tableScope covers every row of a table.
+func tableScope(tableName string) scope {
+	return scope{table: tableName}
+}
+
+// selectScans returns the scope of the scans of tableName that sel picks,
+// and the scans. A table without scans is taken whole, with no scans, when
+// sel picks the latest scan.
+func selectScans(ctx context.Context, s *Store, tableName string, sel Selector) (scope, []Scan, error) {
+	scans, err := Scans(ctx, s, tableName)
+	if err != nil {
+		return scope{}, nil, err
+	}
+	if len(scans) == 0 && sel == (Selector{}) {
+		return tableScope(tableName), nil, nil
+	}
+	if scans, err = ResolveScans(ctx, s, tableName, sel); err != nil {
+		return scope{}, nil, err
+	}
+	ids := make([]int64, len(scans))
+	for i, scan := range scans {
+		ids[i] = scan.ID
+	}
+	return scanScope(tableName, ids...), scans, nil
+}
+
+// scanScope covers the rows of some scans of a table.
+func scanScope(tableName string, scanIDs ...int64) scope {
+	args := make([]interface{}, len(scanIDs))
+	for i, id := range scanIDs {
+		args[i] = id
+	}
+	return scope{table: tableName, where: "{scan_id} IN (" + generatePlaceholders(len(args)) + ")", args: args}
+}
+
+// expand replaces the !! table marker and the {Column} markers of a report
+// query with quoted identifiers. A narrowed scope replaces each !! with a
+// subquery and returns its arguments once per occurrence.
+func (sc scope) expand(s *Store, query string) (string, []interface{}) {
+	source := s.Quote(sc.table)
+	var args []interface{}
+	if sc.where != "" {
+		source = fmt.Sprintf("(SELECT * FROM %s WHERE %s) %s", source, sc.condition(s), source)
+		for i := strings.Count(query, "!!"); i > 0; i-- {
+			args = append(args, sc.args...)
+		}
+	}
+	// The table name may itself look like a marker, so it goes in last
+	query = columnMarker.ReplaceAllStringFunc(query, func(marker string) string {
+		return s.Quote(marker[1 : len(marker)-1])
+	})
+	return strings.Replace(query, "!!", source, -1), args
+}
+
+// condition returns the WHERE condition of a narrowed scope with its
+// column markers quoted, for statements that update the rows in scope.
+func (sc scope) condition(s *Store) string {
+	return columnMarker.ReplaceAllStringFunc(sc.where, func(marker string) string {
+		return s.Quote(marker[1 : len(marker)-1])
+	})
+}
+
+// columnMarker matches the {Column} markers of a report query.
+var columnMarker = regexp.MustCompile(`\{[A-Za-z_][A-Za-z0-9_]*\}`)
+
+// generatePlaceholders returns count comma separated ? bind parameters.
+func generatePlaceholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", count), ", ")
 }
@@ -0,0 +1,56 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestValidateCVSS(t *testing.T) {
+	ctx := context.Background()
+	s, err := Open(ctx, "sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.DB.SetMaxOpenConns(1)
+
+	for _, tt := range []struct {
+		value string
+		valid bool
+	}{
+		{"0", true},
+		{"9.8", true},
+		{"10", true},
+		{" 7.5 ", true},
+		{"", true},
+		{"-0.1", false},
+		{"10.1", false},
+		{"NaN", false},
+		{"nan", false},
+		{"Inf", false},
+		{"+Inf", false},
+		{"-Inf", false},
+		{"Infinity", false},
+		{"1e400", false},
+		{"High", false},
+	} {
+		if _, err := s.Exec(ctx, fmt.Sprintf("CREATE TABLE %s (%s TEXT)", s.Quote("vulns"), s.Quote("CVSS"))); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Exec(ctx, fmt.Sprintf("INSERT INTO %s VALUES ('5'), (?)", s.Quote("vulns")), tt.value); err != nil {
+			t.Fatal(err)
+		}
+		err := validateCVSS(ctx, s, tableScope("vulns"))
+		if tt.valid && err != nil {
+			t.Errorf("validateCVSS(%q) = %v, want nil", tt.value, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidCVSS) {
+			t.Errorf("validateCVSS(%q) = %v, want ErrInvalidCVSS", tt.value, err)
+		}
+		if err := s.DropTable(ctx, "vulns"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -2,11 +2,16 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"strings"
+)
 
-	_ "github.com/mattn/go-sqlite3"
+// countedSeverities and countedStates are the values CountByOS counts.
+var (
+	countedSeverities = []string{"Critical", "High", "Medium", "Low"}
+	countedStates     = []string{"ACTIVE", "RESURFACED", "FIXED", "NEW"}
 )
 
 type Vulnerability struct {
@@ -16,102 +21,104 @@ type Vulnerability struct {
 	Count           int
 }
 
-func vulnByTypePerOs(conn *sql.DB, tableName string, columnName string, os string, lastColumn string) []Vulnerability {
+func vulnByTypePerOs(ctx context.Context, s *Store, tableName string, columnName string, os string, lastColumn string) ([]Vulnerability, error) {
+	if err := validateIdentifiers(tableName, columnName, lastColumn); err != nil {
+		return nil, err
+	}
 	query := fmt.Sprintf(`
 	SELECT
-		asset_operating_system AS OperatingSystem,
-		Severity,
-		%s AS State,
+		%[1]s AS OperatingSystem,
+		%[2]s,
+		%[3]s AS State,
 		COUNT(*) AS Count
-	FROM %s
-	WHERE Severity IN ('Critical', 'High', 'Medium', 'Low')
-		AND %s IN ('ACTIVE', 'RESURFACED', 'FIXED', 'NEW')
-		AND asset_operating_system = '%s'
-	GROUP BY asset_operating_system, Severity, State
-	`, lastColumn, tableName, columnName, os)
-
-	rows, err := conn.Query(query)
+	FROM %[4]s
+	WHERE %[2]s IN (%[6]s)
+		AND %[5]s IN (%[7]s)
+		AND %[1]s = ?
+	GROUP BY %[1]s, %[2]s, %[3]s
+	`, s.Quote("asset_operating_system"), s.Quote("Severity"), s.Quote(lastColumn), s.Quote(tableName), s.Quote(columnName),
+		generatePlaceholders(len(countedSeverities)), generatePlaceholders(len(countedStates)))
+
+	args := append(stringArgs(countedSeverities, countedStates), os)
+	rows, err := s.Query(ctx, query, args...)
 	if err != nil {
-		log.Fatalf("Error running SQL Query. Error: %v\n", err)
+		return nil, fmt.Errorf("error running SQL query: %w", err)
 	}
+	defer rows.Close()
 
 	var vulnerabilities []Vulnerability
 	for rows.Next() {
 		var v Vulnerability
-		err := rows.Scan(&v.OperatingSystem, &v.Severity, &v.State, &v.Count)
+		var osName sql.NullString
+		err := rows.Scan(&osName, &v.Severity, &v.State, &v.Count)
 		if err != nil {
-			log.Fatalf("Error scanning row. Error: %v\n", err)
+			return nil, fmt.Errorf("error scanning row: %w", err)
 		}
+		v.OperatingSystem = osName.String
 		vulnerabilities = append(vulnerabilities, v)
 	}
-	return vulnerabilities
+	return vulnerabilities, rows.Err()
 }
 
-func getDistinctOs(db *sql.DB, tableName string, columnName string) []string {
+func getDistinctOs(ctx context.Context, s *Store, tableName string, columnName string) ([]string, error) {
+	if err := validateIdentifiers(tableName, columnName); err != nil {
+		return nil, err
+	}
 	var osList []string
-	rows, err := db.Query(fmt.Sprintf("SELECT DISTINCT %s FROM %s", columnName, tableName))
+	rows, err := s.Query(ctx, fmt.Sprintf("SELECT DISTINCT %s FROM %s", s.Quote(columnName), s.Quote(tableName)))
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var os string
 		if err := rows.Scan(&os); err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		osList = append(osList, os)
 	}
-	if err := rows.Err(); err != nil {
-		log.Fatal(err)
-	}
-	return osList
+	return osList, rows.Err()
 }
 
-func getLastColumnName(db *sql.DB, tableName string) (string, error) {
-	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", tableName))
+func getLastColumnName(ctx context.Context, s *Store, tableName string) (string, error) {
+	columns, err := s.Columns(ctx, tableName)
 	if err != nil {
 		return "", err
 	}
-	defer rows.Close()
+	if len(columns) == 0 {
+		return "", &TableError{Table: tableName}
+	}
 
-	var lastColumnName string
-	for rows.Next() {
-		var cid int
-		var name string
-		var type_ string
-		var notnull int
-		var dflt_value interface{}
-		var pk int
-
-		err = rows.Scan(&cid, &name, &type_, &notnull, &dflt_value, &pk)
-		if err != nil {
-			return "", err
+	// Skip the scan bookkeeping column added after the data columns
+	for i := len(columns) - 1; i >= 0; i-- {
+		if !strings.EqualFold(columns[i].Name, ScanColumn) {
+			return columns[i].Name, nil
 		}
-
-		lastColumnName = name
 	}
-
-	return lastColumnName, nil
+	return "", &TableError{Table: tableName}
 }
 
-func createResultTable(db *sql.DB) error {
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS ResultTable (
-			OperatingSystem TEXT,
-			Severity TEXT,
-			State TEXT,
-			Count INTEGER
-		)`)
+func createResultTable(ctx context.Context, s *Store, tableName string) error {
+	_, err := s.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			%s TEXT,
+			%s TEXT,
+			%s TEXT,
+			%s INTEGER
+		)`, s.Quote(tableName), s.Quote("OperatingSystem"), s.Quote("Severity"), s.Quote("State"), s.Quote("Count")))
 	return err
 }
 
-func clearResultTable(db *sql.DB) error {
-	_, err := db.Exec("DELETE FROM ResultTable")
+func clearResultTable(ctx context.Context, s *Store, tableName string) error {
+	_, err := s.Exec(ctx, "DELETE FROM "+s.Quote(tableName))
 	return err
 }
-func insertResultsIntoTable(db *sql.DB, tableName string, vulnerabilities []Vulnerability) error {
+
+func insertResultsIntoTable(ctx context.Context, s *Store, tableName string, vulnerabilities []Vulnerability) error {
 	// Prepare the SQL statement
-	stmt, err := db.Prepare(fmt.Sprintf("INSERT INTO %s (OperatingSystem, Severity, State, Count) VALUES (?, ?, ?, ?)", tableName))
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s) VALUES (?, ?, ?, ?)",
+		s.Quote(tableName), s.Quote("OperatingSystem"), s.Quote("Severity"), s.Quote("State"), s.Quote("Count"))
+	stmt, err := s.DB.PrepareContext(ctx, s.Rebind(query))
 	if err != nil {
 		return err
 	}
@@ -119,7 +126,7 @@ func insertResultsIntoTable(db *sql.DB, tableName string, vulnerabilities []Vuln
 
 	// Insert each vulnerability into the table
 	for _, v := range vulnerabilities {
-		_, err := stmt.Exec(v.OperatingSystem, v.Severity, v.State, v.Count)
+		_, err := stmt.ExecContext(ctx, v.OperatingSystem, v.Severity, v.State, v.Count)
 		if err != nil {
 			return err
 		}
@@ -128,37 +135,62 @@ func insertResultsIntoTable(db *sql.DB, tableName string, vulnerabilities []Vuln
 	return nil
 }
 
-func countbyOS() {
-	db, err := sql.Open("sqlite3", "./vulns.db")
-	if err != nil {
-		log.Fatal(err)
+// CountByOS counts the vulnerabilities of tableName by operating system,
+// severity and state, and stores the counts in resultTableName. When
+// stateColumn is empty the last column of tableName is used as the state.
+// When assetsTable names an existing table, rows without an operating system
+// take the one recorded for their host in the latest asset scans. Only the
+// rows of the scans sel picks are counted.
+func CountByOS(ctx context.Context, s *Store, tableName string, resultTableName string, stateColumn string, assetsTable string, sel Selector) ([]Vulnerability, error) {
+	if err := validateIdentifiers(tableName, resultTableName); err != nil {
+		return nil, err
+	}
+	if stateColumn == "" {
+		lastColumn, err := getLastColumnName(ctx, s, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find state column: %w", err)
+		}
+		stateColumn = lastColumn
+	}
+	if err := ValidateIdentifier(stateColumn); err != nil {
+		return nil, err
+	}
+	if err := s.RequireColumns(ctx, tableName, "asset_operating_system", "Severity", stateColumn); err != nil {
+		return nil, err
 	}
-	defer db.Close()
 
-	tableName := "PopularBank"
-	resultTableName := "ResultTable"
+	sc, _, err := selectScans(ctx, s, tableName, sel)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create the ResultTable if it doesn't exist
-	err = createResultTable(db)
+	err = createResultTable(ctx, s, resultTableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create result table: %w", err)
+	}
+	from, os, args, err := osSource(ctx, s, sc, assetsTable)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("failed to join assets: %w", err)
 	}
 
 	query := fmt.Sprintf(`
 		SELECT
-			asset_operating_system AS OperatingSystem,
-			Severity,
-			date_123456 AS State,
+			%[1]s AS OperatingSystem,
+			v.%[2]s,
+			v.%[3]s AS State,
 			COUNT(*) AS Count
-		FROM %s
-		WHERE Severity IN ('Critical', 'High', 'Medium', 'Low')
-			AND date_123456 IN ('ACTIVE', 'RESURFACED', 'FIXED', 'NEW')
-		GROUP BY asset_operating_system, Severity, State
-		`, tableName)
-
-	rows, err := db.Query(query)
+		FROM %[4]s
+		WHERE v.%[2]s IN (%[5]s)
+			AND v.%[3]s IN (%[6]s)
+		GROUP BY %[1]s, v.%[2]s, v.%[3]s
+		`, os, s.Quote("Severity"), s.Quote(stateColumn), from,
+		generatePlaceholders(len(countedSeverities)), generatePlaceholders(len(countedStates)))
+
+	args = append(args, stringArgs(countedSeverities, countedStates)...)
+	rows, err := s.Query(ctx, query, args...)
 	if err != nil {
-		log.Fatalf("Error running SQL Query. Error: %v\n", err)
+		return nil, fmt.Errorf("error running SQL query: %w", err)
 	}
 	defer rows.Close()
 
@@ -166,27 +198,41 @@ func countbyOS() {
 
 	for rows.Next() {
 		var v Vulnerability
-		err := rows.Scan(&v.OperatingSystem, &v.Severity, &v.State, &v.Count)
+		var osName sql.NullString
+		err := rows.Scan(&osName, &v.Severity, &v.State, &v.Count)
 		if err != nil {
-			log.Fatalf("Error scanning row. Error: %v\n", err)
+			return nil, fmt.Errorf("error scanning row: %w", err)
 		}
+		v.OperatingSystem = osName.String
 		vulnerabilities = append(vulnerabilities, v)
-		log.Printf("Operating System: %s, Severity: %s, State: %s, Count: %d\n", v.OperatingSystem, v.Severity, v.State, v.Count)
 	}
 
 	if err := rows.Err(); err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// Clear the ResultTable
-	err = clearResultTable(db)
+	err = clearResultTable(ctx, s, resultTableName)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("failed to clear result table: %w", err)
 	}
 
 	// Insert the results into the ResultTable
-	err = insertResultsIntoTable(db, resultTableName, vulnerabilities)
+	err = insertResultsIntoTable(ctx, s, resultTableName, vulnerabilities)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("failed to insert results: %w", err)
+	}
+
+	return vulnerabilities, nil
+}
+
+// stringArgs flattens string lists into bind arguments.
+func stringArgs(lists ...[]string) []interface{} {
+	var args []interface{}
+	for _, list := range lists {
+		for _, value := range list {
+			args = append(args, value)
+		}
 	}
+	return args
 }
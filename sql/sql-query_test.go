@@ -0,0 +1,173 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func FuzzCountByOS(f *testing.F) {
+	f.Add("vulns", "ResultTable", "State", "Windows Server 2019")
+	f.Add(`"; DROP TABLE vulns; --`, "`; DROP TABLE x; --", `a"b`, "'); DROP TABLE vulns; --")
+	f.Add("{Host}", "!!", "{Severity}", "{Host}")
+	f.Add("%s%v", "$1", "?", "?")
+	f.Add("Robert'); DROP TABLE students;--", `""`, "``", `" OR 1=1 --`)
+	f.Add("名前", "結果", "状態", "")
+	f.Fuzz(func(t *testing.T, table string, result string, state string, os string) {
+		if validateIdentifiers(table, result, state) != nil {
+			t.Skip()
+		}
+		// SQLite folds the case of names and reserves the sqlite_ prefix
+		if strings.EqualFold(table, result) || strings.HasPrefix(strings.ToLower(table), "sqlite_") ||
+			strings.HasPrefix(strings.ToLower(result), "sqlite_") {
+			t.Skip()
+		}
+		for _, column := range []string{"Host", "asset_operating_system", "Severity"} {
+			if strings.EqualFold(state, column) {
+				t.Skip()
+			}
+		}
+
+		ctx := context.Background()
+		s, err := Open(ctx, "sqlite", ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		// Every connection to :memory: opens a database of its own
+		s.DB.SetMaxOpenConns(1)
+
+		create := fmt.Sprintf("CREATE TABLE %s (%s TEXT, %s TEXT, %s TEXT, %s TEXT)", s.Quote(table),
+			s.Quote("Host"), s.Quote("asset_operating_system"), s.Quote("Severity"), s.Quote(state))
+		if _, err := s.Exec(ctx, create); err != nil {
+			t.Fatal(err)
+		}
+		insert := fmt.Sprintf("INSERT INTO %s VALUES (?, ?, ?, ?)", s.Quote(table))
+		for _, row := range [][]interface{}{
+			{"host1", os, "Critical", "ACTIVE"},
+			{"host2", os, "Critical", "ACTIVE"},
+			{"host3", os, "High", "FIXED"},
+			{"host4", os, "Info", "ACTIVE"},
+			{"host5", os, "Low", "IGNORED"},
+			{"host6", os + "'", "Medium", "NEW"},
+		} {
+			if _, err := s.Exec(ctx, insert, row...); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		got, err := CountByOS(ctx, s, table, result, state, "", Selector{})
+		if err != nil {
+			t.Fatalf("CountByOS(%q, %q, %q) = %v", table, result, state, err)
+		}
+		want := []Vulnerability{
+			{OperatingSystem: os, Severity: "Critical", State: "ACTIVE", Count: 2},
+			{OperatingSystem: os, Severity: "High", State: "FIXED", Count: 1},
+			{OperatingSystem: os + "'", Severity: "Medium", State: "NEW", Count: 1},
+		}
+		sortVulnerabilities(got)
+		sortVulnerabilities(want)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("CountByOS(%q, %q, %q) = %v, want %v", table, result, state, got, want)
+		}
+
+		// The OS is a value of the query, not a part of it
+		perOS, err := vulnByTypePerOs(ctx, s, table, state, os, state)
+		if err != nil {
+			t.Fatalf("vulnByTypePerOs(%q, %q, %q) = %v", table, state, os, err)
+		}
+		sortVulnerabilities(perOS)
+		if fmt.Sprint(perOS) != fmt.Sprint(want[:2]) {
+			t.Fatalf("vulnByTypePerOs(%q, %q, %q) = %v, want %v", table, state, os, perOS, want[:2])
+		}
+
+		// The schema holds the two tables and nothing else, under their names
+		rows, err := s.Query(ctx, "SELECT name, sql FROM sqlite_master ORDER BY name")
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema := map[string]string{}
+		for rows.Next() {
+			var name, sql string
+			if err := rows.Scan(&name, &sql); err != nil {
+				t.Fatal(err)
+			}
+			schema[name] = sql
+		}
+		rows.Close()
+		if len(schema) != 2 || schema[table] == "" || !strings.Contains(schema[result], s.Quote(result)) {
+			t.Fatalf("schema after CountByOS(%q, %q, %q) = %q", table, result, state, schema)
+		}
+
+		// The result table holds the counts returned
+		stored, err := s.Query(ctx, fmt.Sprintf("SELECT %s, %s, %s, %s FROM %s", s.Quote("OperatingSystem"),
+			s.Quote("Severity"), s.Quote("State"), s.Quote("Count"), s.Quote(result)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stored.Close()
+		var saved []Vulnerability
+		for stored.Next() {
+			var v Vulnerability
+			if err := stored.Scan(&v.OperatingSystem, &v.Severity, &v.State, &v.Count); err != nil {
+				t.Fatal(err)
+			}
+			saved = append(saved, v)
+		}
+		sortVulnerabilities(saved)
+		if fmt.Sprint(saved) != fmt.Sprint(want) {
+			t.Fatalf("%s holds %v, want %v", result, saved, want)
+		}
+	})
+}
+
+func sortVulnerabilities(vulnerabilities []Vulnerability) {
+	sort.Slice(vulnerabilities, func(i, j int) bool {
+		a, b := vulnerabilities[i], vulnerabilities[j]
+		if a.OperatingSystem != b.OperatingSystem {
+			return a.OperatingSystem < b.OperatingSystem
+		}
+		return a.Severity < b.Severity
+	})
+}
+
+func TestScopedToScans(t *testing.T) {
+	const columns = "Host,asset_operating_system,Severity,State,Notes"
+	s := newTestStore(t)
+	mustExec(t, s, `CREATE TABLE "vulns" ("Host" TEXT, "asset_operating_system" TEXT, "Severity" TEXT, "State" TEXT, "Notes" TEXT)`)
+	for _, rows := range []string{
+		columns + "\nhost1,Linux,High,ACTIVE,\n",
+		columns + "\nhost1,Linux,Critical,ACTIVE,\n",
+	} {
+		if _, err := importScan(t, s, BeginScan, "vulns", rows); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The importer stores empty cells as NULL
+	mustExec(t, s, `UPDATE "vulns" SET "Notes" = NULL`)
+
+	for _, tt := range []struct {
+		sel  Selector
+		want string
+	}{
+		{Selector{}, "[{Linux Critical ACTIVE 1}]"},
+		{Selector{ID: 1}, "[{Linux High ACTIVE 1}]"},
+	} {
+		got, err := CountByOS(context.Background(), s, "vulns", "result", "State", "", tt.sel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("CountByOS(%v) = %v, want %s", tt.sel, got, tt.want)
+		}
+	}
+
+	if err := FixSql(context.Background(), s, "vulns", Selector{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(columnValues(t, s, "vulns", "Notes")); got != "[NULL ]" {
+		t.Errorf("notes after fixing the latest scan = %s, want [NULL ]", got)
+	}
+}
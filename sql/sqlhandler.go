@@ -2,18 +2,22 @@
 package sql
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // UpdateMatchStatus updates the match status in the database for a given serial number.
-func UpdateMatchStatus(db *sql.DB, serialNumber string, columnHeader string, value string) error {
-	query := fmt.Sprintf("UPDATE PopularBank SET %q = ? WHERE id = ?", columnHeader)
-	_, err := db.Exec(query, value, serialNumber)
-	if err != nil {
+func UpdateMatchStatus(ctx context.Context, s *Store, tableName string, serialNumber string, columnHeader string, value string) error {
+	if err := validateIdentifiers(tableName, columnHeader); err != nil {
 		return err
 	}
+	query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", s.Quote(tableName), s.Quote(columnHeader), s.Quote("id"))
+	result, err := s.Exec(ctx, query, value, serialNumber)
+	if err != nil {
+		return fmt.Errorf("unable to update %s: %w", tableName, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("no row of %s has id %s: %w", tableName, serialNumber, ErrNoMatch)
+	}
 	return nil
 }
@@ -0,0 +1,197 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	// Import the MySQL, SQLite and PostgreSQL SQL drivers
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// Store is a database connection together with the dialect used to talk to it.
+type Store struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+// driverNames maps a dialect name to the database/sql driver that serves it.
+var driverNames = map[string]string{
+	"mysql":    "mysql",
+	"sqlite":   "sqlite",
+	"postgres": "postgres",
+}
+
+// Open connects to the database at dsn. When driver is empty the backend is
+// picked from the DSN scheme (mysql://, postgres://, sqlite://, file:) or,
+// failing that, from the shape of the DSN.
+func Open(ctx context.Context, driver string, dsn string) (*Store, error) {
+	if driver == "" {
+		detected, err := DetectDriver(dsn)
+		if err != nil {
+			return nil, err
+		}
+		driver = detected
+	}
+
+	dialect, err := DialectFor(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(driverNames[dialect.Name()], driverDSN(dialect.Name(), dsn))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s database: %w", dialect.Name(), err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to connect to %s database: %w", dialect.Name(), err)
+	}
+
+	return &Store{DB: db, Dialect: dialect}, nil
+}
+
+// DetectDriver guesses the dialect name from a DSN.
+func DetectDriver(dsn string) (string, error) {
+	lower := strings.ToLower(dsn)
+	switch {
+	case strings.HasPrefix(lower, "mysql://"):
+		return "mysql", nil
+	case strings.HasPrefix(lower, "postgres://"), strings.HasPrefix(lower, "postgresql://"):
+		return "postgres", nil
+	case strings.HasPrefix(lower, "sqlite://"), strings.HasPrefix(lower, "sqlite:"), strings.HasPrefix(lower, "file:"):
+		return "sqlite", nil
+	case strings.Contains(lower, "@tcp("), strings.Contains(lower, "@unix("), strings.Contains(lower, "@/"):
+		return "mysql", nil
+	case strings.Contains(lower, "dbname="), strings.Contains(lower, "host="):
+		return "postgres", nil
+	case lower == ":memory:", strings.HasSuffix(lower, ".db"), strings.HasSuffix(lower, ".sqlite"), strings.HasSuffix(lower, ".sqlite3"):
+		return "sqlite", nil
+	}
+	return "", fmt.Errorf("unable to detect the database driver from the DSN, set it explicitly")
+}
+
+// driverDSN strips the scheme prefixes that the underlying driver does not understand.
+func driverDSN(dialect string, dsn string) string {
+	switch dialect {
+	case "mysql":
+		return trimPrefixFold(dsn, "mysql://")
+	case "sqlite":
+		dsn = trimPrefixFold(dsn, "sqlite://")
+		return trimPrefixFold(dsn, "sqlite:")
+	}
+	return dsn
+}
+
+func trimPrefixFold(s string, prefix string) string {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):]
+	}
+	return s
+}
+
+// Close closes the database connection.
+func (s *Store) Close() error {
+	return s.DB.Close()
+}
+
+// Quote quotes an identifier for the store's dialect.
+func (s *Store) Quote(identifier string) string {
+	return s.Dialect.Quote(identifier)
+}
+
+// Rebind rewrites the ? bind parameters of query into the dialect's form.
+// Question marks inside quoted strings and identifiers are left untouched.
+func (s *Store) Rebind(query string) string {
+	if s.Dialect.Placeholder(1) == "?" {
+		return query
+	}
+
+	var b strings.Builder
+	var quote rune
+	n := 0
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString(s.Dialect.Placeholder(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Exec rebinds and executes a statement.
+func (s *Store) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.DB.ExecContext(ctx, s.Rebind(query), args...)
+}
+
+// Query rebinds and runs a query.
+func (s *Store) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.DB.QueryContext(ctx, s.Rebind(query), args...)
+}
+
+// QueryRow rebinds and runs a query that returns at most one row.
+func (s *Store) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.DB.QueryRowContext(ctx, s.Rebind(query), args...)
+}
+
+// Truncate removes every row of a table.
+func (s *Store) Truncate(ctx context.Context, table string) error {
+	_, err := s.DB.ExecContext(ctx, s.Dialect.Truncate(table))
+	return err
+}
+
+// DropTable drops a table when it exists.
+func (s *Store) DropTable(ctx context.Context, table string) error {
+	_, err := s.DB.ExecContext(ctx, "DROP TABLE IF EXISTS "+s.Quote(table))
+	return err
+}
+
+// Columns returns the columns of a table in their declared order.
+func (s *Store) Columns(ctx context.Context, table string) ([]Column, error) {
+	return s.Dialect.Columns(ctx, s.DB, table)
+}
+
+// TableExists reports whether a table exists.
+func (s *Store) TableExists(ctx context.Context, table string) (bool, error) {
+	return s.Dialect.TableExists(ctx, s.DB, table)
+}
+
+// RequireColumns returns a *ColumnError for the first of columns that table lacks.
+func (s *Store) RequireColumns(ctx context.Context, table string, columns ...string) error {
+	existing, err := s.Columns(ctx, table)
+	if err != nil {
+		return fmt.Errorf("unable to list columns of %s: %w", table, err)
+	}
+	if len(existing) == 0 {
+		return &TableError{Table: table}
+	}
+	for _, want := range columns {
+		if !hasColumn(existing, want) {
+			return &ColumnError{Table: table, Column: want}
+		}
+	}
+	return nil
+}
+
+// hasColumn reports whether name is among columns, ignoring case.
+func hasColumn(columns []Column, name string) bool {
+	for _, c := range columns {
+		if strings.EqualFold(c.Name, name) {
+			return true
+		}
+	}
+	return false
+}
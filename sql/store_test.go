@@ -0,0 +1,57 @@
+package sql
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// newTestStore opens an in-memory SQLite database that lasts for the test.
+func newTestStore(t testing.TB) *Store {
+	t.Helper()
+	s, err := Open(context.Background(), "sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens a database of its own
+	s.DB.SetMaxOpenConns(1)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// csvRows reads CSV text, header first, as ImportRows does.
+func csvRows(text string) RowReader {
+	return csv.NewReader(strings.NewReader(text))
+}
+
+// mustExec runs a statement of a test setup.
+func mustExec(t testing.TB, s *Store, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := s.Exec(context.Background(), query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// columnValues returns the values of a column of table, in rowid order.
+func columnValues(t testing.TB, s *Store, table string, column string) []string {
+	t.Helper()
+	rows, err := s.Query(context.Background(), fmt.Sprintf("SELECT COALESCE(CAST(%s AS TEXT), 'NULL') FROM %s ORDER BY rowid", s.Quote(column), s.Quote(table)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
@@ -0,0 +1,403 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sentlab/update-db/csv"
+)
+
+// ImportOptions controls how a CSV file is loaded into a table.
+type ImportOptions struct {
+	// Mapping maps source headers to table columns. Headers it does not list
+	// are matched to the table column with the same name.
+	Mapping map[string]string
+	// Required lists the table columns the file must provide.
+	Required []string
+	// Create creates a missing table, with column types inferred from the
+	// first SampleRows rows and indexes on the IndexedColumns.
+	Create bool
+	// SampleRows is the number of rows inspected to infer column types.
+	SampleRows int
+	// AddColumns adds a text column to the table for every source header
+	// that matches none of its columns, instead of skipping the header,
+	// and for every mapping target it lacks.
+	AddColumns bool
+	// Derived lists source headers the reader computed rather than read,
+	// such as the components of CVSS vectors. They are added to the table
+	// like AddColumns adds headers when it lacks them.
+	Derived []string
+	// SourceFile, when set, tags every row with the name of the file it
+	// came from, in the SourceFileColumn column.
+	SourceFile string
+	// Scan adds the rows to the table as part of a scan started with
+	// BeginScan or BeginReplace, tagging each with the scan ID. It implies
+	// Append; the rows of a replacing scan go to its staging table.
+	Scan *Scan
+	// Append inserts into the table directly and keeps its rows. Otherwise
+	// the rows are loaded into a staging table that replaces the table only
+	// once the whole source loaded and validated.
+	Append bool
+	// BatchSize is the number of rows sent per INSERT statement.
+	BatchSize int
+	// TxRows is the number of rows committed per transaction.
+	TxRows int
+	// Progress, when set, is called after every committed transaction.
+	Progress func(ImportResult)
+}
+
+// ImportResult describes an import, finished or in progress.
+type ImportResult struct {
+	// Rows is the number of data rows committed.
+	Rows int
+	// Bytes is the number of source bytes read.
+	Bytes int64
+	// Duration is the time spent so far.
+	Duration time.Duration
+	// Unmapped lists the source headers that were not loaded.
+	Unmapped []string
+}
+
+// RowsPerSecond returns the import throughput.
+func (r ImportResult) RowsPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Rows) / r.Duration.Seconds()
+}
+
+// RowReader yields a header row followed by data rows, the way encoding/csv
+// does. Read returns io.EOF after the last row.
+type RowReader interface {
+	Read() ([]string, error)
+}
+
+// byteCounter is implemented by row readers that know how much input they consumed.
+type byteCounter interface {
+	BytesRead() int64
+}
+
+// UploadCSV streams the CSV file at csvFilePath into tableName. See ImportRows.
+func UploadCSV(ctx context.Context, s *Store, tableName string, csvFilePath string, opts ImportOptions) (*ImportResult, error) {
+	reader, err := csv.OpenReader(csvFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open CSV file: %w", err)
+	}
+	defer reader.Close()
+
+	return ImportRows(ctx, s, tableName, reader, opts)
+}
+
+// ImportRows loads the rows of source into tableName. The header row is
+// matched to the table columns by name or through opts.Mapping, and a
+// *MappingError is returned before anything is written when a required
+// column is missing. Rows are sent in multi-row INSERTs of opts.BatchSize
+// rows and committed every opts.TxRows rows.
+//
+// Unless opts.Append is set, the rows go to a staging table that is checked
+// and then swapped in for tableName atomically, so a failed import leaves
+// the previous rows in place and readers never see a partial load. The
+// rows of a scan begun with BeginReplace load into its staging table, which
+// the scan swaps in when it commits.
+func ImportRows(ctx context.Context, s *Store, tableName string, source RowReader, opts ImportOptions) (*ImportResult, error) {
+	start := time.Now()
+
+	if err := ValidateIdentifier(tableName); err != nil {
+		return nil, err
+	}
+	if opts.Scan != nil && opts.Scan.staging != "" {
+		tableName = opts.Scan.staging
+	}
+
+	// Read the header row
+	header, err := source.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("source has no header row")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read header: %w", err)
+	}
+	header = append([]string(nil), header...)
+
+	exists, err := s.TableExists(ctx, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to check table %s: %w", tableName, err)
+	}
+	if !exists {
+		if !opts.Create {
+			return nil, &TableError{Table: tableName}
+		}
+		if header, source, err = createFromSample(ctx, s, tableName, header, source, &opts); err != nil {
+			return nil, err
+		}
+	}
+
+	// Match the header to the table columns before touching the table
+	columns, err := s.Columns(ctx, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list columns of %s: %w", tableName, err)
+	}
+	if len(columns) == 0 {
+		return nil, &TableError{Table: tableName}
+	}
+	plan, err := PlanColumns(header, columns, opts.Mapping, opts.Required)
+	var mappingErr *MappingError
+	var targets []string
+	if opts.AddColumns && errors.As(err, &mappingErr) && len(mappingErr.Unknown) > 0 && len(mappingErr.Duplicate) == 0 {
+		// Mapping targets the table lacks are fields to add as well
+		targets, err = mappingErr.Unknown, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the fields the table has no column for yet, or only the derived
+	// ones
+	added := targets
+	for _, name := range plan.Unmapped {
+		if opts.AddColumns || containsFold(opts.Derived, name) {
+			added = append(added, name)
+		}
+	}
+	if len(added) > 0 {
+		if err := AddTextColumns(ctx, s, tableName, added); err != nil {
+			return nil, err
+		}
+		if columns, err = s.Columns(ctx, tableName); err != nil {
+			return nil, fmt.Errorf("unable to list columns of %s: %w", tableName, err)
+		}
+		if plan, err = PlanColumns(header, columns, opts.Mapping, opts.Required); err != nil {
+			return nil, err
+		}
+	}
+
+	// Tag the rows of a scan with its ID
+	if opts.Scan != nil {
+		if err := addScanColumn(ctx, s, tableName, columns); err != nil {
+			return nil, err
+		}
+		plan.setConstant(ScanColumn, opts.Scan.ID)
+		opts.Append = true
+	}
+
+	// Tag the rows with the file they came from, out of an archive or a
+	// directory of many
+	if opts.SourceFile != "" {
+		if !hasColumn(columns, SourceFileColumn) {
+			if err := AddTextColumns(ctx, s, tableName, []string{SourceFileColumn}); err != nil {
+				return nil, err
+			}
+		}
+		plan.setConstant(SourceFileColumn, opts.SourceFile)
+	}
+
+	// Load into a staging copy of the table unless appending
+	target := tableName
+	if !opts.Append {
+		target = tableName + "_staging"
+		if err := ValidateIdentifier(target); err != nil {
+			return nil, err
+		}
+		if err := s.DropTable(ctx, target); err != nil {
+			return nil, fmt.Errorf("unable to drop stale staging table: %w", err)
+		}
+		if err := s.Dialect.CreateLike(ctx, s.DB, target, tableName); err != nil {
+			return nil, fmt.Errorf("unable to create staging table: %w", err)
+		}
+		// The staging table is renamed away on success; drop it otherwise
+		defer s.DropTable(context.Background(), target)
+	}
+
+	writer := newBatchWriter(s, target, plan.Columns, opts.BatchSize, opts.TxRows)
+	defer writer.Abort()
+
+	result := &ImportResult{Unmapped: plan.Unmapped}
+	snapshot := func() ImportResult {
+		result.Rows = writer.Written()
+		result.Duration = time.Since(start)
+		if counter, ok := source.(byteCounter); ok {
+			result.Bytes = counter.BytesRead()
+		}
+		return *result
+	}
+
+	// Insert each record into the table
+	for row := 2; ; row++ {
+		record, err := source.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			snapshot()
+			return result, fmt.Errorf("unable to read row %d: %w", row, err)
+		}
+
+		values, err := plan.Values(record)
+		if err != nil {
+			snapshot()
+			return result, fmt.Errorf("row %d: %w", row, err)
+		}
+		committed, err := writer.Add(ctx, values)
+		if err != nil {
+			snapshot()
+			return result, fmt.Errorf("row %d: %w", row, err)
+		}
+		if committed && opts.Progress != nil {
+			opts.Progress(snapshot())
+		}
+	}
+
+	if err := writer.Close(ctx); err != nil {
+		snapshot()
+		return result, err
+	}
+	snapshot()
+
+	if !opts.Append {
+		if err := validateStaging(ctx, s, tableName, target, result.Rows); err != nil {
+			return result, err
+		}
+		if err := s.Dialect.Swap(ctx, s.DB, tableName, target); err != nil {
+			return result, fmt.Errorf("unable to swap in the new rows of %s: %w", tableName, err)
+		}
+	}
+	return result, nil
+}
+
+// validateStaging checks a loaded staging table before it replaces tableName.
+func validateStaging(ctx context.Context, s *Store, tableName string, staging string, rows int) error {
+	if rows == 0 {
+		return fmt.Errorf("keeping the existing rows of %s: %w", tableName, ErrEmptyImport)
+	}
+	columns, err := s.Columns(ctx, staging)
+	if err != nil {
+		return fmt.Errorf("unable to list columns of %s: %w", staging, err)
+	}
+	if hasColumn(columns, "CVSS") {
+		if err := validateCVSS(ctx, s, tableScope(staging)); err != nil {
+			var cvssErr *CVSSError
+			if errors.As(err, &cvssErr) {
+				cvssErr.Table = tableName
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// createFromSample creates tableName from the header and the first rows of
+// source. It returns the header renamed to the new column names and a reader
+// that replays the sampled rows; opts.Mapping is rewritten to match.
+func createFromSample(ctx context.Context, s *Store, tableName string, header []string, source RowReader, opts *ImportOptions) ([]string, RowReader, error) {
+	mapped := make([]string, len(header))
+	dropped := make(map[int]bool)
+	for i, name := range header {
+		mapped[i] = name
+		for sourceName, target := range opts.Mapping {
+			if columnKey(sourceName) != columnKey(name) {
+				continue
+			}
+			if target == "" {
+				dropped[i] = true
+			} else {
+				mapped[i] = target
+			}
+		}
+	}
+	// Kept headers claim their names before dropped ones named alike
+	order := make([]int, 0, len(mapped))
+	for i := range mapped {
+		if !dropped[i] {
+			order = append(order, i)
+		}
+	}
+	for i := range mapped {
+		if dropped[i] {
+			order = append(order, i)
+		}
+	}
+	ordered := make([]string, len(order))
+	for j, i := range order {
+		ordered[j] = mapped[i]
+	}
+	normalized := NormalizeHeaders(ordered)
+	names := make([]string, len(mapped))
+	for j, i := range order {
+		names[i] = normalized[j]
+	}
+
+	// Refuse a header that lacks a required column before creating anything
+	planned := make([]Column, 0, len(names))
+	for i, name := range names {
+		if !dropped[i] {
+			planned = append(planned, Column{Name: name})
+		}
+	}
+	if _, err := PlanColumns(names, planned, nil, opts.Required); err != nil {
+		return nil, nil, err
+	}
+
+	sampleRows := opts.SampleRows
+	if sampleRows <= 0 {
+		sampleRows = DefaultSampleRows
+	}
+	replay := &replayReader{source: source}
+	for len(replay.rows) < sampleRows {
+		record, err := source.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to read sample row %d: %w", len(replay.rows)+2, err)
+		}
+		replay.rows = append(replay.rows, append([]string(nil), record...))
+	}
+
+	if err := CreateInferredTable(ctx, s, tableName, names, replay.rows); err != nil {
+		return nil, nil, err
+	}
+
+	// The new columns carry the normalized names; only drops still apply
+	opts.Mapping = make(map[string]string, len(dropped))
+	for i := range dropped {
+		opts.Mapping[names[i]] = ""
+	}
+	return names, replay, nil
+}
+
+// replayReader returns buffered rows before reading on from its source.
+type replayReader struct {
+	rows   [][]string
+	source RowReader
+}
+
+func (r *replayReader) Read() ([]string, error) {
+	if len(r.rows) > 0 {
+		row := r.rows[0]
+		r.rows = r.rows[1:]
+		return row, nil
+	}
+	return r.source.Read()
+}
+
+func (r *replayReader) BytesRead() int64 {
+	if counter, ok := r.source.(byteCounter); ok {
+		return counter.BytesRead()
+	}
+	return 0
+}
+
+// containsFold reports whether names holds name, ignoring case.
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestImportRowsStaging(t *testing.T) {
+	var mappingErr *MappingError
+	for _, tt := range []struct {
+		name     string
+		rows     string
+		opts     ImportOptions
+		wantErr  func(error) bool
+		wantRows string
+	}{
+		{
+			name:     "replace",
+			rows:     "Host,CVSS\nnew1,9.8\nnew2,\n",
+			wantRows: "[new1 new2]",
+		},
+		{
+			name:     "append",
+			rows:     "Host,CVSS\nnew1,9.8\n",
+			opts:     ImportOptions{Append: true},
+			wantRows: "[old1 new1]",
+		},
+		{
+			name:     "invalid CVSS",
+			rows:     "Host,CVSS\nnew1,9.8\nnew2,high\n",
+			wantErr:  func(err error) bool { return errors.Is(err, ErrInvalidCVSS) },
+			wantRows: "[old1]",
+		},
+		{
+			name:     "no rows",
+			rows:     "Host,CVSS\n",
+			wantErr:  func(err error) bool { return errors.Is(err, ErrEmptyImport) },
+			wantRows: "[old1]",
+		},
+		{
+			name:     "missing column",
+			rows:     "Host,CVSS\nnew1,9.8\n",
+			opts:     ImportOptions{Required: []string{"Severity"}},
+			wantErr:  func(err error) bool { return errors.As(err, &mappingErr) },
+			wantRows: "[old1]",
+		},
+		{
+			name:     "short row",
+			rows:     "Host,CVSS\nnew1,9.8\nnew2\n",
+			opts:     ImportOptions{BatchSize: 1, TxRows: 1},
+			wantErr:  func(err error) bool { return err != nil },
+			wantRows: "[old1]",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStore(t)
+			mustExec(t, s, `CREATE TABLE "vulns" ("Host" TEXT, "CVSS" TEXT)`)
+			mustExec(t, s, `CREATE INDEX "vulns_host" ON "vulns" ("Host")`)
+			mustExec(t, s, `INSERT INTO "vulns" VALUES ('old1', '5.0')`)
+			ctx := context.Background()
+
+			_, err := ImportRows(ctx, s, "vulns", csvRows(tt.rows), tt.opts)
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !tt.wantErr(err) {
+				t.Fatalf("ImportRows = %v", err)
+			}
+			if got := fmt.Sprint(columnValues(t, s, "vulns", "Host")); got != tt.wantRows {
+				t.Errorf("rows = %s, want %s", got, tt.wantRows)
+			}
+			if exists, _ := s.TableExists(ctx, "vulns_staging"); exists {
+				t.Error("the staging table is left behind")
+			}
+			var indexes int
+			if err := s.QueryRow(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = 'vulns'").Scan(&indexes); err != nil || indexes != 1 {
+				t.Errorf("vulns has %d indexes, %v, want 1", indexes, err)
+			}
+		})
+	}
+}
+
+func TestImportRowsProgress(t *testing.T) {
+	s := newTestStore(t)
+	mustExec(t, s, `CREATE TABLE "vulns" ("Host" TEXT, "CVSS" TEXT)`)
+
+	var progress []int
+	result, err := ImportRows(context.Background(), s, "vulns", csvRows("Host,CVSS\nh1,1\nh2,2\nh3,3\nh4,4\nh5,5\n"), ImportOptions{
+		BatchSize: 1,
+		TxRows:    2,
+		Progress:  func(r ImportResult) { progress = append(progress, r.Rows) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(progress); got != "[2 4]" {
+		t.Errorf("progress after %s rows, want [2 4]", got)
+	}
+	if result.Rows != 5 {
+		t.Errorf("imported %d rows, want 5", result.Rows)
+	}
+}
@@ -0,0 +1,104 @@
+// Package sql performs SQL operations
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// StateNotAffected is the VEX state of statements that suppress findings.
+const StateNotAffected = "not_affected"
+
+// vexColumns are the columns of a VEX table, as the vex import creates it.
+var vexColumns = []string{"Host", "vulnerability_id", "component", "version", "bom_ref", "state"}
+
+// vexMatches pairs the VEX columns that narrow a statement with the finding
+// columns they are compared to.
+var vexMatches = []struct{ statement, finding string }{
+	{"Host", "Host"},
+	{"component", "package"},
+	{"version", "installed_version"},
+	{"bom_ref", "bom_ref"},
+}
+
+// listedCVEs returns the expression that turns the CVE column value cve,
+// which may list several identifiers like splitCVEs reads them, into a comma
+// separated list in upper case that starts and ends with a comma.
+func listedCVEs(s *Store, cve string) string {
+	list := "UPPER(" + cve + ")"
+	for _, separator := range cveSeparators {
+		if separator != ',' {
+			list = fmt.Sprintf("REPLACE(%s, '%c', ',')", list, separator)
+		}
+	}
+	return s.Dialect.Concat("','", list, "','")
+}
+
+// suppressVEX narrows sc to the findings that no not_affected statement of
+// the latest VEX scans covers, and counts the findings it leaves out. A
+// statement covers the findings of its vulnerability, alone or among the
+// CVEs they list, whose product and component match its own; empty
+// statement columns match any. sc is returned as is when the VEX table does
+// not exist.
+func suppressVEX(ctx context.Context, s *Store, sc scope, vexTable string) (scope, int, error) {
+	if err := ValidateIdentifier(vexTable); err != nil {
+		return sc, 0, err
+	}
+	exists, err := s.TableExists(ctx, vexTable)
+	if err != nil || !exists {
+		return sc, 0, err
+	}
+	if err := s.RequireColumns(ctx, vexTable, vexColumns...); err != nil {
+		return sc, 0, err
+	}
+	columns, err := s.Columns(ctx, sc.table)
+	if err != nil {
+		return sc, 0, fmt.Errorf("unable to list columns of %s: %w", sc.table, err)
+	}
+	statements, err := assetScope(ctx, s, vexTable, Selector{})
+	if err != nil {
+		return sc, 0, err
+	}
+
+	finding := s.Quote(sc.table) + "."
+	statement := s.Quote(vexTable) + "."
+	ids := []string{finding + s.Quote("CVE")}
+	if hasColumn(columns, "vulnerability_id") {
+		ids = append(ids, "UPPER("+finding+s.Quote("vulnerability_id")+")")
+	}
+	conditions := []string{
+		statement + "{state} = ?",
+		fmt.Sprintf("(%s{vulnerability_id} IN (%s) OR %s LIKE %s)",
+			statement, strings.Join(ids, ", "), listedCVEs(s, finding+s.Quote("CVE")),
+			s.Dialect.Concat("'%,'", "UPPER("+statement+"{vulnerability_id})", "',%'")),
+	}
+	for _, m := range vexMatches {
+		unset := fmt.Sprintf("COALESCE(%s{%s}, '') = ''", statement, m.statement)
+		if !hasColumn(columns, m.finding) {
+			// Findings without the column only match statements without it
+			conditions = append(conditions, unset)
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s OR %s{%s} = %s%s)", unset, statement, m.statement, finding, s.Quote(m.finding)))
+	}
+
+	exclude, args := statements.expand(s, "NOT EXISTS (SELECT 1 FROM !! WHERE "+strings.Join(conditions, " AND ")+")")
+	args = append(args, StateNotAffected)
+	narrowed := scope{table: sc.table, where: exclude, args: append(append([]interface{}{}, sc.args...), args...)}
+	if sc.where != "" {
+		narrowed.where = sc.where + " AND " + exclude
+	}
+
+	// Count what the statements leave out
+	var all, kept int
+	query, queryArgs := sc.expand(s, "SELECT COUNT(*) FROM !!")
+	if err := s.QueryRow(ctx, query, queryArgs...).Scan(&all); err != nil {
+		return sc, 0, &QueryError{Query: "VEX suppression", Err: err}
+	}
+	query, queryArgs = narrowed.expand(s, "SELECT COUNT(*) FROM !!")
+	if err := s.QueryRow(ctx, query, queryArgs...).Scan(&kept); err != nil {
+		return sc, 0, &QueryError{Query: "VEX suppression", Err: err}
+	}
+	return narrowed, all - kept, nil
+}
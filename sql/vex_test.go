@@ -0,0 +1,52 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestSuppressVEXListedCVEs(t *testing.T) {
+	s := newTestStore(t)
+	mustExec(t, s, `CREATE TABLE "vulns" ("Host" TEXT, "CVE" TEXT)`)
+	mustExec(t, s, `INSERT INTO "vulns" VALUES
+		('h1', 'CVE-2023-0001, CVE-2023-0002'),
+		('h2', 'cve-2023-0003;CVE-2023-0002'),
+		('h3', 'CVE-2023-00021'),
+		('h4', 'CVE-2023-0002'),
+		('h5', 'CVE-2023-0001 CVE-2023-0004'),
+		('h6', 'CVE-2023-0005')`)
+	mustExec(t, s, `CREATE TABLE "vex" ("Host" TEXT, "vulnerability_id" TEXT, "component" TEXT, "version" TEXT, "bom_ref" TEXT, "state" TEXT)`)
+	mustExec(t, s, `INSERT INTO "vex" VALUES
+		('', 'CVE-2023-0002', '', '', '', 'not_affected'),
+		('', 'CVE-2023-0004', '', '', '', 'affected')`)
+	ctx := context.Background()
+
+	sc, suppressed, err := suppressVEX(ctx, s, scope{table: "vulns"}, "vex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suppressed != 3 {
+		t.Errorf("suppressed %d findings, want 3", suppressed)
+	}
+	query, args := sc.expand(s, "SELECT {Host} FROM !! ORDER BY {Host}")
+	rows, err := s.Query(ctx, query, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var kept []string
+	for rows.Next() {
+		var host string
+		if err := rows.Scan(&host); err != nil {
+			t.Fatal(err)
+		}
+		kept = append(kept, host)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(kept); got != "[h3 h5 h6]" {
+		t.Errorf("kept %s, want [h3 h5 h6]", got)
+	}
+}